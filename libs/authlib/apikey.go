@@ -0,0 +1,57 @@
+package authlib
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// APIKeyPrefix starts every personal API key. Lets the middleware tell keys
+// apart from JWTs sent in the same header.
+const APIKeyPrefix = "edk_"
+
+// APIKeyValidator looks up personal API keys for JWTMiddleware
+type APIKeyValidator interface {
+	// ValidateAPIKey returns the claims granted by key. Returns nil claims
+	// if the key is unknown, expired or revoked.
+	ValidateAPIKey(key string) (*JWTClaims, error)
+}
+
+// IsAPIKey returns true if token has the format of a personal API key
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// getAPIKeyFromHeader returns the API key in the Authorization header, if
+// the header holds one
+func getAPIKeyFromHeader(ctx echo.Context) (string, bool) {
+	token, err := getAuthTokenFromHeader(ctx)
+	if err != nil || !IsAPIKey(token) {
+		return "", false
+	}
+	return token, true
+}
+
+// authWithAPIKey validates an API key and stores its claims in the context
+// like a JWT would. Keys are never turned into browser cookies.
+func authWithAPIKey(ctx echo.Context, next echo.HandlerFunc, config JWTConfig, policy Policy, key string) error {
+	claims, err := config.APIKeys.ValidateAPIKey(key)
+	if err != nil {
+		return &echo.HTTPError{
+			Code:     http.StatusInternalServerError,
+			Message:  "failed to check API key",
+			Internal: err,
+		}
+	}
+
+	if !policy.Allows(claims) {
+		return &echo.HTTPError{
+			Code:    http.StatusUnauthorized,
+			Message: "invalid or expired API key in request",
+		}
+	}
+
+	ctx.Set("user", claims)
+	return next(ctx)
+}
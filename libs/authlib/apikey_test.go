@@ -0,0 +1,75 @@
+package authlib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type testAPIKeys map[string]*JWTClaims
+
+func (k testAPIKeys) ValidateAPIKey(key string) (*JWTClaims, error) {
+	return k[key], nil
+}
+
+func TestMiddlewareAcceptsAPIKey(t *testing.T) {
+	e := echo.New()
+	handler := func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	}
+
+	validKey := APIKeyPrefix + "abcd_secret"
+	keys := testAPIKeys{
+		validKey: {
+			Username: "pelle",
+			UserID:   "random_id",
+			Roles:    []string{"user"},
+			Scopes:   []string{"lineups:read"},
+		},
+	}
+
+	makeReq := func(config JWTConfig, key string) (echo.Context, error) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		req.Header.Set(echo.HeaderAuthorization, "Bearer: "+key)
+		c := e.NewContext(req, res)
+		return c, JWTMiddleware(config)(handler)(c)
+	}
+
+	c, err := makeReq(JWTConfig{SigningKey: []byte("secret"), APIKeys: keys}, validKey)
+	if err != nil {
+		t.Fatalf("Valid API key should be accepted: %+v", err)
+	}
+	claims := c.Get("user").(*JWTClaims)
+	if claims.Username != "pelle" || !claims.HasScope("lineups:read") {
+		t.Errorf("Wrong claims in context: %+v", claims)
+	}
+	if cookies := c.Response().Header().Get("Set-Cookie"); cookies != "" {
+		t.Errorf("API keys should not set cookies, got '%s'", cookies)
+	}
+
+	if _, err := makeReq(JWTConfig{SigningKey: []byte("secret"), APIKeys: keys}, APIKeyPrefix+"unknown"); err == nil {
+		t.Errorf("Unknown API key should be rejected")
+	}
+
+	if _, err := makeReq(JWTConfig{SigningKey: []byte("secret"), APIKeys: keys, AllowedRole: "admin"}, validKey); err == nil {
+		t.Errorf("API key without the allowed role should be rejected")
+	}
+
+	// Services that do not opt in treat the key as a malformed JWT
+	if _, err := makeReq(JWTConfig{SigningKey: []byte("secret")}, validKey); err == nil {
+		t.Errorf("API key should be rejected without a validator")
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	claims := &JWTClaims{Scopes: []string{"lineups:read", "lineups:write"}}
+	if !claims.HasScope("lineups:write") {
+		t.Errorf("Scope 'lineups:write' should be found")
+	}
+	if claims.HasScope("lineups") {
+		t.Errorf("Partial scope should not match")
+	}
+}
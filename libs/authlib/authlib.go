@@ -13,11 +13,16 @@ import (
 
 // JWTClaims holds esportsdrafts auth claims. Roles array denotes what the user
 // can do within the application. For example and 'admin' would have elevated
-// access compared to a 'user'.
+// access compared to a 'user'. Scopes narrow down what a token may be used
+// for, they are only set for delegated access like personal API keys.
+// SessionID ties user tokens to a server-side session so they can be revoked
+// together.
 type JWTClaims struct {
-	Username string   `json:"username"`
-	UserID   string   `json:"user_id"`
-	Roles    []string `json:"roles"`
+	Username  string   `json:"username"`
+	UserID    string   `json:"user_id"`
+	Roles     []string `json:"roles"`
+	Scopes    []string `json:"scopes,omitempty"`
+	SessionID string   `json:"sid,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -25,17 +30,48 @@ type JWTClaims struct {
 type (
 	// JWTConfig defines the config for JWT middleware.
 	JWTConfig struct {
-		// Signing key to validate token. Used as fallback if SigningKeys has length 0.
-		// Required. This or SigningKeys.
+		// Signing key to validate token. Used as fallback if Keys is nil.
+		// Required. This or Keys.
 		SigningKey []byte
 
+		// Asymmetric (or HMAC) keys to validate tokens with. If the set has no
+		// private key the middleware runs in verification-only mode and
+		// browser cookies are not refreshed.
+		Keys *KeySet
+
+		// Optional. Tokens must carry this role. Shorthand for a Policy
+		// with AllRoles, 'user' if the policy has no role condition either.
 		AllowedRole string
+
+		// Optional. Tokens must carry all of these scopes on top of the
+		// allowed role.
+		RequiredScopes []string
+
+		// Optional. Role and scope conditions tokens must satisfy, combined
+		// with AllowedRole and RequiredScopes.
+		Policy Policy
+
+		// Optional. Tokens with a jti in the store are rejected.
+		RevocationStore RevocationStore
+
+		// Optional. Tokens of revoked or expired sessions are rejected, and
+		// so are user tokens without a session. Browser cookies are only
+		// refreshed while the session is active.
+		Sessions SessionValidator
+
+		// Optional. Accept personal API keys in the Authorization header in
+		// place of a JWT. Keys are rejected if nil.
+		APIKeys APIKeyValidator
 	}
 )
 
 const (
 	// DefaultCookiePayloadTimeout denotes the payload cookie expiry
 	DefaultCookiePayloadTimeout = 60 * time.Minute
+
+	// ServiceRolePrefix starts the role of tokens issued to services, see
+	// ServiceRole
+	ServiceRolePrefix = "service:"
 )
 
 var (
@@ -44,50 +80,46 @@ var (
 )
 
 // JWTMiddleware will check if token/cookie has correct signature,
-// and if the allowed roles are in token
+// and if the token satisfies the configured policy
 func JWTMiddleware(config JWTConfig) echo.MiddlewareFunc {
-	if config.SigningKey == nil {
-		panic("JWT auth middleware requires signing secret")
+	keys := config.Keys
+	if keys == nil {
+		if config.SigningKey == nil {
+			panic("JWT auth middleware requires signing secret")
+		}
+		keys = NewKeySet(NewHMACKey(config.SigningKey))
 	}
 
-	if config.AllowedRole == "" {
-		config.AllowedRole = "user"
-	}
+	policy := config.policy()
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(ctx echo.Context) error {
 			// Try and grab token from cookies since this is
 			// probably a browser
-			var rawToken string
-			var err error
-
 			isBrowser := HasRequestedWithHeader(ctx)
 
-			if isBrowser {
-				rawToken, err = readAuthCookies(ctx)
-				if err != nil {
-					return &echo.HTTPError{
-						Code:     http.StatusUnauthorized,
-						Message:  "missing or invalid JWT in request",
-						Internal: err,
-					}
+			if config.APIKeys != nil && !isBrowser {
+				if key, ok := getAPIKeyFromHeader(ctx); ok {
+					return authWithAPIKey(ctx, next, config, policy, key)
 				}
-			} else {
-				rawToken, err = getAuthTokenFromHeader(ctx)
-				if err != nil {
-					return &echo.HTTPError{
-						Code:     http.StatusUnauthorized,
-						Message:  "missing or invalid JWT in request",
-						Internal: err,
-					}
+			}
+
+			rawToken, err := ReadAuthToken(ctx)
+			if err != nil {
+				return &echo.HTTPError{
+					Code:     http.StatusUnauthorized,
+					Message:  "missing or invalid JWT in request",
+					Internal: err,
 				}
 			}
 
-			token, err := jwt.ParseWithClaims(rawToken, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-				return config.SigningKey, nil
-			})
+			token, err := jwt.ParseWithClaims(rawToken, &JWTClaims{}, keys.keyFunc)
 
-			claims, ok := token.Claims.(*JWTClaims)
+			var claims *JWTClaims
+			ok := false
+			if token != nil {
+				claims, ok = token.Claims.(*JWTClaims)
+			}
 
 			if err != nil || !ok {
 				if err == jwt.ErrSignatureInvalid {
@@ -104,13 +136,48 @@ func JWTMiddleware(config JWTConfig) echo.MiddlewareFunc {
 				}
 			}
 
-			if token.Valid && contains(claims.Roles, config.AllowedRole) {
+			if token.Valid && config.RevocationStore != nil {
+				revoked, err := config.RevocationStore.IsRevoked(claims.Id)
+				if err != nil {
+					return &echo.HTTPError{
+						Code:     http.StatusInternalServerError,
+						Message:  "failed to check JWT revocation",
+						Internal: err,
+					}
+				}
+				if revoked {
+					return &echo.HTTPError{
+						Code:    http.StatusUnauthorized,
+						Message: "invalid or expired JWT in request",
+					}
+				}
+			}
+
+			if token.Valid && config.Sessions != nil {
+				active, err := sessionActive(config.Sessions, claims)
+				if err != nil {
+					return &echo.HTTPError{
+						Code:     http.StatusInternalServerError,
+						Message:  "failed to check session",
+						Internal: err,
+					}
+				}
+				if !active {
+					return &echo.HTTPError{
+						Code:    http.StatusUnauthorized,
+						Message: "session has ended, please log in again",
+					}
+				}
+			}
+
+			if token.Valid && policy.Allows(claims) {
 				// Store user information from token into context.
 				ctx.Set("user", claims)
 
-				// Update the payload cookie with new expiry
-				if isBrowser {
-					tokenString, _, err := GenerateAuthToken(claims, DefaultCookiePayloadTimeout, config.SigningKey)
+				// Update the payload cookie with new expiry. Only possible if
+				// this service holds the signing key.
+				if isBrowser && keys.CanSign() {
+					tokenString, _, err := GenerateAuthTokenWithKeys(claims, DefaultCookiePayloadTimeout, keys)
 					if err != nil {
 						return &echo.HTTPError{
 							Code:     http.StatusInternalServerError,
@@ -168,6 +235,15 @@ func HasRequestedWithHeader(ctx echo.Context) bool {
 	return ctx.Request().Header.Get("X-Requested-With") == "XMLHttpRequest"
 }
 
+// ReadAuthToken reads the raw JWT from the request. Browsers send it split in
+// two cookies, other clients in the Authorization header.
+func ReadAuthToken(ctx echo.Context) (string, error) {
+	if HasRequestedWithHeader(ctx) {
+		return readAuthCookies(ctx)
+	}
+	return getAuthTokenFromHeader(ctx)
+}
+
 // getAuthTokenFromHeader grabs JWT token from header entry
 func getAuthTokenFromHeader(ctx echo.Context) (string, error) {
 	headerContent := ctx.Request().Header.Get(echo.HeaderAuthorization)
@@ -206,6 +282,20 @@ func WriteSignatureCookie(ctx echo.Context, signature string) {
 	ctx.SetCookie(cookie)
 }
 
+// ClearAuthCookies expires both auth cookies in the browser
+func ClearAuthCookies(ctx echo.Context) {
+	for _, name := range []string{"header.payload", "signature"} {
+		cookie := new(http.Cookie)
+		cookie.Name = name
+		cookie.Value = ""
+		cookie.Secure = true
+		cookie.HttpOnly = name == "signature"
+		cookie.MaxAge = -1
+		cookie.Expires = time.Unix(0, 0)
+		ctx.SetCookie(cookie)
+	}
+}
+
 // readAuthCookies get both header and signature from cookies
 func readAuthCookies(ctx echo.Context) (string, error) {
 	headerCookie, err := ctx.Cookie("header.payload")
@@ -225,20 +315,62 @@ func reconstructAuthToken(header, signature string) string {
 	return header + "." + signature
 }
 
-// GenerateAuthToken generates a auth token with provided claims
+// GenerateAuthToken generates a HS256 auth token with provided claims
 func GenerateAuthToken(claims *JWTClaims, expiry time.Duration, jwtKey []byte) (string, time.Time, error) {
+	return GenerateAuthTokenWithKeys(claims, expiry, NewKeySet(NewHMACKey(jwtKey)))
+}
+
+// GenerateAuthTokenWithKeys generates an auth token with provided claims
+// signed by the signing key of the key set
+func GenerateAuthTokenWithKeys(claims *JWTClaims, expiry time.Duration, keys *KeySet) (string, time.Time, error) {
 	issuedTime := time.Now()
 	expirationTime := issuedTime.Add(expiry)
 	claims.StandardClaims = jwt.StandardClaims{
 		// In JWT, the expiry time is expressed as unix milliseconds
 		ExpiresAt: expirationTime.Unix(),
-		// Can be used to blacklist in the future. Needs to hold state
-		// in that case :/
+		// Used to revoke single tokens, see RevocationStore
 		Id:       uuid.NewV4().String(),
 		IssuedAt: issuedTime.Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	res, err := token.SignedString(jwtKey)
+	res, err := keys.Sign(claims)
 	return res, expirationTime, err
 }
+
+// ParseAuthToken parses a HS256 signed token string and validates its
+// signature and expiry. Returns the claims of the token if valid.
+func ParseAuthToken(tokenString string, jwtKey []byte) (*JWTClaims, error) {
+	return ParseAuthTokenWithKeys(tokenString, NewKeySet(NewHMACKey(jwtKey)))
+}
+
+// ParseAuthTokenWithKeys parses a token string signed by any key in the key
+// set and validates its signature and expiry
+func ParseAuthTokenWithKeys(tokenString string, keys *KeySet) (*JWTClaims, error) {
+	return keys.Parse(tokenString)
+}
+
+// HasRole returns true if role is one of the roles in the claims
+func (c *JWTClaims) HasRole(role string) bool {
+	return contains(c.Roles, role)
+}
+
+// ServiceRole returns the role carried by tokens issued to the named service,
+// e.g. 'service:notifications'. Use as AllowedRole to only let that service in.
+func ServiceRole(name string) string {
+	return ServiceRolePrefix + name
+}
+
+// IsService returns true if the claims belong to a service rather than a user
+func (c *JWTClaims) IsService() bool {
+	for _, role := range c.Roles {
+		if strings.HasPrefix(role, ServiceRolePrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope returns true if scope is one of the scopes in the claims
+func (c *JWTClaims) HasScope(scope string) bool {
+	return contains(c.Scopes, scope)
+}
@@ -331,3 +331,57 @@ func TestGenerateAuthToken(t *testing.T) {
 		t.Errorf("Expiry in claims different from plain token expiration. Got: %d, wanted: %d", expTimeUnix, readExpTime)
 	}
 }
+
+func TestRequiredScopes(t *testing.T) {
+	e := echo.New()
+	handler := func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	}
+	validKey := []byte("secret")
+
+	h := JWTMiddleware(JWTConfig{
+		SigningKey:     validKey,
+		AllowedRole:    ServiceRole("notifications"),
+		RequiredScopes: []string{"accounts:read"},
+	})(handler)
+
+	makeReq := func(claims *JWTClaims) error {
+		token, _, err := GenerateAuthToken(claims, time.Minute, validKey)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %+v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		req.Header.Set(echo.HeaderAuthorization, "Bearer: "+token)
+		return h(e.NewContext(req, res))
+	}
+
+	service := &JWTClaims{
+		Username: "notifications",
+		Roles:    []string{ServiceRole("notifications")},
+		Scopes:   []string{"accounts:read", "emails:send"},
+	}
+	if err := makeReq(service); err != nil {
+		t.Errorf("Service with the scope should be accepted: %+v", err)
+	}
+	if !service.IsService() {
+		t.Errorf("Claims with a service role should be a service")
+	}
+
+	service.Scopes = []string{"emails:send"}
+	if err := makeReq(service); err == nil {
+		t.Errorf("Service without the scope should be rejected")
+	}
+
+	user := &JWTClaims{
+		Username: "pelle",
+		Roles:    []string{"user"},
+		Scopes:   []string{"accounts:read"},
+	}
+	if err := makeReq(user); err == nil {
+		t.Errorf("User should be rejected even with the scope")
+	}
+	if user.IsService() {
+		t.Errorf("User claims should not be a service")
+	}
+}
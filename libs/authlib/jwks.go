@@ -0,0 +1,220 @@
+package authlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const jwksFetchTimeout = 10 * time.Second
+
+// JWK is a single public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC and OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a set of public keys as served on /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set. Symmetric keys are never
+// included.
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := time.Now()
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		if key.IsSymmetric() || !key.isActive(now) {
+			continue
+		}
+		jwk, err := key.JWK()
+		if err != nil {
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+// JWK encodes the public part of the key
+func (k *Key) JWK() (JWK, error) {
+	jwk := JWK{
+		Kid: k.ID,
+		Use: "sig",
+		Alg: k.Method.Alg(),
+	}
+
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeJWKInt(pub.N.Bytes())
+		jwk.E = encodeJWKInt(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = encodeJWKInt(padBytes(pub.X.Bytes(), size))
+		jwk.Y = encodeJWKInt(padBytes(pub.Y.Bytes(), size))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeJWKInt(pub)
+	default:
+		return JWK{}, fmt.Errorf("unsupported public key type %T", k.Public)
+	}
+	return jwk, nil
+}
+
+// Key decodes a JWK into a verification-only key
+func (j JWK) Key() (*Key, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return &Key{ID: j.Kid, Method: jwt.SigningMethodRS256, Public: pub}, nil
+	case "EC":
+		if j.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", j.Crv)
+		}
+		x, err := decodeJWKInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("invalid EC public key")
+		}
+		return &Key{ID: j.Kid, Method: jwt.SigningMethodES256, Public: pub}, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", j.Crv)
+		}
+		x, err := decodeJWKInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 public key")
+		}
+		return &Key{ID: j.Kid, Method: SigningMethodEd25519, Public: ed25519.PublicKey(x)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", j.Kty)
+	}
+}
+
+// ParseJWKS decodes a JWKS document into a verification-only key set
+func ParseJWKS(data []byte) (*KeySet, error) {
+	keys, err := parseJWKSKeys(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySet(keys...), nil
+}
+
+func parseJWKSKeys(data []byte) ([]*Key, error) {
+	var jwks JWKS
+	err := json.Unmarshal(data, &jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*Key
+	for _, jwk := range jwks.Keys {
+		key, err := jwk.Key()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys found in JWKS")
+	}
+	return keys, nil
+}
+
+// LoadJWKS loads a verification-only key set from a JWKS file or an
+// http(s) URL, typically the auth service's /.well-known/jwks.json. The set
+// is loaded again when a token signed by an unknown key ID shows up, which
+// is how new keys are picked up after rotation.
+func LoadJWKS(source string) (*KeySet, error) {
+	return newReloadingKeySet(func() ([]*Key, error) {
+		var data []byte
+		var err error
+		if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+			data, err = fetchJWKS(source)
+		} else {
+			data, err = ioutil.ReadFile(source)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return parseJWKSKeys(data)
+	})
+}
+
+func fetchJWKS(url string) ([]byte, error) {
+	client := &http.Client{Timeout: jwksFetchTimeout}
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS, status %d", res.StatusCode)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+func encodeJWKInt(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeJWKInt(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
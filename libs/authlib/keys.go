@@ -0,0 +1,335 @@
+package authlib
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Key is a key used to sign and/or verify tokens. Private is nil for keys
+// that can only verify, which is the case for keys loaded from a JWKS.
+type Key struct {
+	// ID is sent as 'kid' header in tokens so verifiers can pick the right
+	// key when several are active
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+	// NotAfter limits how long a retired key is accepted for verification.
+	// Zero means no limit.
+	NotAfter time.Time
+}
+
+// KeySet holds the keys a service signs and verifies tokens with. The first
+// key with a private part is used for signing. A set without private keys
+// can only verify tokens.
+type KeySet struct {
+	mu   sync.RWMutex
+	keys []*Key
+
+	// Optional, used to reload keys when rotated
+	source     func() ([]*Key, error)
+	lastReload time.Time
+}
+
+const (
+	// DefaultKeyGracePeriod is how long the previous signing key is still
+	// accepted after rotation. Long enough for every access token signed
+	// with it to expire.
+	DefaultKeyGracePeriod = 2 * DefaultCookiePayloadTimeout
+
+	// Minimum time between reloads triggered by unknown key IDs
+	keyReloadInterval = time.Minute
+
+	// Key file names start with the UTC time the key was put in place, or
+	// just the day. Kubernetes secret keys can not contain ':'.
+	keyRotationTimeLayout = "2006-01-02T150405Z"
+	keyRotationDateLayout = "2006-01-02"
+)
+
+// NewKeySet creates a key set from keys
+func NewKeySet(keys ...*Key) *KeySet {
+	return &KeySet{keys: keys}
+}
+
+// newReloadingKeySet creates a key set that can be reloaded from source
+func newReloadingKeySet(source func() ([]*Key, error)) (*KeySet, error) {
+	ks := &KeySet{source: source}
+	err := ks.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Reload fetches the keys again from the source of the set, e.g. after a
+// new key has been put in the key directory. No-op for static sets.
+func (ks *KeySet) Reload() error {
+	if ks.source == nil {
+		return nil
+	}
+
+	keys, err := ks.source()
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys = keys
+	ks.lastReload = time.Now()
+	return nil
+}
+
+// NewHMACKey creates a symmetric HS256 key from a shared secret
+func NewHMACKey(secret []byte) *Key {
+	return &Key{
+		Method:  jwt.SigningMethodHS256,
+		Private: secret,
+		Public:  secret,
+	}
+}
+
+// IsSymmetric returns true for shared secret keys that must never be
+// published
+func (k *Key) IsSymmetric() bool {
+	_, ok := k.Method.(*jwt.SigningMethodHMAC)
+	return ok
+}
+
+// isActive returns false once a retired key is past its grace window
+func (k *Key) isActive(now time.Time) bool {
+	return k.NotAfter.IsZero() || now.Before(k.NotAfter)
+}
+
+// SigningKey returns the key new tokens are signed with. Nil if the set is
+// verification only.
+func (ks *KeySet) SigningKey() *Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	for _, key := range ks.keys {
+		if key.Private != nil && key.NotAfter.IsZero() {
+			return key
+		}
+	}
+	return nil
+}
+
+// CanSign returns true if the set holds a private key
+func (ks *KeySet) CanSign() bool {
+	return ks.SigningKey() != nil
+}
+
+// Sign signs claims with the signing key of the set
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	key := ks.SigningKey()
+	if key == nil {
+		return "", fmt.Errorf("key set has no signing key")
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.Private)
+}
+
+// Parse parses and validates a token using the key matching its kid and
+// algorithm. Only accepting keys whose method matches the token header prevents
+// algorithm confusion, e.g. an RSA public key used as HMAC secret.
+func (ks *KeySet) Parse(tokenString string) (*JWTClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, ks.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return claims, nil
+}
+
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key := ks.findKey(kid, token.Method.Alg())
+	// Key might have been rotated since we last loaded the set
+	if key == nil && kid != "" && ks.shouldReload() {
+		ks.Reload()
+		key = ks.findKey(kid, token.Method.Alg())
+	}
+
+	if key == nil {
+		return nil, fmt.Errorf("no key found for kid '%s' and alg '%v'", kid, token.Header["alg"])
+	}
+	return key.Public, nil
+}
+
+// findKey picks the active key with the given ID and algorithm. Tokens
+// without kid, issued before keys had IDs, match on algorithm only.
+func (ks *KeySet) findKey(kid string, alg string) *Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := time.Now()
+	for _, key := range ks.keys {
+		if key.Method.Alg() != alg || !key.isActive(now) {
+			continue
+		}
+		if kid == "" || key.ID == kid {
+			return key
+		}
+	}
+	return nil
+}
+
+func (ks *KeySet) shouldReload() bool {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.source != nil && time.Since(ks.lastReload) > keyReloadInterval
+}
+
+// LoadKeyDirectory loads every PEM private key in dir, using the file name
+// without extension as key ID. Names start with the UTC time the key was put
+// in place, like '2019-11-24T153000Z.pem' or just '2019-11-24.pem'. Keys are
+// ordered by name and the last one is the signing key. The previous keys are
+// only accepted for verification until grace has passed since the time in
+// the name of the current key, or since the end of its day if the name only
+// has a date.
+func LoadKeyDirectory(dir string, grace time.Duration) (*KeySet, error) {
+	return newReloadingKeySet(func() ([]*Key, error) {
+		return readKeyDirectory(dir, grace)
+	})
+}
+
+func readKeyDirectory(dir string, grace time.Duration) ([]*Key, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var pemFiles []os.FileInfo
+	for _, file := range files {
+		if !file.IsDir() && filepath.Ext(file.Name()) == ".pem" {
+			pemFiles = append(pemFiles, file)
+		}
+	}
+
+	if len(pemFiles) == 0 {
+		return nil, fmt.Errorf("no .pem keys found in %s", dir)
+	}
+
+	// Newest key first so it is picked for signing
+	sort.Slice(pemFiles, func(i, j int) bool {
+		return pemFiles[i].Name() > pemFiles[j].Name()
+	})
+	rotatedAt, err := keyRotationTime(pemFiles[0].Name())
+	if err != nil {
+		return nil, err
+	}
+	retiredAfter := rotatedAt.Add(grace)
+
+	var keys []*Key
+	for i, file := range pemFiles {
+		key, err := LoadPrivateKeyFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key %s: %s", file.Name(), err)
+		}
+		key.ID = strings.TrimSuffix(file.Name(), ".pem")
+
+		if i > 0 {
+			// Retired keys never sign again
+			key.Private = nil
+			key.NotAfter = retiredAfter
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// keyRotationTime returns when the key in the named file was put in place.
+// File times are not used, copying or mounting keys changes them.
+func keyRotationTime(name string) (time.Time, error) {
+	if len(name) >= len(keyRotationTimeLayout) {
+		rotatedAt, err := time.Parse(keyRotationTimeLayout, name[:len(keyRotationTimeLayout)])
+		if err == nil {
+			return rotatedAt, nil
+		}
+	}
+	if len(name) >= len(keyRotationDateLayout) {
+		day, err := time.Parse(keyRotationDateLayout, name[:len(keyRotationDateLayout)])
+		if err == nil {
+			// Could have been any time that day
+			return day.AddDate(0, 0, 1), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("key %s does not start with the date it was put in place", name)
+}
+
+// LoadPrivateKeyFile loads a PEM encoded private key from disk
+func LoadPrivateKeyFile(path string) (*Key, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateKeyPEM(data)
+}
+
+// ParsePrivateKeyPEM parses a PEM encoded RSA, ECDSA P-256 or Ed25519 private
+// key in PKCS#8, PKCS#1 or SEC 1 format. The signing method is picked from
+// the key type.
+func ParsePrivateKeyPEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	var privateKey interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPrivateKey(privateKey)
+}
+
+// NewPrivateKey wraps a private key picking the signing method from its type
+func NewPrivateKey(privateKey interface{}) (*Key, error) {
+	switch k := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return &Key{Method: jwt.SigningMethodRS256, Private: k, Public: &k.PublicKey}, nil
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("only P-256 ECDSA keys are supported")
+		}
+		return &Key{Method: jwt.SigningMethodES256, Private: k, Public: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{Method: SigningMethodEd25519, Private: k, Public: k.Public()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+}
@@ -0,0 +1,312 @@
+package authlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func generateTestKeys(t *testing.T) []*Key {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %+v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %+v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %+v", err)
+	}
+
+	var keys []*Key
+	for _, private := range []interface{}{rsaKey, ecKey, edKey} {
+		key, err := NewPrivateKey(private)
+		if err != nil {
+			t.Fatalf("Failed to wrap private key: %+v", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestSignAndVerifyWithJWKS(t *testing.T) {
+	for _, key := range generateTestKeys(t) {
+		signer := NewKeySet(key)
+		claims := &JWTClaims{
+			Username: "pelle",
+			UserID:   "random_id",
+			Roles:    []string{"user"},
+		}
+		token, _, err := GenerateAuthTokenWithKeys(claims, time.Minute, signer)
+		if err != nil {
+			t.Fatalf("Failed to sign with %s: %+v", key.Method.Alg(), err)
+		}
+
+		// Round trip public keys through JSON like a downstream service would
+		data, err := json.Marshal(signer.JWKS())
+		if err != nil {
+			t.Fatalf("Failed to marshal JWKS: %+v", err)
+		}
+		verifier, err := ParseJWKS(data)
+		if err != nil {
+			t.Fatalf("Failed to parse JWKS for %s: %+v", key.Method.Alg(), err)
+		}
+
+		if verifier.CanSign() {
+			t.Errorf("Key set from JWKS should be verification only")
+		}
+
+		readClaims, err := ParseAuthTokenWithKeys(token, verifier)
+		if err != nil {
+			t.Errorf("Failed to verify %s token: %+v", key.Method.Alg(), err)
+			continue
+		}
+		if readClaims.Username != "pelle" {
+			t.Errorf("Wrong claims read from %s token", key.Method.Alg())
+		}
+	}
+}
+
+func TestHMACKeyNotPublished(t *testing.T) {
+	jwks := NewKeySet(NewHMACKey([]byte("secret"))).JWKS()
+	if len(jwks.Keys) != 0 {
+		t.Errorf("Shared secrets must never be part of the JWKS")
+	}
+}
+
+func TestRejectAlgorithmConfusion(t *testing.T) {
+	keys := generateTestKeys(t)
+	rsaOnly := NewKeySet(keys[0])
+
+	// HS256 token signed with some secret must not verify against RSA keys
+	token, _, err := GenerateAuthToken(&JWTClaims{Username: "evil"}, time.Minute, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %+v", err)
+	}
+	_, err = ParseAuthTokenWithKeys(token, rsaOnly)
+	if err == nil {
+		t.Errorf("HS256 token accepted by RSA key set")
+	}
+}
+
+func TestParsePrivateKeyPEM(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %+v", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	key, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		t.Fatalf("Failed to parse PEM: %+v", err)
+	}
+	if key.Method.Alg() != "ES256" {
+		t.Errorf("Wrong signing method, got %s, wanted ES256", key.Method.Alg())
+	}
+
+	_, err = ParsePrivateKeyPEM([]byte("not a key"))
+	if err == nil {
+		t.Errorf("Parsing garbage should fail")
+	}
+}
+
+func TestVerificationOnlyMiddleware(t *testing.T) {
+	e := echo.New()
+	handler := func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	}
+
+	signer := NewKeySet(generateTestKeys(t)[2])
+	verifier := NewKeySet(&Key{Method: SigningMethodEd25519, Public: signer.SigningKey().Public})
+
+	h := JWTMiddleware(JWTConfig{
+		Keys: verifier,
+	})(handler)
+
+	claims := &JWTClaims{Username: "pelle", Roles: []string{"user"}}
+	token, _, err := GenerateAuthTokenWithKeys(claims, time.Minute, signer)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %+v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	res := httptest.NewRecorder()
+	split := strings.LastIndex(token, ".")
+	req.AddCookie(&http.Cookie{Name: "header.payload", Value: token[:split]})
+	req.AddCookie(&http.Cookie{Name: "signature", Value: token[split+1:]})
+	req.Header.Set("X-Requested-With", "XMLHttpRequest")
+
+	err = h(e.NewContext(req, res))
+	if err != nil {
+		t.Errorf("Valid token rejected: %+v", err)
+	}
+
+	// Cookies can not be refreshed without the private key
+	if len(res.Result().Cookies()) != 0 {
+		t.Errorf("Verification-only middleware should not set cookies")
+	}
+}
+
+func writeTestKeyFile(t *testing.T, dir string, name string, modTime time.Time) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %+v", err)
+	}
+	path := filepath.Join(dir, name)
+	err = ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write key: %+v", err)
+	}
+	os.Chtimes(path, modTime, modTime)
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwt-keys")
+	if err != nil {
+		t.Fatalf("Failed to create key dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestKeyFile(t, dir, "2019-01-01.pem", time.Now())
+	keys, err := LoadKeyDirectory(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to load key dir: %+v", err)
+	}
+
+	claims := &JWTClaims{Username: "pelle", Roles: []string{"user"}}
+	oldToken, _, err := GenerateAuthTokenWithKeys(claims, time.Minute, keys)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %+v", err)
+	}
+
+	// Rotate in a new key, the old one should still verify during grace.
+	// The file time is from long ago, only the name counts.
+	newID := time.Now().UTC().Format(keyRotationTimeLayout)
+	writeTestKeyFile(t, dir, newID+".pem", time.Now().Add(-2*time.Hour))
+	err = keys.Reload()
+	if err != nil {
+		t.Fatalf("Failed to reload keys: %+v", err)
+	}
+
+	if keys.SigningKey().ID != newID {
+		t.Errorf("Wrong signing key after rotation, got %s", keys.SigningKey().ID)
+	}
+
+	newToken, _, err := GenerateAuthTokenWithKeys(claims, time.Minute, keys)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %+v", err)
+	}
+
+	for _, token := range []string{oldToken, newToken} {
+		_, err = ParseAuthTokenWithKeys(token, keys)
+		if err != nil {
+			t.Errorf("Token rejected during grace window: %+v", err)
+		}
+	}
+
+	if len(keys.JWKS().Keys) != 2 {
+		t.Errorf("Both keys should be published during grace window")
+	}
+
+	// Put in place long ago by its name, even though the file is new
+	os.Remove(filepath.Join(dir, newID+".pem"))
+	newID = time.Now().Add(-2 * time.Hour).UTC().Format(keyRotationTimeLayout)
+	writeTestKeyFile(t, dir, newID+".pem", time.Now())
+	keys.Reload()
+
+	_, err = ParseAuthTokenWithKeys(oldToken, keys)
+	if err == nil {
+		t.Errorf("Token signed with retired key accepted after grace window")
+	}
+	if len(keys.JWKS().Keys) != 1 || keys.JWKS().Keys[0].Kid != newID {
+		t.Errorf("Only the current key should be published after grace window")
+	}
+}
+
+func TestKeyRotationTime(t *testing.T) {
+	rotatedAt, err := keyRotationTime("2019-11-24T153000Z.pem")
+	if err != nil || !rotatedAt.Equal(time.Date(2019, 11, 24, 15, 30, 0, 0, time.UTC)) {
+		t.Errorf("Wrong rotation time %s: %+v", rotatedAt, err)
+	}
+
+	// Only the day is known, so the grace window starts when it ends
+	rotatedAt, err = keyRotationTime("2019-11-24-ec.pem")
+	if err != nil || !rotatedAt.Equal(time.Date(2019, 11, 25, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Wrong rotation time %s: %+v", rotatedAt, err)
+	}
+
+	for _, name := range []string{"current.pem", "24-11-2019.pem", "2019.pem"} {
+		_, err = keyRotationTime(name)
+		if err == nil {
+			t.Errorf("Key name without date %s accepted", name)
+		}
+	}
+}
+
+func TestKeyDirectoryWithoutDates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwt-keys")
+	if err != nil {
+		t.Fatalf("Failed to create key dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestKeyFile(t, dir, "current.pem", time.Now())
+	_, err = LoadKeyDirectory(dir, time.Hour)
+	if err == nil {
+		t.Errorf("Signing key without date in name accepted")
+	}
+}
+
+func TestSelectKeyByKid(t *testing.T) {
+	first, _ := NewPrivateKey(mustGenerateECKey(t))
+	first.ID = "first"
+	second, _ := NewPrivateKey(mustGenerateECKey(t))
+	second.ID = "second"
+
+	// Same algorithm, so only the kid tells them apart
+	token, _, err := GenerateAuthTokenWithKeys(&JWTClaims{Username: "pelle"}, time.Minute, NewKeySet(second))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %+v", err)
+	}
+
+	verifier := NewKeySet(
+		&Key{ID: first.ID, Method: first.Method, Public: first.Public},
+		&Key{ID: second.ID, Method: second.Method, Public: second.Public},
+	)
+	_, err = ParseAuthTokenWithKeys(token, verifier)
+	if err != nil {
+		t.Errorf("Failed to verify token by kid: %+v", err)
+	}
+
+	_, err = ParseAuthTokenWithKeys(token, NewKeySet(first))
+	if err == nil {
+		t.Errorf("Token accepted with unknown kid")
+	}
+}
+
+func mustGenerateECKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %+v", err)
+	}
+	return key
+}
@@ -0,0 +1,129 @@
+package authlib
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Policy decides which claims may use a route. Every non-empty condition
+// must hold.
+type Policy struct {
+	// Claims must carry at least one of these roles
+	AnyRole []string
+	// Claims must carry every one of these roles
+	AllRoles []string
+	// Claims must carry at least one of these scopes
+	AnyScope []string
+	// Claims must carry every one of these scopes
+	AllScopes []string
+}
+
+// AnyRole returns a policy letting in claims with at least one of roles,
+// e.g. AnyRole("admin", "support")
+func AnyRole(roles ...string) Policy {
+	return Policy{AnyRole: roles}
+}
+
+// AllRoles returns a policy letting in claims with every one of roles, e.g.
+// AllRoles("user", "verified_age")
+func AllRoles(roles ...string) Policy {
+	return Policy{AllRoles: roles}
+}
+
+// WithAnyScope returns a copy of the policy also requiring at least one of
+// scopes
+func (p Policy) WithAnyScope(scopes ...string) Policy {
+	p.AnyScope = append(append([]string{}, p.AnyScope...), scopes...)
+	return p
+}
+
+// WithAllScopes returns a copy of the policy also requiring every one of
+// scopes, e.g. AnyRole("user").WithAllScopes("lineups:write")
+func (p Policy) WithAllScopes(scopes ...string) Policy {
+	p.AllScopes = append(append([]string{}, p.AllScopes...), scopes...)
+	return p
+}
+
+// Allows returns true if claims satisfy the policy
+func (p Policy) Allows(claims *JWTClaims) bool {
+	if claims == nil {
+		return false
+	}
+	return containsAny(claims.Roles, p.AnyRole) && containsAll(claims.Roles, p.AllRoles) &&
+		containsAny(claims.Scopes, p.AnyScope) && containsAll(claims.Scopes, p.AllScopes)
+}
+
+// policy combines Policy with the AllowedRole and RequiredScopes fields.
+// Tokens need the 'user' role if no role condition is configured.
+func (config JWTConfig) policy() Policy {
+	p := config.Policy
+	if config.AllowedRole != "" {
+		p.AllRoles = append(append([]string{}, p.AllRoles...), config.AllowedRole)
+	}
+	if len(config.RequiredScopes) > 0 {
+		p = p.WithAllScopes(config.RequiredScopes...)
+	}
+	if len(p.AnyRole) == 0 && len(p.AllRoles) == 0 {
+		p.AllRoles = []string{"user"}
+	}
+	return p
+}
+
+// RequirePolicy checks the claims stored by JWTMiddleware against policy.
+// Lets a route group share one JWTMiddleware and narrow access further, e.g.
+//
+//	admin := e.Group("/v1/admin", auth, RequirePolicy(AnyRole("admin", "support")))
+//
+// Tokens that are valid but not allowed get 403 since retrying with the same
+// identity will not help.
+func RequirePolicy(policy Policy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			claims, ok := ClaimsFromContext(ctx)
+			if !ok {
+				return &echo.HTTPError{
+					Code:    http.StatusUnauthorized,
+					Message: "missing or invalid JWT in request",
+				}
+			}
+			if !policy.Allows(claims) {
+				return &echo.HTTPError{
+					Code:    http.StatusForbidden,
+					Message: "insufficient roles or scopes",
+				}
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// ClaimsFromContext returns the claims stored by JWTMiddleware, false if the
+// request was not authenticated
+func ClaimsFromContext(ctx echo.Context) (*JWTClaims, bool) {
+	claims, ok := ctx.Get("user").(*JWTClaims)
+	return claims, ok && claims != nil
+}
+
+// containsAny returns true if a holds at least one of xs, or xs is empty
+func containsAny(a []string, xs []string) bool {
+	if len(xs) == 0 {
+		return true
+	}
+	for _, x := range xs {
+		if contains(a, x) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAll returns true if a holds every one of xs
+func containsAll(a []string, xs []string) bool {
+	for _, x := range xs {
+		if !contains(a, x) {
+			return false
+		}
+	}
+	return true
+}
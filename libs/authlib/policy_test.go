@@ -0,0 +1,136 @@
+package authlib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestPolicyAllows(t *testing.T) {
+	claims := &JWTClaims{
+		Roles:  []string{"user", "support"},
+		Scopes: []string{"lineups:read", "lineups:write"},
+	}
+	cases := []struct {
+		name   string
+		policy Policy
+		allows bool
+	}{
+		{"any role match", AnyRole("admin", "support"), true},
+		{"any role no match", AnyRole("admin", "moderator"), false},
+		{"all roles match", AllRoles("user", "support"), true},
+		{"all roles missing one", AllRoles("user", "verified_age"), false},
+		{"all scopes match", AllRoles("user").WithAllScopes("lineups:read", "lineups:write"), true},
+		{"all scopes missing one", AllRoles("user").WithAllScopes("lineups:write", "contests:read"), false},
+		{"any scope match", AnyRole("user").WithAnyScope("contests:read", "lineups:read"), true},
+		{"any scope no match", AnyRole("user").WithAnyScope("contests:read"), false},
+		{"empty policy", Policy{}, true},
+	}
+	for _, c := range cases {
+		if got := c.policy.Allows(claims); got != c.allows {
+			t.Errorf("%s: got %t, wanted %t", c.name, got, c.allows)
+		}
+	}
+
+	if AnyRole("user").Allows(nil) {
+		t.Errorf("Nil claims should never be allowed")
+	}
+}
+
+func TestPolicyWithScopesCopies(t *testing.T) {
+	base := AllRoles("user").WithAllScopes("lineups:read")
+	write := base.WithAllScopes("lineups:write")
+	read := base.WithAllScopes("contests:read")
+	if len(base.AllScopes) != 1 || write.AllScopes[1] != "lineups:write" || read.AllScopes[1] != "contests:read" {
+		t.Errorf("Policies derived from the same base should not share scopes: %v %v %v",
+			base.AllScopes, write.AllScopes, read.AllScopes)
+	}
+}
+
+func TestConfigPolicy(t *testing.T) {
+	user := &JWTClaims{Roles: []string{"user"}, Scopes: []string{"accounts:read"}}
+	service := &JWTClaims{Roles: []string{ServiceRole("notifications")}, Scopes: []string{"accounts:read"}}
+
+	// Without a role condition the user role is required
+	p := JWTConfig{RequiredScopes: []string{"accounts:read"}}.policy()
+	if !p.Allows(user) || p.Allows(service) {
+		t.Errorf("Default policy should require the user role, got %+v", p)
+	}
+
+	p = JWTConfig{Policy: AnyRole("user", ServiceRole("notifications"))}.policy()
+	if !p.Allows(user) || !p.Allows(service) {
+		t.Errorf("Policy roles should replace the default, got %+v", p)
+	}
+
+	p = JWTConfig{AllowedRole: "user", Policy: Policy{AnyScope: []string{"emails:send"}}}.policy()
+	if p.Allows(user) {
+		t.Errorf("AllowedRole and Policy should both apply, got %+v", p)
+	}
+}
+
+func TestMiddlewarePolicy(t *testing.T) {
+	e := echo.New()
+	validKey := []byte("secret")
+	handler := func(c echo.Context) error {
+		claims, ok := ClaimsFromContext(c)
+		if !ok || claims.Username != "pelle" {
+			t.Errorf("Expected claims of 'pelle' in context")
+		}
+		return c.String(http.StatusOK, "test")
+	}
+
+	auth := JWTMiddleware(JWTConfig{
+		SigningKey: validKey,
+		Policy:     AnyRole("admin", "support"),
+	})
+	narrowed := JWTMiddleware(JWTConfig{SigningKey: validKey})
+	supportOnly := RequirePolicy(AllRoles("support").WithAllScopes("tickets:write"))
+
+	makeReq := func(h echo.HandlerFunc, roles []string, scopes []string) error {
+		claims := &JWTClaims{Username: "pelle", Roles: roles, Scopes: scopes}
+		token, _, err := GenerateAuthToken(claims, time.Minute, validKey)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %+v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer: "+token)
+		return h(e.NewContext(req, httptest.NewRecorder()))
+	}
+
+	if err := makeReq(auth(handler), []string{"user", "support"}, nil); err != nil {
+		t.Errorf("Support should be let in: %+v", err)
+	}
+	if err := makeReq(auth(handler), []string{"user"}, nil); err == nil {
+		t.Errorf("User without admin or support should be rejected")
+	}
+
+	h := narrowed(supportOnly(handler))
+	if err := makeReq(h, []string{"user", "support"}, []string{"tickets:write"}); err != nil {
+		t.Errorf("Support with the scope should be let in: %+v", err)
+	}
+	err := makeReq(h, []string{"user", "support"}, nil)
+	if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusForbidden {
+		t.Errorf("Missing scope should be forbidden, got %+v", err)
+	}
+}
+
+func TestClaimsFromContext(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	if _, ok := ClaimsFromContext(c); ok {
+		t.Errorf("No claims expected before authentication")
+	}
+
+	c.Set("user", "not claims")
+	if _, ok := ClaimsFromContext(c); ok {
+		t.Errorf("Wrong type in context should not be claims")
+	}
+
+	c.Set("user", &JWTClaims{Username: "pelle"})
+	if claims, ok := ClaimsFromContext(c); !ok || claims.Username != "pelle" {
+		t.Errorf("Expected claims of 'pelle', got %+v", claims)
+	}
+}
@@ -0,0 +1,100 @@
+package authlib
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// RevocationStore keeps track of revoked tokens by their ID (jti). Entries
+// only need to be kept until the token would have expired anyway.
+type RevocationStore interface {
+	Revoke(tokenID string, expiresAt time.Time) error
+	IsRevoked(tokenID string) (bool, error)
+}
+
+// MemoryRevocationStore holds revoked tokens in memory. Only useful for tests
+// and single instance deployments since nothing is shared between processes.
+type MemoryRevocationStore struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+// NewMemoryRevocationStore creates an empty in-memory revocation store
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		revoked: make(map[string]time.Time),
+	}
+}
+
+// Revoke adds a token to the denylist until expiresAt
+func (s *MemoryRevocationStore) Revoke(tokenID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop entries for tokens that have expired by now
+	now := time.Now()
+	for id, expiry := range s.revoked {
+		if expiry.Before(now) {
+			delete(s.revoked, id)
+		}
+	}
+
+	s.revoked[tokenID] = expiresAt
+	return nil
+}
+
+// IsRevoked returns true if the token is on the denylist
+func (s *MemoryRevocationStore) IsRevoked(tokenID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiry, ok := s.revoked[tokenID]
+	return ok && expiry.After(time.Now()), nil
+}
+
+// RevokedToken is a denylist entry stored by GormRevocationStore
+type RevokedToken struct {
+	TokenID   string    `gorm:"type:varchar(36);primary_key" json:"token_id"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// GormRevocationStore keeps the denylist in a database table so every
+// instance of a service sees the same revoked tokens.
+type GormRevocationStore struct {
+	db *gorm.DB
+}
+
+// NewGormRevocationStore creates a DB-backed revocation store, creating the
+// table if needed
+func NewGormRevocationStore(db *gorm.DB) (*GormRevocationStore, error) {
+	err := db.AutoMigrate(RevokedToken{}).Error
+	if err != nil {
+		return nil, err
+	}
+	return &GormRevocationStore{db: db}, nil
+}
+
+// Revoke adds a token to the denylist until expiresAt
+func (s *GormRevocationStore) Revoke(tokenID string, expiresAt time.Time) error {
+	// Ignore errors, cleaning up is not important
+	s.db.Where("expires_at < ?", time.Now()).Delete(RevokedToken{})
+
+	return s.db.Save(&RevokedToken{
+		TokenID:   tokenID,
+		ExpiresAt: expiresAt,
+	}).Error
+}
+
+// IsRevoked returns true if the token is on the denylist
+func (s *GormRevocationStore) IsRevoked(tokenID string) (bool, error) {
+	var count int
+	err := s.db.Model(&RevokedToken{}).
+		Where("token_id = ? AND expires_at > ?", tokenID, time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
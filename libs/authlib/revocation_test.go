@@ -0,0 +1,71 @@
+package authlib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestMemoryRevocationStore(t *testing.T) {
+	store := NewMemoryRevocationStore()
+
+	revoked, _ := store.IsRevoked("some-id")
+	if revoked {
+		t.Errorf("Token should not be revoked before calling Revoke")
+	}
+
+	store.Revoke("some-id", time.Now().Add(time.Minute))
+	revoked, _ = store.IsRevoked("some-id")
+	if !revoked {
+		t.Errorf("Token should be revoked")
+	}
+
+	store.Revoke("expired-id", time.Now().Add(-time.Minute))
+	revoked, _ = store.IsRevoked("expired-id")
+	if revoked {
+		t.Errorf("Expired entries should not count as revoked")
+	}
+}
+
+func TestMiddlewareRejectsRevokedToken(t *testing.T) {
+	e := echo.New()
+	handler := func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	}
+	validKey := []byte("secret")
+	store := NewMemoryRevocationStore()
+
+	h := JWTMiddleware(JWTConfig{
+		SigningKey:      validKey,
+		RevocationStore: store,
+	})(handler)
+
+	claims := &JWTClaims{
+		Username: "pelle",
+		UserID:   "random_id",
+		Roles:    []string{"user"},
+	}
+	token, expiry, err := GenerateAuthToken(claims, time.Minute, validKey)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %+v", err)
+	}
+
+	makeReq := func() error {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		req.Header.Set(echo.HeaderAuthorization, "Bearer: "+token)
+		return h(e.NewContext(req, res))
+	}
+
+	if err := makeReq(); err != nil {
+		t.Errorf("Valid token should be accepted: %+v", err)
+	}
+
+	store.Revoke(claims.Id, expiry)
+	if err := makeReq(); err == nil {
+		t.Errorf("Revoked token should be rejected")
+	}
+}
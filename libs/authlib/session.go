@@ -0,0 +1,18 @@
+package authlib
+
+// SessionValidator checks the server-side session a token was issued for,
+// see JWTClaims.SessionID
+type SessionValidator interface {
+	// ValidateSession returns false if the session was revoked or has
+	// expired. Implementations may record the session as seen.
+	ValidateSession(sessionID string) (bool, error)
+}
+
+// sessionActive returns true if the session of claims is active. Tokens of
+// services are not tied to a session, user tokens without one are rejected.
+func sessionActive(sessions SessionValidator, claims *JWTClaims) (bool, error) {
+	if claims.SessionID == "" {
+		return claims.IsService(), nil
+	}
+	return sessions.ValidateSession(claims.SessionID)
+}
@@ -0,0 +1,100 @@
+package authlib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type testSessions map[string]bool
+
+func (s testSessions) ValidateSession(sessionID string) (bool, error) {
+	return s[sessionID], nil
+}
+
+func TestMiddlewareRejectsEndedSession(t *testing.T) {
+	e := echo.New()
+	handler := func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	}
+	validKey := []byte("secret")
+	sessions := testSessions{"session-1": true}
+
+	h := JWTMiddleware(JWTConfig{
+		SigningKey: validKey,
+		Sessions:   sessions,
+	})(handler)
+
+	claims := &JWTClaims{
+		Username:  "pelle",
+		UserID:    "random_id",
+		Roles:     []string{"user"},
+		SessionID: "session-1",
+	}
+	token, _, err := GenerateAuthToken(claims, time.Minute, validKey)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %+v", err)
+	}
+	parts := strings.Split(token, ".")
+
+	makeHeaderReq := func() error {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer: "+token)
+		return h(e.NewContext(req, httptest.NewRecorder()))
+	}
+	makeBrowserReq := func() (error, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Requested-With", "XMLHttpRequest")
+		req.AddCookie(&http.Cookie{Name: "header.payload", Value: parts[0] + "." + parts[1]})
+		req.AddCookie(&http.Cookie{Name: "signature", Value: parts[2]})
+		res := httptest.NewRecorder()
+		return h(e.NewContext(req, res)), res
+	}
+
+	if err := makeHeaderReq(); err != nil {
+		t.Errorf("Token of an active session should be accepted: %+v", err)
+	}
+	err, res := makeBrowserReq()
+	if err != nil {
+		t.Errorf("Browser with an active session should be accepted: %+v", err)
+	}
+	if len(res.Result().Cookies()) == 0 {
+		t.Errorf("Cookies of an active session should be refreshed")
+	}
+
+	sessions["session-1"] = false
+	if err := makeHeaderReq(); err == nil {
+		t.Errorf("Token of a revoked session should be rejected")
+	}
+	err, res = makeBrowserReq()
+	if err == nil {
+		t.Errorf("Browser with a revoked session should be rejected")
+	}
+	if len(res.Result().Cookies()) != 0 {
+		t.Errorf("Cookies of a revoked session must not be refreshed")
+	}
+}
+
+func TestSessionActive(t *testing.T) {
+	sessions := testSessions{"session-1": true}
+	cases := []struct {
+		claims *JWTClaims
+		active bool
+	}{
+		{&JWTClaims{Roles: []string{"user"}, SessionID: "session-1"}, true},
+		{&JWTClaims{Roles: []string{"user"}, SessionID: "session-2"}, false},
+		// Tokens from before sessions were tracked
+		{&JWTClaims{Roles: []string{"user"}}, false},
+		{&JWTClaims{Roles: []string{ServiceRole("notifications")}}, true},
+	}
+	for _, c := range cases {
+		active, err := sessionActive(sessions, c.claims)
+		if err != nil || active != c.active {
+			t.Errorf("Claims %+v gave %t (%+v), wanted %t", c.claims, active, err, c.active)
+		}
+	}
+}
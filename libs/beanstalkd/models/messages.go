@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type WelcomeEmail struct {
 	Job
 	Username         string `json:"username"`
@@ -13,3 +15,64 @@ type ResetPasswordEmail struct {
 	Email     string `json:"email"`
 	ResetCode string `json:"reset_code"`
 }
+
+type MFACodeEmail struct {
+	Job
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Code     string `json:"code"`
+}
+
+type AccountLockedEmail struct {
+	Job
+	Username    string    `json:"username"`
+	Email       string    `json:"email"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+type PasswordChangedEmail struct {
+	Job
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type EmailChangeConfirmEmail struct {
+	Job
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	ConfirmCode string `json:"confirm_code"`
+}
+
+type EmailChangeNoticeEmail struct {
+	Job
+	Username   string `json:"username"`
+	Email      string `json:"email"`
+	NewEmail   string `json:"new_email"`
+	CancelCode string `json:"cancel_code"`
+}
+
+type LoginLinkEmail struct {
+	Job
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	LoginCode string `json:"login_code"`
+}
+
+type RecoveryCodeUsedEmail struct {
+	Job
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	UsedAt    time.Time `json:"used_at"`
+	Remaining int       `json:"remaining"`
+}
+
+type NewLoginEmail struct {
+	Job
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	LoginAt    time.Time `json:"login_at"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	ReportCode string    `json:"report_code"`
+}
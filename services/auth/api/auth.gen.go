@@ -10,12 +10,25 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/deepmap/oapi-codegen/pkg/runtime"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/labstack/echo/v4"
 )
 
+// APIKey defines model for APIKey.
+type APIKey struct {
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Id         string     `json:"id"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Scopes     []string   `json:"scopes"`
+}
+
 // Account defines model for Account.
 type Account struct {
 	Email    string `json:"email"`
@@ -23,13 +36,72 @@ type Account struct {
 	Username string `json:"username"`
 }
 
+// AccountDeletion defines model for AccountDeletion.
+type AccountDeletion struct {
+	Password string `json:"password"`
+}
+
+// AccountDeletionStatus defines model for AccountDeletionStatus.
+type AccountDeletionStatus struct {
+	DeleteAfter time.Time `json:"delete_after"`
+}
+
+// AccountExport defines model for AccountExport.
+type AccountExport struct {
+	Account                ExportedAccount        `json:"account"`
+	ApiKeys                []APIKey               `json:"api_keys"`
+	EmailChanges           []ExportedEmailChange  `json:"email_changes"`
+	EmailVerificationCodes []ExportedCode         `json:"email_verification_codes"`
+	FailedLogins           []time.Time            `json:"failed_logins"`
+	KnownDevices           []ExportedKnownDevice  `json:"known_devices"`
+	LoginLinkTokens        []ExportedCode         `json:"login_link_tokens"`
+	MfaCodes               []ExportedCode         `json:"mfa_codes"`
+	MfaMethods             []ExportedMFAMethod    `json:"mfa_methods"`
+	MfaRecoveryCodes       []ExportedRecoveryCode `json:"mfa_recovery_codes"`
+	Passkeys               []WebAuthnCredential   `json:"passkeys"`
+	PasswordResetTokens    []ExportedCode         `json:"password_reset_tokens"`
+	RefreshTokens          []ExportedRefreshToken `json:"refresh_tokens"`
+	RoleAssignments        []RoleAssignment       `json:"role_assignments"`
+	RoleChanges            []RoleAuditEntry       `json:"role_changes"`
+	SecurityEvents         []SecurityEvent        `json:"security_events"`
+	Sessions               []Session              `json:"sessions"`
+}
+
+// AccountUnlock defines model for AccountUnlock.
+type AccountUnlock struct {
+	Username string `json:"username"`
+}
+
 // AuthClaim defines model for AuthClaim.
 type AuthClaim struct {
-	Claim    string  `json:"claim"`
-	MfaCode  *string `json:"mfa_code,omitempty"`
-	Password *string `json:"password,omitempty"`
-	Token    *string `json:"token,omitempty"`
-	Username *string `json:"username,omitempty"`
+	Assertion    *WebAuthnAssertion `json:"assertion,omitempty"`
+	Claim        string             `json:"claim"`
+	ClientId     *string            `json:"client_id,omitempty"`
+	ClientSecret *string            `json:"client_secret,omitempty"`
+	Email        *string            `json:"email,omitempty"`
+	MfaCode      *string            `json:"mfa_code,omitempty"`
+	Password     *string            `json:"password,omitempty"`
+	Scope        *string            `json:"scope,omitempty"`
+	Token        *string            `json:"token,omitempty"`
+	Username     *string            `json:"username,omitempty"`
+}
+
+// CreatedAPIKey defines model for CreatedAPIKey.
+type CreatedAPIKey struct {
+	ApiKey APIKey `json:"api_key"`
+	Key    string `json:"key"`
+}
+
+// CreatedServiceClient defines model for CreatedServiceClient.
+type CreatedServiceClient struct {
+	Client       ServiceClient `json:"client"`
+	ClientSecret string        `json:"client_secret"`
+}
+
+// EmailChange defines model for EmailChange.
+type EmailChange struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
 }
 
 // EmailVerification defines model for EmailVerification.
@@ -40,16 +112,144 @@ type EmailVerification struct {
 
 // Error defines model for Error.
 type Error struct {
-	Code    int32  `json:"code"`
-	Message string `json:"message"`
+	Code             int32             `json:"code"`
+	Message          string            `json:"message"`
+	PasswordFeedback *PasswordStrength `json:"password_feedback,omitempty"`
+}
+
+// ExportedAccount defines model for ExportedAccount.
+type ExportedAccount struct {
+	AcceptedTermsAt *time.Time `json:"accepted_terms_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	DeleteAfter     *time.Time `json:"delete_after,omitempty"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	Id              string     `json:"id"`
+	Username        string     `json:"username"`
+}
+
+// ExportedCode defines model for ExportedCode.
+type ExportedCode struct {
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ExportedEmailChange defines model for ExportedEmailChange.
+type ExportedEmailChange struct {
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	NewEmail    string     `json:"new_email"`
+	OldEmail    string     `json:"old_email"`
+}
+
+// ExportedKnownDevice defines model for ExportedKnownDevice.
+type ExportedKnownDevice struct {
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// ExportedMFAMethod defines model for ExportedMFAMethod.
+type ExportedMFAMethod struct {
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Type        string     `json:"type"`
+}
+
+// ExportedRecoveryCode defines model for ExportedRecoveryCode.
+type ExportedRecoveryCode struct {
+	CreatedAt time.Time  `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+// ExportedRefreshToken defines model for ExportedRefreshToken.
+type ExportedRefreshToken struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	FamilyId  string     `json:"family_id"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+// HashParamsCount defines model for HashParamsCount.
+type HashParamsCount struct {
+	Accounts  int    `json:"accounts"`
+	Algorithm string `json:"algorithm"`
+	Outdated  bool   `json:"outdated"`
+	Params    string `json:"params"`
+}
+
+// Identity defines model for Identity.
+type Identity struct {
+	Roles    []string `json:"roles"`
+	Scopes   []string `json:"scopes"`
+	UserId   string   `json:"user_id"`
+	Username string   `json:"username"`
+}
+
+// JWKS defines model for JWKS.
+type JWKS struct {
+	Keys []map[string]interface{} `json:"keys"`
 }
 
 // JWT defines model for JWT.
 type JWT struct {
-	AccessToken string `json:"access_token"`
-	ExpiresIn   int    `json:"expires_in"`
-	MfaRequired bool   `json:"mfa_required"`
-	MfaType     string `json:"mfa_type"`
+	AccessToken  string  `json:"access_token"`
+	ExpiresIn    int     `json:"expires_in"`
+	MfaRequired  bool    `json:"mfa_required"`
+	MfaType      string  `json:"mfa_type"`
+	RefreshToken *string `json:"refresh_token,omitempty"`
+}
+
+// Logout defines model for Logout.
+type Logout struct {
+	RefreshToken *string `json:"refresh_token,omitempty"`
+}
+
+// MFACode defines model for MFACode.
+type MFACode struct {
+	Code string `json:"code"`
+}
+
+// MFARecoveryCodes defines model for MFARecoveryCodes.
+type MFARecoveryCodes struct {
+	Codes []string `json:"codes"`
+}
+
+// MFARecoveryStatus defines model for MFARecoveryStatus.
+type MFARecoveryStatus struct {
+	Remaining int `json:"remaining"`
+}
+
+// NewAPIKey defines model for NewAPIKey.
+type NewAPIKey struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+}
+
+// NewServiceClient defines model for NewServiceClient.
+type NewServiceClient struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// PasswordChange defines model for PasswordChange.
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// PasswordCheck defines model for PasswordCheck.
+type PasswordCheck struct {
+	Email    *string `json:"email,omitempty"`
+	Password string  `json:"password"`
+	Username *string `json:"username,omitempty"`
+}
+
+// PasswordHashReport defines model for PasswordHashReport.
+type PasswordHashReport struct {
+	Current       string            `json:"current"`
+	ParameterSets []HashParamsCount `json:"parameter_sets"`
 }
 
 // PasswordResetRequest defines model for PasswordResetRequest.
@@ -65,6 +265,159 @@ type PasswordResetVerify struct {
 	Username string `json:"username"`
 }
 
+// PasswordStrength defines model for PasswordStrength.
+type PasswordStrength struct {
+	Accepted    bool     `json:"accepted"`
+	Score       int      `json:"score"`
+	Suggestions []string `json:"suggestions"`
+	Warning     string   `json:"warning"`
+}
+
+// RecoveryCodeRegeneration defines model for RecoveryCodeRegeneration.
+type RecoveryCodeRegeneration struct {
+	Password string `json:"password"`
+}
+
+// RoleAssignment defines model for RoleAssignment.
+type RoleAssignment struct {
+	GrantedAt time.Time `json:"granted_at"`
+	GrantedBy *string   `json:"granted_by,omitempty"`
+	Reason    string    `json:"reason"`
+	Role      string    `json:"role"`
+	Username  string    `json:"username"`
+}
+
+// RoleAuditEntry defines model for RoleAuditEntry.
+type RoleAuditEntry struct {
+	Action    string    `json:"action"`
+	Actor     *string   `json:"actor,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Reason    string    `json:"reason"`
+	Role      string    `json:"role"`
+	Username  string    `json:"username"`
+}
+
+// RoleChange defines model for RoleChange.
+type RoleChange struct {
+	Reason   string `json:"reason"`
+	Role     string `json:"role"`
+	Username string `json:"username"`
+}
+
+// SecurityEvent defines model for SecurityEvent.
+type SecurityEvent struct {
+	CreatedAt time.Time `json:"created_at"`
+	Detail    *string   `json:"detail,omitempty"`
+	Ip        string    `json:"ip"`
+	RequestId string    `json:"request_id"`
+	Type      string    `json:"type"`
+	UserAgent string    `json:"user_agent"`
+	Username  *string   `json:"username,omitempty"`
+}
+
+// ServiceClient defines model for ServiceClient.
+type ServiceClient struct {
+	ClientId   string     `json:"client_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Id         string     `json:"id"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+}
+
+// Session defines model for Session.
+type Session struct {
+	CreatedAt  time.Time `json:"created_at"`
+	Current    bool      `json:"current"`
+	Id         string    `json:"id"`
+	Ip         string    `json:"ip"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	UserAgent  string    `json:"user_agent"`
+}
+
+// TOTPEnrollment defines model for TOTPEnrollment.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	Uri    string `json:"uri"`
+}
+
+// WebAuthnAssertion defines model for WebAuthnAssertion.
+type WebAuthnAssertion struct {
+	AuthenticatorData string  `json:"authenticator_data"`
+	ClientDataJson    string  `json:"client_data_json"`
+	CredentialId      string  `json:"credential_id"`
+	Signature         string  `json:"signature"`
+	UserHandle        *string `json:"user_handle,omitempty"`
+}
+
+// WebAuthnCredential defines model for WebAuthnCredential.
+type WebAuthnCredential struct {
+	CreatedAt  time.Time  `json:"created_at"`
+	Id         string     `json:"id"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Name       string     `json:"name"`
+	Transports []string   `json:"transports"`
+}
+
+// WebAuthnCredentialDescriptor defines model for WebAuthnCredentialDescriptor.
+type WebAuthnCredentialDescriptor struct {
+	Id         string    `json:"id"`
+	Transports *[]string `json:"transports,omitempty"`
+}
+
+// WebAuthnOptions defines model for WebAuthnOptions.
+type WebAuthnOptions struct {
+	Challenge   string                         `json:"challenge"`
+	Credentials []WebAuthnCredentialDescriptor `json:"credentials"`
+	RpId        string                         `json:"rp_id"`
+	RpName      *string                        `json:"rp_name,omitempty"`
+	Timeout     int                            `json:"timeout"`
+	UserId      *string                        `json:"user_id,omitempty"`
+	UserName    *string                        `json:"user_name,omitempty"`
+}
+
+// WebAuthnRegistration defines model for WebAuthnRegistration.
+type WebAuthnRegistration struct {
+	AttestationObject string    `json:"attestation_object"`
+	ClientDataJson    string    `json:"client_data_json"`
+	Name              *string   `json:"name,omitempty"`
+	Transports        *[]string `json:"transports,omitempty"`
+}
+
+// requestaccountdeletionJSONBody defines parameters for Requestaccountdeletion.
+type requestaccountdeletionJSONBody AccountDeletion
+
+// createServiceClientJSONBody defines parameters for CreateServiceClient.
+type createServiceClientJSONBody NewServiceClient
+
+// ListSecurityEventsParams defines parameters for ListSecurityEvents.
+type ListSecurityEventsParams struct {
+	Username *string `json:"username,omitempty"`
+	Ip       *string `json:"ip,omitempty"`
+}
+
+// ListRoleAssignmentsParams defines parameters for ListRoleAssignments.
+type ListRoleAssignmentsParams struct {
+	Username *string `json:"username,omitempty"`
+}
+
+// grantRoleJSONBody defines parameters for GrantRole.
+type grantRoleJSONBody RoleChange
+
+// ListRoleAuditParams defines parameters for ListRoleAudit.
+type ListRoleAuditParams struct {
+	Username *string `json:"username,omitempty"`
+}
+
+// revokeRoleJSONBody defines parameters for RevokeRole.
+type revokeRoleJSONBody RoleChange
+
+// unlockAccountJSONBody defines parameters for UnlockAccount.
+type unlockAccountJSONBody AccountUnlock
+
+// createapikeyJSONBody defines parameters for Createapikey.
+type createapikeyJSONBody NewAPIKey
+
 // performAuthJSONBody defines parameters for PerformAuth.
 type performAuthJSONBody AuthClaim
 
@@ -73,6 +426,36 @@ type CheckParams struct {
 	Username *string `json:"username,omitempty"`
 }
 
+// checkPasswordJSONBody defines parameters for CheckPassword.
+type checkPasswordJSONBody PasswordCheck
+
+// reportloginJSONBody defines parameters for Reportlogin.
+type reportloginJSONBody EmailVerification
+
+// requestemailchangeJSONBody defines parameters for Requestemailchange.
+type requestemailchangeJSONBody EmailChange
+
+// cancelemailchangeJSONBody defines parameters for Cancelemailchange.
+type cancelemailchangeJSONBody EmailVerification
+
+// confirmemailchangeJSONBody defines parameters for Confirmemailchange.
+type confirmemailchangeJSONBody EmailVerification
+
+// consumeloginlinkJSONBody defines parameters for Consumeloginlink.
+type consumeloginlinkJSONBody EmailVerification
+
+// logoutJSONBody defines parameters for Logout.
+type logoutJSONBody Logout
+
+// regenerateRecoveryCodesJSONBody defines parameters for RegenerateRecoveryCodes.
+type regenerateRecoveryCodesJSONBody RecoveryCodeRegeneration
+
+// confirmTOTPJSONBody defines parameters for ConfirmTOTP.
+type confirmTOTPJSONBody MFACode
+
+// changepasswordJSONBody defines parameters for Changepassword.
+type changepasswordJSONBody PasswordChange
+
 // passwordresetrequestJSONBody defines parameters for Passwordresetrequest.
 type passwordresetrequestJSONBody PasswordResetRequest
 
@@ -85,9 +468,60 @@ type createAccountJSONBody Account
 // verifyJSONBody defines parameters for Verify.
 type verifyJSONBody EmailVerification
 
+// finishwebauthnregistrationJSONBody defines parameters for Finishwebauthnregistration.
+type finishwebauthnregistrationJSONBody WebAuthnRegistration
+
+// RequestaccountdeletionRequestBody defines body for Requestaccountdeletion for application/json ContentType.
+type RequestaccountdeletionJSONRequestBody requestaccountdeletionJSONBody
+
+// CreateServiceClientRequestBody defines body for CreateServiceClient for application/json ContentType.
+type CreateServiceClientJSONRequestBody createServiceClientJSONBody
+
+// GrantRoleRequestBody defines body for GrantRole for application/json ContentType.
+type GrantRoleJSONRequestBody grantRoleJSONBody
+
+// RevokeRoleRequestBody defines body for RevokeRole for application/json ContentType.
+type RevokeRoleJSONRequestBody revokeRoleJSONBody
+
+// UnlockAccountRequestBody defines body for UnlockAccount for application/json ContentType.
+type UnlockAccountJSONRequestBody unlockAccountJSONBody
+
+// CreateapikeyRequestBody defines body for Createapikey for application/json ContentType.
+type CreateapikeyJSONRequestBody createapikeyJSONBody
+
 // PerformAuthRequestBody defines body for PerformAuth for application/json ContentType.
 type PerformAuthJSONRequestBody performAuthJSONBody
 
+// CheckPasswordRequestBody defines body for CheckPassword for application/json ContentType.
+type CheckPasswordJSONRequestBody checkPasswordJSONBody
+
+// ReportloginRequestBody defines body for Reportlogin for application/json ContentType.
+type ReportloginJSONRequestBody reportloginJSONBody
+
+// RequestemailchangeRequestBody defines body for Requestemailchange for application/json ContentType.
+type RequestemailchangeJSONRequestBody requestemailchangeJSONBody
+
+// CancelemailchangeRequestBody defines body for Cancelemailchange for application/json ContentType.
+type CancelemailchangeJSONRequestBody cancelemailchangeJSONBody
+
+// ConfirmemailchangeRequestBody defines body for Confirmemailchange for application/json ContentType.
+type ConfirmemailchangeJSONRequestBody confirmemailchangeJSONBody
+
+// ConsumeloginlinkRequestBody defines body for Consumeloginlink for application/json ContentType.
+type ConsumeloginlinkJSONRequestBody consumeloginlinkJSONBody
+
+// LogoutRequestBody defines body for Logout for application/json ContentType.
+type LogoutJSONRequestBody logoutJSONBody
+
+// RegenerateRecoveryCodesRequestBody defines body for RegenerateRecoveryCodes for application/json ContentType.
+type RegenerateRecoveryCodesJSONRequestBody regenerateRecoveryCodesJSONBody
+
+// ConfirmTOTPRequestBody defines body for ConfirmTOTP for application/json ContentType.
+type ConfirmTOTPJSONRequestBody confirmTOTPJSONBody
+
+// ChangepasswordRequestBody defines body for Changepassword for application/json ContentType.
+type ChangepasswordJSONRequestBody changepasswordJSONBody
+
 // PasswordresetrequestRequestBody defines body for Passwordresetrequest for application/json ContentType.
 type PasswordresetrequestJSONRequestBody passwordresetrequestJSONBody
 
@@ -100,20 +534,103 @@ type CreateAccountJSONRequestBody createAccountJSONBody
 // VerifyRequestBody defines body for Verify for application/json ContentType.
 type VerifyJSONRequestBody verifyJSONBody
 
+// FinishwebauthnregistrationRequestBody defines body for Finishwebauthnregistration for application/json ContentType.
+type FinishwebauthnregistrationJSONRequestBody finishwebauthnregistrationJSONBody
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Public keys used to sign auth tokens, for services that only verify tokens// (GET /.well-known/jwks.json)
+	GetJWKS(ctx echo.Context) error
+	// Cancel a scheduled deletion of the logged in user's account// (DELETE /v1/auth/account/deletion)
+	Cancelaccountdeletion(ctx echo.Context) error
+	// Schedule deletion of the logged in user's account// (POST /v1/auth/account/deletion)
+	Requestaccountdeletion(ctx echo.Context) error
+	// Export everything the auth service stores about the logged in user// (GET /v1/auth/account/export)
+	Exportaccount(ctx echo.Context) error
+	// Recent security events of the logged in user, newest first// (GET /v1/auth/activity)
+	Listactivity(ctx echo.Context) error
+	// List registered service clients// (GET /v1/auth/admin/clients)
+	ListServiceClients(ctx echo.Context) error
+	// Register a service client for the client_credentials claim// (POST /v1/auth/admin/clients)
+	CreateServiceClient(ctx echo.Context) error
+	// Remove a service client, it can no longer get tokens// (DELETE /v1/auth/admin/clients/{id})
+	DeleteServiceClient(ctx echo.Context, id string) error
+	// Security events by account or IP, newest first// (GET /v1/auth/admin/events)
+	ListSecurityEvents(ctx echo.Context, params ListSecurityEventsParams) error
+	// Count accounts per password hashing parameter set// (GET /v1/auth/admin/hashes)
+	GetPasswordHashReport(ctx echo.Context) error
+	// List role assignments, of one account or all accounts// (GET /v1/auth/admin/roles)
+	ListRoleAssignments(ctx echo.Context, params ListRoleAssignmentsParams) error
+	// Grant a role to an account// (POST /v1/auth/admin/roles)
+	GrantRole(ctx echo.Context) error
+	// Role changes, newest first// (GET /v1/auth/admin/roles/audit)
+	ListRoleAudit(ctx echo.Context, params ListRoleAuditParams) error
+	// Revoke a role from an account// (POST /v1/auth/admin/roles/revoke)
+	RevokeRole(ctx echo.Context) error
+	// Lift a lockout caused by too many failed login attempts// (POST /v1/auth/admin/unlock)
+	UnlockAccount(ctx echo.Context) error
+	// List the active personal API keys of the logged in user// (GET /v1/auth/apikeys)
+	Listapikeys(ctx echo.Context) error
+	// Create a personal API key for the logged in user// (POST /v1/auth/apikeys)
+	Createapikey(ctx echo.Context) error
+	// Revoke a personal API key of the logged in user// (DELETE /v1/auth/apikeys/{id})
+	Deleteapikey(ctx echo.Context, id string) error
 	// Authenticate a user returning a JWT for future operations and set session token for browsers// (POST /v1/auth/auth)
 	PerformAuth(ctx echo.Context) error
 	// Check if parameter is valid/available// (GET /v1/auth/check)
 	Check(ctx echo.Context, params CheckParams) error
+	// Estimate password strength, sent in the body so passwords never end up in URLs or access logs// (POST /v1/auth/check)
+	CheckPassword(ctx echo.Context) error
+	// Report a login from a new device as not made by the account owner// (POST /v1/auth/devices/report)
+	Reportlogin(ctx echo.Context) error
+	// Request a change of email address for the logged in user// (POST /v1/auth/email)
+	Requestemailchange(ctx echo.Context) error
+	// Cancel an email change with the code sent to the old address// (POST /v1/auth/email/cancel)
+	Cancelemailchange(ctx echo.Context) error
+	// Confirm a new email address with the code sent to it// (POST /v1/auth/email/confirm)
+	Confirmemailchange(ctx echo.Context) error
+	// Sign in with the token from an emailed sign-in link// (POST /v1/auth/link)
+	Consumeloginlink(ctx echo.Context) error
+	// Revoke the current tokens and clear auth cookies// (POST /v1/auth/logout)
+	Logout(ctx echo.Context) error
+	// Identity behind the token or API key in the request// (GET /v1/auth/me)
+	Getidentity(ctx echo.Context) error
+	// Enable one-time codes sent by email as second factor// (POST /v1/auth/mfa/email)
+	EnableEmailMFA(ctx echo.Context) error
+	// Number of unused recovery codes of the logged in user// (GET /v1/auth/mfa/recovery)
+	GetRecoveryCodeStatus(ctx echo.Context) error
+	// Replace the recovery codes of the logged in user with a new set// (POST /v1/auth/mfa/recovery)
+	RegenerateRecoveryCodes(ctx echo.Context) error
+	// Start TOTP enrollment for the logged in user// (POST /v1/auth/mfa/totp)
+	EnrollTOTP(ctx echo.Context) error
+	// Confirm TOTP enrollment with the first code from the authenticator// (POST /v1/auth/mfa/totp/confirm)
+	ConfirmTOTP(ctx echo.Context) error
+	// Change the password of the logged in user// (POST /v1/auth/password)
+	Changepassword(ctx echo.Context) error
 	// Submit a password reset request// (POST /v1/auth/passwordreset/request)
 	Passwordresetrequest(ctx echo.Context) error
 	// Submit a password reset verification// (POST /v1/auth/passwordreset/verify)
 	Passwordresetverify(ctx echo.Context) error
 	// Create a new account// (POST /v1/auth/register)
 	CreateAccount(ctx echo.Context) error
+	// Active sessions of the logged in user, most recently seen first// (GET /v1/auth/sessions)
+	Listsessions(ctx echo.Context) error
+	// Sign out every session of the logged in user except the current one// (POST /v1/auth/sessions/revoke_others)
+	Revokeothersessions(ctx echo.Context) error
+	// Sign out a session of the logged in user// (DELETE /v1/auth/sessions/{id})
+	Revokesession(ctx echo.Context, id string) error
 	// Verify a user's email// (POST /v1/auth/verifyemail)
 	Verify(ctx echo.Context) error
+	// Send a new email verification code// (POST /v1/auth/verifyemail/resend)
+	Resendverification(ctx echo.Context) error
+	// List the passkeys of the logged in user// (GET /v1/auth/webauthn/credentials)
+	Listwebauthncredentials(ctx echo.Context) error
+	// Remove a passkey from the logged in user// (DELETE /v1/auth/webauthn/credentials/{id})
+	Deletewebauthncredential(ctx echo.Context, id string) error
+	// Start registering a passkey for the logged in user// (POST /v1/auth/webauthn/register)
+	Beginwebauthnregistration(ctx echo.Context) error
+	// Finish registering a passkey with the authenticator response// (POST /v1/auth/webauthn/register/finish)
+	Finishwebauthnregistration(ctx echo.Context) error
 }
 
 // ServerInterfaceWrapper converts echo contexts to parameters.
@@ -121,6 +638,228 @@ type ServerInterfaceWrapper struct {
 	Handler ServerInterface
 }
 
+// GetJWKS converts echo context to params.
+func (w *ServerInterfaceWrapper) GetJWKS(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetJWKS(ctx)
+	return err
+}
+
+// Cancelaccountdeletion converts echo context to params.
+func (w *ServerInterfaceWrapper) Cancelaccountdeletion(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Cancelaccountdeletion(ctx)
+	return err
+}
+
+// Requestaccountdeletion converts echo context to params.
+func (w *ServerInterfaceWrapper) Requestaccountdeletion(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Requestaccountdeletion(ctx)
+	return err
+}
+
+// Exportaccount converts echo context to params.
+func (w *ServerInterfaceWrapper) Exportaccount(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Exportaccount(ctx)
+	return err
+}
+
+// Listactivity converts echo context to params.
+func (w *ServerInterfaceWrapper) Listactivity(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Listactivity(ctx)
+	return err
+}
+
+// ListServiceClients converts echo context to params.
+func (w *ServerInterfaceWrapper) ListServiceClients(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ListServiceClients(ctx)
+	return err
+}
+
+// CreateServiceClient converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateServiceClient(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.CreateServiceClient(ctx)
+	return err
+}
+
+// DeleteServiceClient converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteServiceClient(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameter("simple", false, "id", ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.DeleteServiceClient(ctx, id)
+	return err
+}
+
+// ListSecurityEvents converts echo context to params.
+func (w *ServerInterfaceWrapper) ListSecurityEvents(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListSecurityEventsParams
+	// ------------- Optional query parameter "username" -------------
+	if paramValue := ctx.QueryParam("username"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "username", ctx.QueryParams(), &params.Username)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter username: %s", err))
+	}
+
+	// ------------- Optional query parameter "ip" -------------
+	if paramValue := ctx.QueryParam("ip"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "ip", ctx.QueryParams(), &params.Ip)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter ip: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ListSecurityEvents(ctx, params)
+	return err
+}
+
+// GetPasswordHashReport converts echo context to params.
+func (w *ServerInterfaceWrapper) GetPasswordHashReport(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetPasswordHashReport(ctx)
+	return err
+}
+
+// ListRoleAssignments converts echo context to params.
+func (w *ServerInterfaceWrapper) ListRoleAssignments(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListRoleAssignmentsParams
+	// ------------- Optional query parameter "username" -------------
+	if paramValue := ctx.QueryParam("username"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "username", ctx.QueryParams(), &params.Username)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter username: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ListRoleAssignments(ctx, params)
+	return err
+}
+
+// GrantRole converts echo context to params.
+func (w *ServerInterfaceWrapper) GrantRole(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GrantRole(ctx)
+	return err
+}
+
+// ListRoleAudit converts echo context to params.
+func (w *ServerInterfaceWrapper) ListRoleAudit(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListRoleAuditParams
+	// ------------- Optional query parameter "username" -------------
+	if paramValue := ctx.QueryParam("username"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "username", ctx.QueryParams(), &params.Username)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter username: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ListRoleAudit(ctx, params)
+	return err
+}
+
+// RevokeRole converts echo context to params.
+func (w *ServerInterfaceWrapper) RevokeRole(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RevokeRole(ctx)
+	return err
+}
+
+// UnlockAccount converts echo context to params.
+func (w *ServerInterfaceWrapper) UnlockAccount(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.UnlockAccount(ctx)
+	return err
+}
+
+// Listapikeys converts echo context to params.
+func (w *ServerInterfaceWrapper) Listapikeys(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Listapikeys(ctx)
+	return err
+}
+
+// Createapikey converts echo context to params.
+func (w *ServerInterfaceWrapper) Createapikey(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Createapikey(ctx)
+	return err
+}
+
+// Deleteapikey converts echo context to params.
+func (w *ServerInterfaceWrapper) Deleteapikey(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameter("simple", false, "id", ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Deleteapikey(ctx, id)
+	return err
+}
+
 // PerformAuth converts echo context to params.
 func (w *ServerInterfaceWrapper) PerformAuth(ctx echo.Context) error {
 	var err error
@@ -151,6 +890,132 @@ func (w *ServerInterfaceWrapper) Check(ctx echo.Context) error {
 	return err
 }
 
+// CheckPassword converts echo context to params.
+func (w *ServerInterfaceWrapper) CheckPassword(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.CheckPassword(ctx)
+	return err
+}
+
+// Reportlogin converts echo context to params.
+func (w *ServerInterfaceWrapper) Reportlogin(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Reportlogin(ctx)
+	return err
+}
+
+// Requestemailchange converts echo context to params.
+func (w *ServerInterfaceWrapper) Requestemailchange(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Requestemailchange(ctx)
+	return err
+}
+
+// Cancelemailchange converts echo context to params.
+func (w *ServerInterfaceWrapper) Cancelemailchange(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Cancelemailchange(ctx)
+	return err
+}
+
+// Confirmemailchange converts echo context to params.
+func (w *ServerInterfaceWrapper) Confirmemailchange(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Confirmemailchange(ctx)
+	return err
+}
+
+// Consumeloginlink converts echo context to params.
+func (w *ServerInterfaceWrapper) Consumeloginlink(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Consumeloginlink(ctx)
+	return err
+}
+
+// Logout converts echo context to params.
+func (w *ServerInterfaceWrapper) Logout(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Logout(ctx)
+	return err
+}
+
+// Getidentity converts echo context to params.
+func (w *ServerInterfaceWrapper) Getidentity(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Getidentity(ctx)
+	return err
+}
+
+// EnableEmailMFA converts echo context to params.
+func (w *ServerInterfaceWrapper) EnableEmailMFA(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.EnableEmailMFA(ctx)
+	return err
+}
+
+// GetRecoveryCodeStatus converts echo context to params.
+func (w *ServerInterfaceWrapper) GetRecoveryCodeStatus(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetRecoveryCodeStatus(ctx)
+	return err
+}
+
+// RegenerateRecoveryCodes converts echo context to params.
+func (w *ServerInterfaceWrapper) RegenerateRecoveryCodes(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RegenerateRecoveryCodes(ctx)
+	return err
+}
+
+// EnrollTOTP converts echo context to params.
+func (w *ServerInterfaceWrapper) EnrollTOTP(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.EnrollTOTP(ctx)
+	return err
+}
+
+// ConfirmTOTP converts echo context to params.
+func (w *ServerInterfaceWrapper) ConfirmTOTP(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ConfirmTOTP(ctx)
+	return err
+}
+
+// Changepassword converts echo context to params.
+func (w *ServerInterfaceWrapper) Changepassword(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Changepassword(ctx)
+	return err
+}
+
 // Passwordresetrequest converts echo context to params.
 func (w *ServerInterfaceWrapper) Passwordresetrequest(ctx echo.Context) error {
 	var err error
@@ -178,6 +1043,40 @@ func (w *ServerInterfaceWrapper) CreateAccount(ctx echo.Context) error {
 	return err
 }
 
+// Listsessions converts echo context to params.
+func (w *ServerInterfaceWrapper) Listsessions(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Listsessions(ctx)
+	return err
+}
+
+// Revokeothersessions converts echo context to params.
+func (w *ServerInterfaceWrapper) Revokeothersessions(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Revokeothersessions(ctx)
+	return err
+}
+
+// Revokesession converts echo context to params.
+func (w *ServerInterfaceWrapper) Revokesession(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameter("simple", false, "id", ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Revokesession(ctx, id)
+	return err
+}
+
 // Verify converts echo context to params.
 func (w *ServerInterfaceWrapper) Verify(ctx echo.Context) error {
 	var err error
@@ -187,6 +1086,58 @@ func (w *ServerInterfaceWrapper) Verify(ctx echo.Context) error {
 	return err
 }
 
+// Resendverification converts echo context to params.
+func (w *ServerInterfaceWrapper) Resendverification(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Resendverification(ctx)
+	return err
+}
+
+// Listwebauthncredentials converts echo context to params.
+func (w *ServerInterfaceWrapper) Listwebauthncredentials(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Listwebauthncredentials(ctx)
+	return err
+}
+
+// Deletewebauthncredential converts echo context to params.
+func (w *ServerInterfaceWrapper) Deletewebauthncredential(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameter("simple", false, "id", ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Deletewebauthncredential(ctx, id)
+	return err
+}
+
+// Beginwebauthnregistration converts echo context to params.
+func (w *ServerInterfaceWrapper) Beginwebauthnregistration(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Beginwebauthnregistration(ctx)
+	return err
+}
+
+// Finishwebauthnregistration converts echo context to params.
+func (w *ServerInterfaceWrapper) Finishwebauthnregistration(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Finishwebauthnregistration(ctx)
+	return err
+}
+
 // RegisterHandlers adds each server route to the EchoRouter.
 func RegisterHandlers(router runtime.EchoRouter, si ServerInterface) {
 
@@ -194,38 +1145,166 @@ func RegisterHandlers(router runtime.EchoRouter, si ServerInterface) {
 		Handler: si,
 	}
 
+	router.GET("/.well-known/jwks.json", wrapper.GetJWKS)
+	router.DELETE("/v1/auth/account/deletion", wrapper.Cancelaccountdeletion)
+	router.POST("/v1/auth/account/deletion", wrapper.Requestaccountdeletion)
+	router.GET("/v1/auth/account/export", wrapper.Exportaccount)
+	router.GET("/v1/auth/activity", wrapper.Listactivity)
+	router.GET("/v1/auth/admin/clients", wrapper.ListServiceClients)
+	router.POST("/v1/auth/admin/clients", wrapper.CreateServiceClient)
+	router.DELETE("/v1/auth/admin/clients/:id", wrapper.DeleteServiceClient)
+	router.GET("/v1/auth/admin/events", wrapper.ListSecurityEvents)
+	router.GET("/v1/auth/admin/hashes", wrapper.GetPasswordHashReport)
+	router.GET("/v1/auth/admin/roles", wrapper.ListRoleAssignments)
+	router.POST("/v1/auth/admin/roles", wrapper.GrantRole)
+	router.GET("/v1/auth/admin/roles/audit", wrapper.ListRoleAudit)
+	router.POST("/v1/auth/admin/roles/revoke", wrapper.RevokeRole)
+	router.POST("/v1/auth/admin/unlock", wrapper.UnlockAccount)
+	router.GET("/v1/auth/apikeys", wrapper.Listapikeys)
+	router.POST("/v1/auth/apikeys", wrapper.Createapikey)
+	router.DELETE("/v1/auth/apikeys/:id", wrapper.Deleteapikey)
 	router.POST("/v1/auth/auth", wrapper.PerformAuth)
 	router.GET("/v1/auth/check", wrapper.Check)
+	router.POST("/v1/auth/check", wrapper.CheckPassword)
+	router.POST("/v1/auth/devices/report", wrapper.Reportlogin)
+	router.POST("/v1/auth/email", wrapper.Requestemailchange)
+	router.POST("/v1/auth/email/cancel", wrapper.Cancelemailchange)
+	router.POST("/v1/auth/email/confirm", wrapper.Confirmemailchange)
+	router.POST("/v1/auth/link", wrapper.Consumeloginlink)
+	router.POST("/v1/auth/logout", wrapper.Logout)
+	router.GET("/v1/auth/me", wrapper.Getidentity)
+	router.POST("/v1/auth/mfa/email", wrapper.EnableEmailMFA)
+	router.GET("/v1/auth/mfa/recovery", wrapper.GetRecoveryCodeStatus)
+	router.POST("/v1/auth/mfa/recovery", wrapper.RegenerateRecoveryCodes)
+	router.POST("/v1/auth/mfa/totp", wrapper.EnrollTOTP)
+	router.POST("/v1/auth/mfa/totp/confirm", wrapper.ConfirmTOTP)
+	router.POST("/v1/auth/password", wrapper.Changepassword)
 	router.POST("/v1/auth/passwordreset/request", wrapper.Passwordresetrequest)
 	router.POST("/v1/auth/passwordreset/verify", wrapper.Passwordresetverify)
 	router.POST("/v1/auth/register", wrapper.CreateAccount)
+	router.GET("/v1/auth/sessions", wrapper.Listsessions)
+	router.POST("/v1/auth/sessions/revoke_others", wrapper.Revokeothersessions)
+	router.DELETE("/v1/auth/sessions/:id", wrapper.Revokesession)
 	router.POST("/v1/auth/verifyemail", wrapper.Verify)
+	router.POST("/v1/auth/verifyemail/resend", wrapper.Resendverification)
+	router.GET("/v1/auth/webauthn/credentials", wrapper.Listwebauthncredentials)
+	router.DELETE("/v1/auth/webauthn/credentials/:id", wrapper.Deletewebauthncredential)
+	router.POST("/v1/auth/webauthn/register", wrapper.Beginwebauthnregistration)
+	router.POST("/v1/auth/webauthn/register/finish", wrapper.Finishwebauthnregistration)
 
 }
 
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/9RYTU8kNxP+K5bfV8phW8ywcJpTNqskyh6iFctmDwihmnb1jEO33bjsGUaI/x6V3V9D",
-	"NywQQJsLtNrV9fHUU4/tuZG5rWpr0HiSixtJ+RoriI8f8twG4/mxdrZG5zXGBaxAl/zgdzXKhSTvtFnJ",
-	"20zWQLS1Tk0uBkJnoMKJxdtMOrwK2qGSi7MmwOCLgefz20x+CH79sQRdjXPL29doQsW+Wh/vOg+ZrAqQ",
-	"HNHgVp5n40yrAi5yq/DpNXp7ieZfVp9K4Dp/ZRz+QqcLnYPX1ozrfYmAA5iTuxjbOesm8G1gKayrwMuF",
-	"1MYfvZcdiNp4XKGLKCIRrB5TMPvs7Tn6p2+n49iQ50h0cX/JeF1rh3Shh8vDjAq46AN3FktrSwTTWqS3",
-	"30t6L5m90HfiDJxyZZ8b+pwgoT/Bq4D0pBF7VluTu1H4SK3dOPqrU3zEuL0BZ+vcGgoVp3Mmoa7LZgBm",
-	"f5M1PLPaFDbG0b7kQPgbGA+0ExD8WmZyg47iwMjDg/nBnNOzNRqotVzIo/iKY/p1LHi2OZzxh/FPRMCm",
-	"rjAqMfAfSi5kjY55/yGFcKl5v1i1S5NhPCa5HCXcCSs//d9hIRfyf7NeeWeN7M56ZYsoKKTc6TqNfpQ9",
-	"NL7xLKJOkACj0qNQ4EEOgfYuYESeamso9fb9fP5i2fKUTuT56dtpbMMg19TlaFlAKP2LpZBkaiKJYPC6",
-	"xtyjEtjYZJJCVYHb7WOJAgQTUjj0wRltVgIE11BYJ4rgg0PR8SDhTegFIVFXWrRdOrsldMSshlWiLlPl",
-	"nEN3FMvXmF9yWSucoFhaZWo6qNCzt8XZzZ3aPrKR0IVo50hoErABXcKy5KFiAZRXAd1OZjKN5HDmeoYU",
-	"UBJmA6zvzu35NH328zmJwKHilBwLi9gCCQpRIotQcuOPpz780/pB3m9Jj6936CFsngeGZJ8mHdJdPxjq",
-	"DZRazYaAP9DvVtgiMDM3UPxpjRmat9avIzaTW9GU7tAlRYLHbSQOQJulsIXwa4xEfKz07Ds/XWsSaFRt",
-	"tfECyi3sqJnEjkKZCKZE4vcxSZFOH9wKbWIzfkTufAnLSnsBPVhpNvqmPpo1m36j/j5pGuM34ExzfpiA",
-	"6CQRgTpyRJVi5oBod/xnkOX5QoPbvg1KK2GsF2vYsPZHAjWUSg6Oxw6+8gahLFL8Eq81eWFdI/786j9H",
-	"w83wXvEgFx2uNHl09/Mvdwge2wvjU5mH11DVJQ7Oven/z2tLnolzkNtqeERc8PFu98W7uVl9PibazuO1",
-	"rj9/ytVxdeR+P3Tl4RH35JGHryb/KQlMS0KhB13SI9h7OOZQ66Tna7kTCbq3Zs6lsVszeTD6GPMRIAxu",
-	"BXQdfYAeSW+6G8s0Q15VlMb35Imih+svpUHDGZqUouOpDQ9F7exGK1TsYD8nFpV4GGVvrCuFDUYdiNO4",
-	"WFsivSx3Il031cGPKDhpV2gO1T+RaH/NuUuh24yvnSrk99zysr1XtbPLEqt36QJ4+08AAAD//0EtYpa4",
-	"EgAA",
+	"H4sIAAAAAAAC/+w9a5PbuJF/BaW7qiQVesa767qq+NNNvN6cs7bXN2Pffsi4VBDZkrBDAQoAjqxs+b9f",
+	"dQMgQRLUazSy7Mone0Ti1e8Xmr+PcrVYKgnSmtHz30cmn8OC03+v3r36Gdb4v6VWS9BWAP2ea+AWijG3",
+	"+NdU6QX+b1RwC0+sWMAoG9n1EkbPR8ZqIWejz9kIPi2FBrPXGFHgu72fS27suDJ7bkDyBSSnW2qYik/4",
+	"qACTa7G0QsnR89GN5doyNWV2DuwO1hmzimnI1UyKfwETlk3WqYU03Ku7PTdncrV0sBUWFia5T/8D15qv",
+	"R59poX9WQkMxev4PBJU/Yn2getYsRlgLEx8/Z6OrPFeVtH0sw4KLMg0xbsxK6TR2KgN6ANadPbsFohHR",
+	"zNHOfoQSHEq6O9ywj85Sm+a9sdxWpj97gc9hzKcW9K6Y7CzbmiJa+uWnpdIJkPMGF/+pYTp6PvqPy4Y7",
+	"Lz1rXrrhUATUfc5GfCnGd7Buk9CmOTxv9+gqc2gZ53MuZ7D7fGFPL3H0Cxo8PPk9aDEVOUfwj3NVHLDO",
+	"C1UkF5hyUUIxLtVMyPasu3Fid747qVZyXMC9yA/Y5c84+kcanJqcdjkuhbwbW3UH8nhgWEz5kQGLMy7A",
+	"zlWx/5xvfrp6Q0OHJkaxeg96feCer/3wob0j++/FHb/C5Kqyc/lCQwHSCl4OTYtSZazBgD02CjVMNZj5",
+	"odNeu+HvcXRyelXCmBsjZnIRdP9OC1yrEq7qcYNT7ytAaNqqEPaltDopmAzklRZ2PYb7vTZ848e9vB/Y",
+	"rwFjhJL7zEgDtmrlIM83yL0hKmozXMzQPcroCr2uCI/oP8ltkfboA7krASNopeRXgq469BCpwQ+yVPld",
+	"Xw3ubkLUb9KslZ2/KLlY9GfkxoAORsQujH9VD/icjfIwKchqES/754A7B9gITR1Id5FGf0tY1bhCGI6y",
+	"0QomHJcf4aICpB3ntQQyo48JfeVfGzCV/VMDuQabfGPYyquPsrcJSHZnwqBe8hyYgSXXaI0yes04q/qf",
+	"FRjLVsLOWf/gGStgyqvS0su8LMNQb5y7EUltTsB+mJnqsI8E9sKZ0UNukeei3S0v//Lm5fGlmkPjbdyA",
+	"RpZ84Q7fd9Lq3zdLsniSHSimBxwP+vY43GZsCR7HuRjwH1rGPa36f5Gc7a99DKKIfBY3Ha2ttdIJTHgm",
+	"qg1QIe0P3zfkKqSFGWjiOTCGzzaz3HgKUEx4frcNt+/8gBurQc7svI88L6j8onSEjmuRclFgib6kBb3Y",
+	"z50/JGxwiBO2SajFenjPrYiH+rvko0eEE+g3gkuMgheebL5E7KVLKBviBynHL8ECcir0Ys99H3JWCavx",
+	"MPpVWQw+7Zy5eTWedBBbsad3FKRRlMsAyCOhrTVfvPPGOfuCaHM/bMMJPR1EQcsLPAoO9gwyDoO/vc3I",
+	"MftisdUpX4hyPWQ3HhLDfBiwmv1sDlf+Dzfzd1zzhXkxqKDwQRxDjRQsL2dKCztfpMVDZXHHMUwmSpXA",
+	"pVPAuOx2Km3WqMdkzbaiVfA8r8jItQlzEr2mvULCh4SRnUIaP1i3hVlaCs6doN4WHvfvv/580z9qLzbj",
+	"F1OT3yC3W91sGu5mf5+2WIwZD1t9gcKETNOMc+TCcinKwDcGJFjX8dtOPfF2W5vr7CRaFs/+Ws1UleCH",
+	"rct/zkZvfroaEJpp/y9lR35088RC2KQnfEiew43vrDUUwdeosyVOm8BrZ+LmXZz8LayGvLy2sG17uD9G",
+	"bupfnrKCrw2barVgUq0yxi1bKGMZZ2vgun4wyh6awXpw9sjza8Snb2G1xb885W6CJzNoXlZao/e5MSqB",
+	"RtzuzmVvys4E7W1BKoz16Am05GZQQV5DOr/kDzWwJ80XYEGPDewRXO3q460M7LfQWzA+wzUYsNcuKLQP",
+	"XA/y3910veUphrDeK+mYPU5oIUsjunbrB330tLIyudKQ1nSmms3A2F4sfKsRseK6I2cHDucWz5otNmPb",
+	"y+NJY11yDTOQoPlRM8GdVEZv2pnmcl9jPIyZrPv64YNHbQhc8mIhZMYWwhghZ0xMmR/NlGTGcm2rZbrE",
+	"gBuVpjO0uY5HgDRbvV4WA6SGX5OzSRBiQFeInNMEo+BgJCPaPLdKHwI7l2HYBrtDHKxTw9vDLQJ8x5dE",
+	"wA8pwx02G7BBMBxlo4UqkLWUJiZckvL4mD0KDeHu2wm5o7jABVivFNpU8/KT1ZzlSlr4ZDMGF7MLoh7K",
+	"XDGXXmNKszc/XTEfXejNLZYDdj3ppyHfKfgDAdYuV2YqMu/r3Blm7ioN/TSgnx2K/iPUwiW4R+14pvcJ",
+	"QGpVllDUq2hwoYdBpI75bMguiHHehu0vslwzA5ZNlXbsaDLmUpEOvoaeVJIyiCxMZNic3wOTSm6PCniU",
+	"iOWotc8W8HvMsVNiZDBjdgDxnaZO7ag1Yg0Q0mViDo4uy30UBo1Mz05W0BMQcqVPLQcRT+YP0k2BDlUp",
+	"jGVR8rlv2QzgQSz7q14VhQZTZxCdOPC7KLkFY/3q3n0+VoR2C7ulMNYn/uG4bgNnROD7X96/e0myIG3e",
+	"bEgLV1ps354f797GBfvZ896amN4GaTE5p/S44JZvSlzj8/FvQ+qsSREPcTOadtxWelhFj+dcFuUuQY7W",
+	"YokdZqmzxVuIIRQVFh2FvU4jgqzm0qAqOZYYiibsiZ8+qH70LJxKsg5p4SPtON7QL8vaQ+ogbs7LEuRA",
+	"/jau5OjJo+aQFDyCT3lZFcBWcxJBM2Es4DwZyihelmoFhXtYqtmM7F85yg6tbYvgmqrmWg5mCZbjYVIR",
+	"C/DxyPZJ34iyFAZyJQuTzIFHAekBe6MLlUExu8Vu2T5PVwbUCA5gaQ7aRnBML9e0wpDryq0FY+nh2Me7",
+	"DxaIj8u4KYnX3/xHiijnSppqgSf8x4gvl6UvxrikcR+zkZBTRTsQFoXvCNzuCs2n1jAUpKNsdA/aGSCj",
+	"7y6eXjzFDaolSL4Uo+ejH+gnNI7tnA5zebGCsnxCxublb6s7cxHgNHNKDqFOu3hVjJ7jj5SMwDOapZLG",
+	"oeP7p0995tN6ndnbfn1JYhun0fwEjjYB/v3ml7fsV5iwn2HNbgD9ksXSrtGHdWV0jGtgqDigcDVRnJk5",
+	"11Awr3JpSoo1H22zrnglsdsPEj4tIbdQMMB3mMrzCkniM0WMFguOrv/oXTUpRY4XJUxtsuERCJn+XBlZ",
+	"esbZ54bZObdMITeSA7NmdRGh5TNHOkgIH3Ghy/vvLvGvS59IuyyiawH0f+jjOOcyh9KPqAekMd6N5LuX",
+	"mZuihIJEr1QS2IobhlArKvSxPmejZ0+/S8k5F5hQmgl5z0tRuOOdI+5e0CEZb47FArQiCxljK8L5cX8w",
+	"rKlt7SArGy2VSQj+93MIg5gwjEsl1wvxLwrY5ECL5EqVQs6eqOmULUELVbA5Nwz9X0RAJa0obyUaWfR6",
+	"ESGJTaDB1QW7KsvaVyBu8vtXlb24laOsQyjenUxRCj34qyrWR8NW915LAm8vnBXPouxDI4mtruDzI4qt",
+	"9P2YxC5rJtmdG5xTpTRbaSVnzflw3Pd/eXyGeK8UW3C5DnEKVGCLpTUZ02D1mlGhGRGXrBYT0Ej+3lhB",
+	"2scH1/jikyt6cQ68AH2OLH3jUfIARk5JXahvLiWVqnvczPjYNOovUiXAhAIALRVmrELFySeqsgQAPPYF",
+	"uyFFalgp7qAmQ5Q2c0DZVDDKNDvRAVNLguObkPUOZAwwsWLnxJNzcEraK2YHMhOBrE0z2yjFintf0JKk",
+	"EYzj1C89kESOcf+jD8nXFLzMGrKguKsjC2XnoFm4JcH8LYkhwnirbAO8c6SGa8hRz3TOk5YVGZOwAmPZ",
+	"VGizTV5gOPjSeQxmIym0orXmRATRKXrfRhB+gL9oYDIyypE5nDluzhGzr4WxtXNLnkPrDDH6EFctw61j",
+	"RVNcpg20x7GMelUnieO/jXKA4UzImPS3uxYiLFvwdbhVclLbKXk3I2WHoIUBK4+NC/Z+Dp6Y0DYmn8jM",
+	"MWti5/R3Dl77JHyVV17luOyo9lBw/gr+xEsNvFgzy89UJV17KkUHpEWldWKgfyeIuWs5fSoelEKXv4vi",
+	"8yZv0f3epfO6TAVX+X0k8FwYcwhBzOcuoNkmsSyCXDek9HEX/7MtcZiGhboP5vWzlJ5hpsrnHfCdJ67x",
+	"JD1MZ8i06MZJxUolZ6DZDGwiIDCIZbjfQdVEhoDp4zYRJsRhLaUoYoOZiOGflbvg56khznzXNDHlpdlI",
+	"FNnW1alckNZ/9Y5xl74a2IJY7rf4x7O0wm5SNlaCVd6CIJssAJ5JpRFGM3EPcpBjvAPBpEIpU8niLJ24",
+	"jlk2WdcRFDrjsEU2yCbOw9kUFU0UEj6iwkysllL6tTPuz+9KC8iJYTUXU1DfGatnGWUjxNUHWIJuO55C",
+	"ztpn2Qmh9TWBQbHXrnLbQ+7R1I8q9k4iebY1LOgjFEew+A77Vy1FXgdcxkfKEK9KQixQ8GZ1dE9l2D/o",
+	"27I0vXAxMpfCEMZUUNRV7kzJjAmJaU2kc19WQcFfMBe3EidxBXw4jYZc6cK5njZMz7HSEH3SVASXCguv",
+	"Q6nZ8V2TqOIuFW1yUMv8RmXBXLlbY8C6oTu6Iglq9JWXPgHsDPo5lMWgWvzgy65oR0rXhZJuY183Rf8N",
+	"gcG4O5tVjMtUFHOzyLwkctouOOmtnUWmwzPl8L8BobmhHcuQ0Aw8+nUTGJ3F4/IAI8sRmK9xfv77kNxs",
+	"CcoJTJUGZixfM+fPU7oLpceauUtHWSQ2nZS9lY2/lHOt17W0dCGFXSTqrRwQqW7/37BMdQccFqFvWiIT",
+	"hSiNwJ9QshKuaXe7EztNgmvjL0F+n5+PjnAJ8pU0+J4Stmo6+yRDiu75VZQnerQ0q28yNExgKKv9fg8i",
+	"pTCNmwNTTbLopheRZmfK2q/dIX0tpqh38aAY/s45VXpM1sx28qquoDYcfxvNLEW4ATycMfLvnEL3DfVG",
+	"TJDQu1dU8dJkBGwTyF1ybb+JlCE5EJbKN6y4B/RcjZK8ZOH46XzRptKQVIbBofjxUgsBremcQhayB8i+",
+	"pG/XUfPXL5FEGN5vyB7cwfqCvXpgyqA+uNL+3ORk1BxNbPdNVDkRWBnv0W9tUeyV53YCacesQk3bXyad",
+	"EA7asnj2ROfGxINf4KytmR7a1XR/rFf+wm1Sji1BY4U9Vv0+llFT9zhMaaPm/gFVxeF7vpgF/8v8jYTT",
+	"CTLsx5Gqf/31PePtvRKVOZ8l9ED0mw6KFS3QcK3kVgppLHB0CWylpWGd2nziaMnvxYxbpS+ivOHFDOwf",
+	"/5TdSgM+aeyW4TMuJC1GP9ZL0Uzc7++WGOH7p98nHAraCBS1LGn6OoaDzIHyFDaqg4RPwljjKkvtBbui",
+	"itknQjIaJ4ybBYlzigmyQoG5+Het3M4nqDoSoiMZIn5B+YDc7wkKZSBnSKeIzmllKw2s5nXHUwbq62i+",
+	"rBHfnWi1MqC3FDHnoWNE0t51T7fEnKjpBNJFnffCcNM9FyWfkMf+6AGnAQYQU1cv5eqk3X3XaVUO5++U",
+	"jfZ9jnZDgHSTnBHGxWguY4DvauzidO/ahb3HVhPtziSJo4cX6tIZV9emo6sqbCqgLKIyOEP3qF2XHByz",
+	"oOrIFfA70P4cp1EricaW/eyxf8ao44QLYjxFV/+ZE/J1E81zLNE0VixQJtXpQeNPkzED0gbpOlHFmhkV",
+	"IUhiRpShZquW+NaH69ekXVxTKbR0tggm3+b5UjcdZJKxyw8m5HZwI7cj8jlW3Mg/WLaA25FXYG6f6Kq4",
+	"gACpswv2WuV3Bh/dSh6ifLg3Roq+VbpPghY1Cu+UY/q5knFL3Dst+Ejs1W8ym4qH0zaojriBVAcUD4s4",
+	"RfGmFGyIViKx+9i07fJNwYsEV0J9nh4BYYZ7TLgAJ6HGkT/jhsJwC14ABbcii02t5DYfoe5PlGadG0/O",
+	"vrmmt9WRSqyqacQX+BBuOW4Gt+Wugt1Kd8+lNcZf+w7jnCXtqCAEB5r2KDncyu5CwrC62+eGGzI0ZSvw",
+	"/ii8NZwVeAsrf64YRPmB92U6JlWMEZzWw53WM2wFGrpXXU7AWCFWEyV+o7JeR2z/vnZzNqKFOALZ26Xe",
+	"1LRDrofEmmiGS8f2w4LF3eIjTQmSqizcHtCv9LuhG5dolwdKqnmeCXMrNZoP1juxnK3mAjNvCHJElLFK",
+	"h/siqixaDBiuoavK3spYg6dkiTvIiUTJNjX9IhKmtZpu9GcQsNGBDxMutJmAh/qmok8qEtRPrqrpzD7U",
+	"W6K9aZXfzDlfUvWmUwBlHbKh0wyjbDt7OVYYju75F86FbnvmwwbqjTT9Q6g3cLw3Jb60bZmFjj1hXyjZ",
+	"It3oNccCuKRWJ+dI1A6L3vxsa4o0ZYstN6/Q8dpac+L6C8hZCU8q44orzFxp+6QU93h9+poIovDXI3kU",
+	"xrmVvY/fuPhmXN2H3dTqXhlpFeD6Q5AB7r9988U4qe4siGDwkbzASnE89pxD55EFeBJWzFyPh4ghT5/2",
+	"2xLdvREzyeKYfoTaoESg6GJ4E2c1HbaT+sE/fxxK9u29k3EGqgnzx3MqXN1BVoeiKYCC6QPu3E51J4D5",
+	"BEo/ELxbBKJc8bXxwXIosrhviVue4sBUu0ZuE7kwZ5wdjH3ocBbKmwHX7nK0g9sWS2IBg0H9GVgRev0/",
+	"otyovycwIOjoWCt0A+jIdWLMFRjvn+jHH844+RvAwSYwFz7U3d13XRJZ3xfdhOEp7wd42pgGiQkBUj1v",
+	"frp6TGT3+u2nksKR+TBZNzEhrMkPrM+UdLGwC/ZCLcCbHuHjdb4VAvmOyOBRqcnmKhM0A4Kj6YByliLg",
+	"JW2NKek64vnjtgHGjY9HsKnrT7yVSAL0NgmEGHm+zcppqGW4p8t1jHRm/Hvnh7TmRlglyRTpUOsDK9J0",
+	"aDcObQZ7pELooUbn59YaaBeBgxHaNjKynshgv5Shw0pTwL5S+m6zMJHKxoIkqah+7UU4zzD9UHLf8WoX",
+	"qg0t4NBD7NwKTIseq+xyk3rSqiyxN+xjCptO79lUCtoHKfHNUDLrOqwsyeD6cP3KWbOyQLPFsP+9rjNK",
+	"X7O6ubFcW3dqqAF0UFg44Hrn0FWN9OOLsPAJoWSkKg5OtZrkMr5cnp0AI9ScxkIKuZ0QiZWqjt1DxDzn",
+	"G7fqknHtbNNlKZYPo34zaccf9EhHsq4dZkzLdQvDXANAHXvGtXAlkco13MrgqYYamBwjVtR4wjTilob5",
+	"7pWh+ZMvTsA+9rfSogKbIce6GsLQzVDUZRc03AdK0tEwiqcuT1UTNJRbDaYFwgNP/0VMjIHAF1oVQ8Gv",
+	"5kYYIfBkYelQhl8HQukCdJ2ttUpRddS+2dkuMf87S3v8ij6fDY1KrA4oBg9jqebmUkffq0pXh8evt5tT",
+	"PRavtz6klQoOmDvfScN5ubLoAcSD4YCc0XusCQNZLJVAoRKHDOvC0AyvzaGM9FBgrkN9xElnacJVkwXG",
+	"U7tlVztFkNpUc998Zmw70fiXT0Az/utnyQBBpHerOHXiq+QPvJB7cPlwLIALUbibtZx6TDlR7Ehq8AoL",
+	"RUULBa7miyrynfRGoYw/fXVkeB8nvDbSYkiWbvAc6NLUoTd14RPHbxVFX+1z//73XBmLhHORq0X8gbvn",
+	"Ixee0k/l7N0zY1ZPdeujss9Hs2eLH/TfvtPldz+4r+LscRd40y1g9wWpXdLj3w1XZDb0igV3BLpTU47L",
+	"lKcSYvX9N6oG2KXrb7B0N97MrV86TRcxWmyXu7kvyTNzJZ7kmc19WecEQIbu+qqyURLz6+3jeuVu5tau",
+	"yUADV/r+rKaer/QNDJDOUduNDHxHizG5QWZYZrjX3FubiaPTRqXtXjUo+nrxQkloJDLXqqzzgatOnA8+",
+	"5bC0LYfWfyRtB9xsu4fqkOJf/4J9LR0AGtxmTFjTzh//5uAatY7anQA23lLlLS45a3rhm2llM1E4O3FL",
+	"mvJRjcmdCm/i58eyHWPbJ2lCPks3MFtqdS8KKHCC9p5QP7gwPDdNd44LRqVUbKmMEZMytOgpLs6Rqpw1",
+	"7684/sHUd092I6FLDQbkhlCcj2JyC4YB16UAHdrnR9d30OpYQZmrRahQ9HkNCtLdShyBlWfevfDeRBNR",
+	"jD51ufYtjq7RninFQiAAlqDr60TpGwy4k45tvB9xuUO0SMyfJP4kDItvANOtTJ+UqD/TedD1++//0h9w",
+	"4+pVfNimvzGToZL/mj5bAbJoFUO2zoQksplsw+Xxy87X1gat1zAgfv8UhmziQ4C79FhrilvRZfpmWnPU",
+	"HWbCqQ7Reinc79ieo08FX8xCeucg0G75fcxWHR7EZ90c3O+xSR0dRAep6EZKuLtYltraOsI59H/80wX7",
+	"q5BcU5++CsytRLN1wg3817NKlwxIlRV1Bz5XBl3fGfeXa6awYgshK5tOCU1gJmQ4SXwt+zGT9t0vXKZy",
+	"RDU8XHyDLNTw+jcgiVx2PvooZEyNB+Toe8R4ORVSmA1NZNzzQdQf31ZPfqdyc3sZurXkiLC+gdqlhlMm",
+	"DVPaNJ2v0z0NetJ0YQ21EKn2Xti3wDo/EeEO8E7TWSdJRQkm+pyNlloVVT7wFdGs9dNSq0kJiz/To4+f",
+	"/38AZDJOTtakAAA=",
 }
 
 // GetSwagger returns the Swagger specification corresponding to the generated code
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/deepmap/oapi-codegen/pkg/middleware"
+	"github.com/deepmap/oapi-codegen/pkg/runtime"
+	authlib "github.com/esportsdrafts/esportsdrafts/libs/authlib"
 	beanstalkd "github.com/esportsdrafts/esportsdrafts/libs/beanstalkd"
 	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
 	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
@@ -20,6 +22,34 @@ import (
 	echomiddleware "github.com/labstack/echo/v4/middleware"
 )
 
+const (
+	// How often keys in 'JWT_KEYS_DIR' are reloaded
+	keyReloadInterval = 5 * time.Minute
+	// How often accounts past their deletion cooling-off are anonymized, and
+	// old security events, devices and ended sessions pruned
+	accountDeletionInterval = time.Hour
+)
+
+// authRouter attaches extra middlewares to some routes when the generated
+// code registers its handlers. Used to put JWT auth in front of the endpoints
+// that require a logged in user.
+type authRouter struct {
+	runtime.EchoRouter
+	middlewares map[string][]echo.MiddlewareFunc
+}
+
+func (r *authRouter) GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.EchoRouter.GET(path, h, append(m, r.middlewares[path]...)...)
+}
+
+func (r *authRouter) POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.EchoRouter.POST(path, h, append(m, r.middlewares[path]...)...)
+}
+
+func (r *authRouter) DELETE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.EchoRouter.DELETE(path, h, append(m, r.middlewares[path]...)...)
+}
+
 func registerHealthChecks(user string, password string, hostname string) {
 	health := healthcheck.NewHandler()
 	health.AddLivenessCheck("goroutine-threshold", healthcheck.GoroutineCountCheck(100))
@@ -33,6 +63,29 @@ func registerHealthChecks(user string, password string, hostname string) {
 	go http.ListenAndServe("0.0.0.0:8086", health)
 }
 
+// loadSigningKeys loads rotating keys from the directory in 'JWT_KEYS_DIR' or
+// a single asymmetric private key from 'JWT_PRIVATE_KEY_FILE' if set. Falls
+// back to the shared HS256 secret in 'JWT_KEY'.
+func loadSigningKeys() (*authlib.KeySet, error) {
+	if keysDir := os.Getenv("JWT_KEYS_DIR"); keysDir != "" {
+		return authlib.LoadKeyDirectory(keysDir, authlib.DefaultKeyGracePeriod)
+	}
+
+	if keyFile := os.Getenv("JWT_PRIVATE_KEY_FILE"); keyFile != "" {
+		key, err := authlib.LoadPrivateKeyFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return authlib.NewKeySet(key), nil
+	}
+
+	jwtKey := os.Getenv("JWT_KEY")
+	if jwtKey == "" {
+		return nil, fmt.Errorf("none of 'JWT_KEYS_DIR', 'JWT_PRIVATE_KEY_FILE' or 'JWT_KEY' found in environment")
+	}
+	return authlib.NewKeySet(authlib.NewHMACKey([]byte(jwtKey))), nil
+}
+
 func main() {
 	var port = flag.Int("port", 8000, "Port to serve auth API")
 	var dbHostname = flag.String("db_hostname", "mysql", "DB hostname")
@@ -40,15 +93,35 @@ func main() {
 	var dbPassword = flag.String("db_password", "password", "DB password")
 	var beanstalkdAddr = flag.String("beanstalkd_address", "beanstalkd", "Beanstalkd address")
 	var beanstalkdPort = flag.String("beanstalkd_port", "11300", "Beanstalkd port")
+	var breachedPasswordsFile = flag.String("breached_passwords_file", "", "Sorted SHA-1 file of breached passwords (Have I Been Pwned, ordered by hash)")
+	var webAuthnRPID = flag.String("webauthn_rp_id", "esportsdrafts.localhost", "Domain passkeys are registered to")
+	var webAuthnOrigin = flag.String("webauthn_origin", "https://esportsdrafts.localhost", "Origin of the web client using passkeys")
+	var bootstrapAdmin = flag.String("bootstrap_admin", "", "Account to make admin on startup if there is no admin yet")
+	var trustedProxies = flag.String("trusted_proxies", internal.DefaultTrustedProxies, "Comma separated CIDRs of proxies whose X-Forwarded-For is trusted")
 	flag.Parse()
 
-	jwtKey := os.Getenv("JWT_KEY")
 	log := efanlog.GetLogger()
 
-	if jwtKey == "" {
-		log.Fatal("'JWT_KEY' not found in environment")
+	proxies, err := internal.ParseTrustedProxies(*trustedProxies)
+	if err != nil {
+		log.Fatal("Error parsing trusted proxies: ", err)
 	}
 
+	keys, err := loadSigningKeys()
+	if err != nil {
+		log.Fatal("Error loading JWT signing keys: ", err)
+	}
+
+	// Pick up new keys dropped in 'JWT_KEYS_DIR' without a restart
+	go func() {
+		for range time.Tick(keyReloadInterval) {
+			err := keys.Reload()
+			if err != nil {
+				log.Error("Failed to reload JWT signing keys: ", err)
+			}
+		}
+	}()
+
 	swagger, err := auth.GetSwagger()
 	if err != nil {
 		log.Fatal("Error loading swagger spec: ", err)
@@ -63,16 +136,153 @@ func main() {
 	defer dbHandler.Close()
 
 	beanstalkClient := beanstalkd.CreateBeanstalkdClient(*beanstalkdAddr, *beanstalkdPort)
-	authAPI := internal.NewAuthAPI(dbHandler, beanstalkClient, []byte(jwtKey))
+	revocationStore, err := authlib.NewGormRevocationStore(dbHandler)
+	if err != nil {
+		log.Fatal("Error creating token revocation store: ", err)
+	}
+
+	var breachedPasswords *internal.BreachedPasswordCorpus
+	if *breachedPasswordsFile != "" {
+		breachedPasswords, err = internal.OpenBreachedPasswordCorpus(*breachedPasswordsFile)
+		if err != nil {
+			log.Fatal("Error opening breached passwords file: ", err)
+		}
+		defer breachedPasswords.Close()
+	} else {
+		log.Warn("No breached passwords file given, passwords are not checked against known breaches")
+	}
+
+	// Carry out account deletions once their cooling-off period has passed and
+	// prune records that are no longer needed
+	go func() {
+		for range time.Tick(accountDeletionInterval) {
+			count, err := internal.AnonymizeDeletedAccounts(dbHandler)
+			if err != nil {
+				log.Error("Failed to anonymize deleted accounts: ", err)
+			}
+			if count > 0 {
+				log.Infof("Anonymized %d deleted accounts", count)
+			}
+
+			pruned, err := internal.PruneSecurityEvents(dbHandler)
+			if err != nil {
+				log.Error("Failed to prune security events: ", err)
+			}
+			if pruned > 0 {
+				log.Infof("Pruned %d old security events", pruned)
+			}
+
+			pruned, err = internal.PruneKnownDevices(dbHandler)
+			if err != nil {
+				log.Error("Failed to prune known devices: ", err)
+			}
+			if pruned > 0 {
+				log.Infof("Forgot %d devices not seen for a long time", pruned)
+			}
+
+			pruned, err = internal.PruneSessions(dbHandler)
+			if err != nil {
+				log.Error("Failed to prune sessions: ", err)
+			}
+			if pruned > 0 {
+				log.Infof("Pruned %d ended sessions", pruned)
+			}
+
+			pruned, err = internal.PruneAPIKeys(dbHandler)
+			if err != nil {
+				log.Error("Failed to prune API keys: ", err)
+			}
+			if pruned > 0 {
+				log.Infof("Pruned %d revoked or expired API keys", pruned)
+			}
+		}
+	}()
+
+	if *bootstrapAdmin != "" {
+		err = internal.BootstrapAdmin(dbHandler, *bootstrapAdmin)
+		if err != nil {
+			log.Error("Failed to grant admin role on startup: ", err)
+		}
+	}
+
+	attemptStore := internal.NewGormAttemptStore(dbHandler)
+	relyingParty := internal.RelyingParty{
+		ID:     *webAuthnRPID,
+		Name:   "esportsdrafts",
+		Origin: *webAuthnOrigin,
+	}
+	authAPI := internal.NewAuthAPI(dbHandler, beanstalkClient, keys, revocationStore, attemptStore, breachedPasswords, relyingParty)
 
 	// TODO: Attach more middlewares and move to global lib for easy use
 	e := echo.New()
+	// Before anything reads the client IP
+	e.Pre(internal.TrustedProxyMiddleware(proxies))
 	e.Use(echomiddleware.RequestID())
 	e.Use(middleware.OapiRequestValidator(swagger))
 	e.Use(efanlog.EchoLoggingMiddleware())
 
+	userAuth := authlib.JWTMiddleware(authlib.JWTConfig{
+		Keys:            keys,
+		AllowedRole:     "user",
+		RevocationStore: revocationStore,
+		Sessions:        authAPI,
+	})
+	emailVerifyAuth := authlib.JWTMiddleware(authlib.JWTConfig{
+		Keys:            keys,
+		AllowedRole:     "email_verify",
+		RevocationStore: revocationStore,
+		Sessions:        authAPI,
+	})
+	// Same as userAuth but also accepts personal API keys
+	apiKeyAuth := authlib.JWTMiddleware(authlib.JWTConfig{
+		Keys:            keys,
+		AllowedRole:     "user",
+		RevocationStore: revocationStore,
+		Sessions:        authAPI,
+		APIKeys:         authAPI,
+	})
+	adminAuth := authlib.JWTMiddleware(authlib.JWTConfig{
+		Keys:            keys,
+		AllowedRole:     "admin",
+		RevocationStore: revocationStore,
+		Sessions:        authAPI,
+	})
+
 	// Register routes
-	auth.RegisterHandlers(e, authAPI)
+	router := &authRouter{
+		EchoRouter: e,
+		middlewares: map[string][]echo.MiddlewareFunc{
+			"/v1/auth/mfa/email":                {userAuth},
+			"/v1/auth/mfa/totp":                 {userAuth},
+			"/v1/auth/mfa/totp/confirm":         {userAuth},
+			"/v1/auth/mfa/recovery":             {userAuth},
+			"/v1/auth/activity":                 {userAuth},
+			"/v1/auth/sessions":                 {userAuth},
+			"/v1/auth/sessions/:id":             {userAuth},
+			"/v1/auth/sessions/revoke_others":   {userAuth},
+			"/v1/auth/verifyemail/resend":       {emailVerifyAuth},
+			"/v1/auth/password":                 {userAuth},
+			"/v1/auth/email":                    {userAuth},
+			"/v1/auth/account/export":           {userAuth},
+			"/v1/auth/account/deletion":         {userAuth},
+			"/v1/auth/webauthn/register":        {userAuth},
+			"/v1/auth/webauthn/register/finish": {userAuth},
+			"/v1/auth/webauthn/credentials":     {userAuth},
+			"/v1/auth/webauthn/credentials/:id": {userAuth},
+			"/v1/auth/apikeys":                  {userAuth},
+			"/v1/auth/apikeys/:id":              {userAuth},
+			"/v1/auth/me":                       {apiKeyAuth},
+			"/v1/auth/admin/unlock":             {adminAuth},
+			"/v1/auth/admin/hashes":             {adminAuth},
+			"/v1/auth/admin/roles":              {adminAuth},
+			"/v1/auth/admin/roles/revoke":       {adminAuth},
+			"/v1/auth/admin/roles/audit":        {adminAuth},
+			"/v1/auth/admin/events":             {adminAuth},
+			"/v1/auth/admin/clients":            {adminAuth},
+			"/v1/auth/admin/clients/:id":        {adminAuth},
+		},
+	}
+	auth.RegisterHandlers(router, authAPI)
 
 	log.Info("Registering health checks...")
 	registerHealthChecks(*dbUser, *dbPassword, *dbHostname)
@@ -38,6 +38,11 @@ func CreateDBHandler(hostname string, user string, password string, dbname strin
 	}
 
 	db.LogMode(true)
-	db = db.AutoMigrate(Account{}, EmailVerificationCode{}, PasswordResetToken{}, MFACode{}, MFAMethod{})
+	db = db.AutoMigrate(Account{}, EmailVerificationCode{}, PasswordResetToken{}, MFACode{}, MFAMethod{}, RefreshToken{},
+		LoginAttempt{}, LoginLockout{}, EmailChangeRequest{}, LoginLinkToken{}, WebAuthnCredential{},
+		WebAuthnChallenge{}, MFARecoveryCode{}, APIKey{},
+		ServiceClient{}, RoleAssignment{}, RoleAuditEntry{}, SecurityEvent{}, KnownDevice{}, Session{})
+	// AutoMigrate never changes existing columns, widen the enum explicitly
+	db.Model(&MFAMethod{}).ModifyColumn("type", "ENUM('email','totp') NOT NULL")
 	return db, nil
 }
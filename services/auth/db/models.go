@@ -32,8 +32,11 @@ type Account struct {
 	Email           string     `gorm:"varchar(256);not null;unique_index" json:"email"`
 	Password        string     `gorm:"column:password_hash;varchar(256);not null" json:"-"`
 	AcceptedTermsAt *time.Time `json:"accepted_terms_at"`
-	MFA             *MFAMethod `json:"mfa_method"`
+	MFA             *MFAMethod `gorm:"foreignkey:UserID" json:"mfa_method"`
 	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// Set when the user asked for the account to be deleted, it is
+	// anonymized after this point in time unless the deletion is cancelled
+	DeleteAfter *time.Time `gorm:"index" json:"delete_after"`
 }
 
 // EmailVerificationCode is used to verify a users email
@@ -54,6 +57,45 @@ type PasswordResetToken struct {
 	ExpiresAt time.Time `gorm:"not null;" json:"expires_at"`
 }
 
+// LoginLinkToken is a single-use token from an emailed sign-in link
+// Uses the built-in ID of the object as the token
+type LoginLinkToken struct {
+	Base
+	User      Account   `gorm:"foreignkey:UserID"`
+	UserID    uuid.UUID `gorm:"varchar(36);not null;index;" json:"user_id"`
+	ExpiresAt time.Time `gorm:"not null;" json:"expires_at"`
+}
+
+// EmailChangeRequest is a requested change of an account's email. Uses the
+// built-in ID of the object as the confirmation code sent to the new address,
+// CancelCode is sent to the old address. The old values are kept so a
+// confirmed change can be reverted from the old address.
+type EmailChangeRequest struct {
+	Base
+	User               Account    `gorm:"foreignkey:UserID"`
+	UserID             uuid.UUID  `gorm:"varchar(36);not null;index;" json:"user_id"`
+	NewEmail           string     `gorm:"varchar(256);not null" json:"new_email"`
+	OldEmail           string     `gorm:"varchar(256);not null" json:"old_email"`
+	OldEmailVerifiedAt *time.Time `json:"old_email_verified_at"`
+	CancelCode         uuid.UUID  `gorm:"varchar(36);not null;unique_index" json:"-"`
+	ExpiresAt          time.Time  `gorm:"not null;" json:"expires_at"`
+	ConfirmedAt        *time.Time `json:"confirmed_at"`
+}
+
+// RefreshToken is a long-lived opaque token that can be exchanged for a new
+// access token. Only a SHA-256 hash of the token is stored. Tokens are rotated
+// on every use and all tokens rotated from the same login share a FamilyID.
+type RefreshToken struct {
+	Base
+	User      Account    `gorm:"foreignkey:UserID"`
+	UserID    uuid.UUID  `gorm:"varchar(36);not null;index;" json:"user_id"`
+	FamilyID  uuid.UUID  `gorm:"varchar(36);not null;index;" json:"family_id"`
+	TokenHash string     `gorm:"varchar(64);not null;unique_index" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null;" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
 // MFACode is very similar to email verification codes. But we have explicit
 // code property since it needs to be a bit more human-readable compared to
 // UUID:s.
@@ -75,7 +117,10 @@ var /* const */ NullAccount = Account{
 }
 
 const (
-	emailMFA MFAMethodOption = "email"
+	// EmailMFA sends a one-time code to the account email on login
+	EmailMFA MFAMethodOption = "email"
+	// TOTPMFA uses an RFC 6238 authenticator app
+	TOTPMFA MFAMethodOption = "totp"
 )
 
 // Scan turns a value into a MFAMethodOption
@@ -89,15 +134,198 @@ func (p MFAMethodOption) Value() (driver.Value, error) {
 	return string(p), nil
 }
 
-// MFAMethod denotes a MFA device type.
+// MFAMethod denotes a MFA device type. A method is pending until
+// ConfirmedAt is set, only confirmed methods are used during login.
 type MFAMethod struct {
 	Base
-	Type string `gorm:"type:ENUM('email');not null;" json:"type"`
+	UserID       uuid.UUID       `gorm:"varchar(36);not null;index;" json:"user_id"`
+	Type         MFAMethodOption `gorm:"type:ENUM('email','totp');not null;" json:"type"`
+	Secret       string          `gorm:"varchar(64)" json:"-"`
+	LastUsedStep int64           `json:"-"`
+	ConfirmedAt  *time.Time      `json:"confirmed_at"`
 }
 
-// SetMFAMethod sets MFA method on an account
-func (a *Account) SetMFAMethod(db *gorm.DB, method MFAMethodOption) error {
-	return nil
+// MFARecoveryCode is a one-time code that replaces the second factor when
+// the MFA device is lost. Only an argon2 hash of the code is stored.
+type MFARecoveryCode struct {
+	Base
+	User     Account    `gorm:"foreignkey:UserID"`
+	UserID   uuid.UUID  `gorm:"varchar(36);not null;index;" json:"user_id"`
+	CodeHash string     `gorm:"type:varchar(255);not null" json:"-"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+// APIKey is a personal API key for scripts and bots. The key is only shown
+// once on creation, a SHA-256 hash of it is stored. Revoked keys are soft
+// deleted.
+type APIKey struct {
+	Base
+	User   Account   `gorm:"foreignkey:UserID"`
+	UserID uuid.UUID `gorm:"varchar(36);not null;index;" json:"user_id"`
+	Name   string    `gorm:"type:varchar(64);not null" json:"name"`
+	// Random start of the key, used to look it up
+	Prefix  string `gorm:"type:varchar(16);not null;unique_index" json:"prefix"`
+	KeyHash string `gorm:"type:varchar(64);not null" json:"-"`
+	// Comma separated, e.g. 'lineups:read,lineups:write'
+	Scopes     string     `gorm:"type:varchar(255);not null" json:"scopes"`
+	ExpiresAt  time.Time  `gorm:"not null;" json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// RoleAssignment grants a role on top of the 'user' role to an account.
+// Revoked roles are deleted, RoleAuditEntry keeps the history.
+type RoleAssignment struct {
+	Base
+	User   Account   `gorm:"foreignkey:UserID"`
+	UserID uuid.UUID `gorm:"varchar(36);not null;unique_index:idx_user_role;" json:"user_id"`
+	Role   string    `gorm:"type:varchar(32);not null;unique_index:idx_user_role" json:"role"`
+	// Admin who granted the role, uuid.Nil if granted on startup
+	GrantedBy uuid.UUID `gorm:"varchar(36)" json:"granted_by"`
+	Reason    string    `gorm:"type:varchar(255);not null" json:"reason"`
+}
+
+// RoleAuditEntry records a role being granted or revoked
+type RoleAuditEntry struct {
+	Base
+	UserID uuid.UUID `gorm:"varchar(36);not null;index;" json:"user_id"`
+	Role   string    `gorm:"type:varchar(32);not null" json:"role"`
+	// 'grant' or 'revoke'
+	Action string `gorm:"type:varchar(8);not null" json:"action"`
+	// Admin who made the change, uuid.Nil if made on startup
+	ActorID uuid.UUID `gorm:"varchar(36)" json:"actor_id"`
+	Reason  string    `gorm:"type:varchar(255);not null" json:"reason"`
+}
+
+// KnownDevice is a device the account has logged in from, so logins from
+// new devices can be reported to the user. Only a SHA-256 hash of the IP and
+// user agent is stored.
+type KnownDevice struct {
+	Base
+	User        Account   `gorm:"foreignkey:UserID"`
+	UserID      uuid.UUID `gorm:"varchar(36);not null;unique_index:idx_user_device;" json:"user_id"`
+	Fingerprint string    `gorm:"type:varchar(64);not null;unique_index:idx_user_device" json:"-"`
+	LastSeenAt  time.Time `gorm:"not null;" json:"last_seen_at"`
+	// Code of the "this wasn't me" link in the new login email
+	ReportCode uuid.UUID `gorm:"varchar(36);not null;unique_index" json:"-"`
+}
+
+// Session is a login of an account, listed to the user so they can sign out
+// devices they no longer use. Every refresh token rotated from the login and
+// every access token issued with them belong to the same session.
+type Session struct {
+	Base
+	User   Account   `gorm:"foreignkey:UserID"`
+	UserID uuid.UUID `gorm:"varchar(36);not null;index;" json:"user_id"`
+	// Refresh token family of the login
+	FamilyID uuid.UUID `gorm:"varchar(36);not null;unique_index" json:"-"`
+	// jti of the latest access token, revoked together with the session
+	TokenID    string     `gorm:"type:varchar(36);" json:"-"`
+	IP         string     `gorm:"type:varchar(45);" json:"ip"`
+	UserAgent  string     `gorm:"type:varchar(255);" json:"user_agent"`
+	LastSeenAt time.Time  `gorm:"not null;" json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"not null;" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// SecurityEventType is the kind of activity a SecurityEvent records
+type SecurityEventType string
+
+const (
+	// LoginSucceeded is a login that passed every factor
+	LoginSucceeded SecurityEventType = "login_success"
+	// LoginFailed is a wrong password, MFA code or other credential
+	LoginFailed SecurityEventType = "login_failure"
+	// PasswordResetRequested is a password reset email being sent
+	PasswordResetRequested SecurityEventType = "password_reset_requested"
+	// PasswordResetCompleted is a password changed with a reset token
+	PasswordResetCompleted SecurityEventType = "password_reset_completed"
+	// EmailVerified is the account email being confirmed
+	EmailVerified SecurityEventType = "email_verified"
+	// MFAEnrolled is MFA being turned on
+	MFAEnrolled SecurityEventType = "mfa_enrolled"
+	// LoginReported is a login from a new device reported as not made by
+	// the owner
+	LoginReported SecurityEventType = "login_reported"
+)
+
+// SecurityEvent records security relevant activity for users to review and
+// admins to investigate. Failed logins for unknown usernames have no user.
+type SecurityEvent struct {
+	Base
+	UserID uuid.UUID         `gorm:"varchar(36);index;" json:"user_id"`
+	Type   SecurityEventType `gorm:"type:varchar(32);not null" json:"type"`
+	// Extra context, e.g. the login method
+	Detail    string `gorm:"type:varchar(64)" json:"detail"`
+	IP        string `gorm:"type:varchar(45);index" json:"ip"`
+	UserAgent string `gorm:"type:varchar(255)" json:"user_agent"`
+	RequestID string `gorm:"type:varchar(64)" json:"request_id"`
+}
+
+// ServiceClient is an internal service that gets tokens of its own with the
+// client_credentials claim. Only a SHA-256 hash of the secret is stored.
+type ServiceClient struct {
+	Base
+	Name       string `gorm:"type:varchar(32);not null;unique_index" json:"name"`
+	ClientID   string `gorm:"type:varchar(64);not null;unique_index" json:"client_id"`
+	SecretHash string `gorm:"type:varchar(64);not null" json:"-"`
+	// Comma separated, e.g. 'accounts:read,emails:send'
+	Scopes     string     `gorm:"type:varchar(255);not null" json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// WebAuthnCredential is a passkey registered to an account
+type WebAuthnCredential struct {
+	Base
+	User   Account   `gorm:"foreignkey:UserID"`
+	UserID uuid.UUID `gorm:"varchar(36);not null;index;" json:"user_id"`
+	// Base64url encoded ID chosen by the authenticator
+	CredentialID string `gorm:"type:varchar(255);not null;unique_index" json:"credential_id"`
+	// COSE encoded public key
+	PublicKey []byte `gorm:"type:blob;not null" json:"-"`
+	SignCount uint32 `gorm:"not null" json:"sign_count"`
+	// Comma separated transport hints, e.g. 'usb,nfc'
+	Transports string     `gorm:"type:varchar(128)" json:"transports"`
+	Name       string     `gorm:"type:varchar(64)" json:"name"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// WebAuthnChallenge is an outstanding challenge of a WebAuthn registration or
+// login. UserID is uuid.Nil for logins without a username.
+type WebAuthnChallenge struct {
+	Base
+	UserID    uuid.UUID `gorm:"varchar(36);index;" json:"user_id"`
+	Challenge string    `gorm:"type:varchar(64);not null;unique_index" json:"-"`
+	Ceremony  string    `gorm:"type:varchar(16);not null" json:"ceremony"`
+	ExpiresAt time.Time `gorm:"not null;" json:"expires_at"`
+}
+
+// GetMFAMethod returns the confirmed MFA method of an account. Returns nil if
+// the account has no MFA enabled.
+func (a *Account) GetMFAMethod(db *gorm.DB) (*MFAMethod, error) {
+	var method MFAMethod
+	err := db.Where("user_id = ? AND confirmed_at IS NOT NULL", a.ID).First(&method).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &method, nil
+}
+
+// SetMFAMethod confirms method and makes it the only MFA method on an account
+func (a *Account) SetMFAMethod(db *gorm.DB, method *MFAMethod) error {
+	return DoInTransaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND id <> ?", a.ID, method.ID).Delete(MFAMethod{}).Error
+		if err != nil {
+			return err
+		}
+
+		timeNow := time.Now()
+		method.UserID = a.ID
+		method.ConfirmedAt = &timeNow
+		return tx.Save(method).Error
+	}, db)
 }
 
 // VerifyEmail marks and account as verified
@@ -117,3 +345,17 @@ func (a *Account) VerifyEmail(db *gorm.DB) error {
 func (a *Account) IsEmailVerified() bool {
 	return a.EmailVerifiedAt != nil
 }
+
+// LoginAttempt is a failed login attempt. Key identifies what was attempted,
+// e.g. a username or an IP address.
+type LoginAttempt struct {
+	Base
+	Key         string    `gorm:"type:varchar(128);not null;index" json:"key"`
+	AttemptedAt time.Time `gorm:"not null;index" json:"attempted_at"`
+}
+
+// LoginLockout blocks logins for Key until LockedUntil
+type LoginLockout struct {
+	Key         string    `gorm:"type:varchar(128);primary_key" json:"key"`
+	LockedUntil time.Time `gorm:"not null" json:"locked_until"`
+}
@@ -0,0 +1,386 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	authlib "github.com/esportsdrafts/esportsdrafts/libs/authlib"
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// Time between asking for deletion and the account being anonymized
+	accountDeletionCoolingOff = 14 * 24 * time.Hour
+	// Domain of the email given to anonymized accounts, reserved so it can
+	// never be delivered
+	anonymizedEmailDomain = "deleted.invalid"
+)
+
+// Exportaccount returns everything the auth service stores about the logged
+// in user. Secrets like the password hash, codes and MFA secrets are left out.
+func (a *AuthAPI) Exportaccount(ctx echo.Context) error {
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	export, err := a.exportAccount(account)
+	if err != nil {
+		efanlog.GetLogger().Errorf("Failed to export account '%s': %s", account.Username, err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	return ctx.JSON(http.StatusOK, export)
+}
+
+// exportAccount collects the records belonging to account. Soft deleted
+// records are still stored so they are included.
+func (a *AuthAPI) exportAccount(account *db.Account) (*auth.AccountExport, error) {
+	tx := a.dbHandler.Unscoped()
+	export := &auth.AccountExport{
+		Account: auth.ExportedAccount{
+			Id:              account.ID.String(),
+			Username:        account.Username,
+			Email:           account.Email,
+			CreatedAt:       account.CreatedAt,
+			AcceptedTermsAt: account.AcceptedTermsAt,
+			EmailVerifiedAt: account.EmailVerifiedAt,
+			DeleteAfter:     account.DeleteAfter,
+		},
+	}
+
+	var verificationCodes []db.EmailVerificationCode
+	err := tx.Where("user_id = ?", account.ID).Order("created_at").Find(&verificationCodes).Error
+	if err != nil {
+		return nil, err
+	}
+	export.EmailVerificationCodes = make([]auth.ExportedCode, len(verificationCodes))
+	for i, code := range verificationCodes {
+		export.EmailVerificationCodes[i] = auth.ExportedCode{CreatedAt: code.CreatedAt, ExpiresAt: code.ExpiresAt}
+	}
+
+	var resetTokens []db.PasswordResetToken
+	err = tx.Where("user_id = ?", account.ID).Order("created_at").Find(&resetTokens).Error
+	if err != nil {
+		return nil, err
+	}
+	export.PasswordResetTokens = make([]auth.ExportedCode, len(resetTokens))
+	for i, token := range resetTokens {
+		export.PasswordResetTokens[i] = auth.ExportedCode{CreatedAt: token.CreatedAt, ExpiresAt: token.ExpiresAt}
+	}
+
+	var mfaMethods []db.MFAMethod
+	err = tx.Where("user_id = ?", account.ID).Order("created_at").Find(&mfaMethods).Error
+	if err != nil {
+		return nil, err
+	}
+	export.MfaMethods = make([]auth.ExportedMFAMethod, len(mfaMethods))
+	for i, method := range mfaMethods {
+		export.MfaMethods[i] = auth.ExportedMFAMethod{
+			Type:        string(method.Type),
+			CreatedAt:   method.CreatedAt,
+			ConfirmedAt: method.ConfirmedAt,
+		}
+	}
+
+	var mfaCodes []db.MFACode
+	err = tx.Where("user_id = ?", account.ID).Order("created_at").Find(&mfaCodes).Error
+	if err != nil {
+		return nil, err
+	}
+	export.MfaCodes = make([]auth.ExportedCode, len(mfaCodes))
+	for i, code := range mfaCodes {
+		export.MfaCodes[i] = auth.ExportedCode{CreatedAt: code.CreatedAt, ExpiresAt: code.ExpiresAt}
+	}
+
+	var refreshTokens []db.RefreshToken
+	err = tx.Where("user_id = ?", account.ID).Order("created_at").Find(&refreshTokens).Error
+	if err != nil {
+		return nil, err
+	}
+	export.RefreshTokens = make([]auth.ExportedRefreshToken, len(refreshTokens))
+	for i, token := range refreshTokens {
+		export.RefreshTokens[i] = auth.ExportedRefreshToken{
+			FamilyId:  token.FamilyID.String(),
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+			UsedAt:    token.UsedAt,
+			RevokedAt: token.RevokedAt,
+		}
+	}
+
+	var attempts []db.LoginAttempt
+	err = tx.Where("`key` = ?", userThrottleKey(strings.ToLower(account.Username))).Order("attempted_at").Find(&attempts).Error
+	if err != nil {
+		return nil, err
+	}
+	export.FailedLogins = make([]time.Time, len(attempts))
+	for i, attempt := range attempts {
+		export.FailedLogins[i] = attempt.AttemptedAt
+	}
+
+	var emailChanges []db.EmailChangeRequest
+	err = tx.Where("user_id = ?", account.ID).Order("created_at").Find(&emailChanges).Error
+	if err != nil {
+		return nil, err
+	}
+	export.EmailChanges = make([]auth.ExportedEmailChange, len(emailChanges))
+	for i, change := range emailChanges {
+		export.EmailChanges[i] = auth.ExportedEmailChange{
+			OldEmail:    change.OldEmail,
+			NewEmail:    change.NewEmail,
+			CreatedAt:   change.CreatedAt,
+			ConfirmedAt: change.ConfirmedAt,
+		}
+	}
+
+	var credentials []db.WebAuthnCredential
+	err = tx.Where("user_id = ?", account.ID).Order("created_at").Find(&credentials).Error
+	if err != nil {
+		return nil, err
+	}
+	export.Passkeys = make([]auth.WebAuthnCredential, len(credentials))
+	for i := range credentials {
+		export.Passkeys[i] = toAPIWebAuthnCredential(&credentials[i])
+	}
+
+	var recoveryCodes []db.MFARecoveryCode
+	err = tx.Where("user_id = ?", account.ID).Order("created_at").Find(&recoveryCodes).Error
+	if err != nil {
+		return nil, err
+	}
+	export.MfaRecoveryCodes = make([]auth.ExportedRecoveryCode, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		export.MfaRecoveryCodes[i] = auth.ExportedRecoveryCode{CreatedAt: code.CreatedAt, UsedAt: code.UsedAt}
+	}
+
+	var apiKeys []db.APIKey
+	err = tx.Where("user_id = ?", account.ID).Order("created_at").Find(&apiKeys).Error
+	if err != nil {
+		return nil, err
+	}
+	export.ApiKeys = make([]auth.APIKey, len(apiKeys))
+	for i := range apiKeys {
+		export.ApiKeys[i] = toAPIKey(&apiKeys[i])
+	}
+
+	var events []db.SecurityEvent
+	err = tx.Where("user_id = ?", account.ID).Order("created_at").Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	export.SecurityEvents = make([]auth.SecurityEvent, len(events))
+	for i := range events {
+		export.SecurityEvents[i] = toAPISecurityEvent(&events[i])
+	}
+
+	var devices []db.KnownDevice
+	err = tx.Where("user_id = ?", account.ID).Order("created_at").Find(&devices).Error
+	if err != nil {
+		return nil, err
+	}
+	export.KnownDevices = make([]auth.ExportedKnownDevice, len(devices))
+	for i, device := range devices {
+		export.KnownDevices[i] = auth.ExportedKnownDevice{CreatedAt: device.CreatedAt, LastSeenAt: device.LastSeenAt}
+	}
+
+	var sessions []db.Session
+	err = tx.Where("user_id = ?", account.ID).Order("created_at").Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	export.Sessions = make([]auth.Session, len(sessions))
+	for i := range sessions {
+		export.Sessions[i] = toAPISession(&sessions[i], "")
+	}
+
+	var loginLinks []db.LoginLinkToken
+	err = tx.Where("user_id = ?", account.ID).Order("created_at").Find(&loginLinks).Error
+	if err != nil {
+		return nil, err
+	}
+	export.LoginLinkTokens = make([]auth.ExportedCode, len(loginLinks))
+	for i, link := range loginLinks {
+		export.LoginLinkTokens[i] = auth.ExportedCode{CreatedAt: link.CreatedAt, ExpiresAt: link.ExpiresAt}
+	}
+
+	var assignments []db.RoleAssignment
+	err = tx.Where("user_id = ?", account.ID).Order("created_at").Find(&assignments).Error
+	if err != nil {
+		return nil, err
+	}
+	var roleChanges []db.RoleAuditEntry
+	err = tx.Where("user_id = ?", account.ID).Order("created_at").Find(&roleChanges).Error
+	if err != nil {
+		return nil, err
+	}
+	usernames, err := a.roleUsernames(assignments, roleChanges)
+	if err != nil {
+		return nil, err
+	}
+	export.RoleAssignments = make([]auth.RoleAssignment, len(assignments))
+	for i := range assignments {
+		export.RoleAssignments[i] = toAPIRoleAssignment(&assignments[i], usernames)
+	}
+	export.RoleChanges = make([]auth.RoleAuditEntry, len(roleChanges))
+	for i := range roleChanges {
+		export.RoleChanges[i] = toAPIRoleAuditEntry(&roleChanges[i], usernames)
+	}
+
+	return export, nil
+}
+
+// Requestaccountdeletion schedules the logged in user's account for deletion
+// after a cooling-off period. All sessions are logged out, the user can log
+// in again to cancel the deletion until it is carried out.
+func (a *AuthAPI) Requestaccountdeletion(ctx echo.Context) error {
+	logger := efanlog.GetLogger()
+
+	var request auth.AccountDeletion
+	err := ctx.Bind(&request)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	if rejected, err := a.rejectWrongPassword(ctx, account, request.Password); rejected {
+		return err
+	}
+
+	// Asking again does not push the deletion further away
+	deleteAfter := time.Now().Add(accountDeletionCoolingOff)
+	if account.DeleteAfter != nil {
+		deleteAfter = *account.DeleteAfter
+	}
+
+	err = db.DoInTransaction(func(tx *gorm.DB) error {
+		err := tx.Model(account).Update("delete_after", deleteAfter).Error
+		if err != nil {
+			return err
+		}
+		return revokeUserRefreshTokens(tx, account.ID)
+	}, a.dbHandler)
+	if err != nil {
+		logger.Errorf("Failed to schedule deletion of '%s': %s", account.Username, err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	if claims, ok := authlib.ClaimsFromContext(ctx); ok {
+		err = a.revocationStore.Revoke(claims.Id, time.Unix(claims.ExpiresAt, 0))
+		if err != nil {
+			logger.Errorf("Failed to revoke token: %s", err)
+		}
+	}
+	authlib.ClearAuthCookies(ctx)
+	writeRefreshTokenCookie(ctx, "", -time.Hour)
+
+	logger.Infof("Account '%s' scheduled for deletion after %s", account.Username, deleteAfter)
+	return ctx.JSON(http.StatusOK, auth.AccountDeletionStatus{DeleteAfter: deleteAfter})
+}
+
+// Cancelaccountdeletion cancels a scheduled deletion of the logged in user's
+// account
+func (a *AuthAPI) Cancelaccountdeletion(ctx echo.Context) error {
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	if account.DeleteAfter == nil {
+		return ctx.JSON(http.StatusOK, map[string]int{})
+	}
+
+	err = a.dbHandler.Model(account).Update("delete_after", gorm.Expr("NULL")).Error
+	if err != nil {
+		efanlog.GetLogger().Errorf("Failed to cancel deletion of '%s': %s", account.Username, err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]int{})
+}
+
+// AnonymizeDeletedAccounts carries out every deletion whose cooling-off period
+// has passed. Username and email are replaced so they can be registered
+// again and all other records of the account are removed. Returns the number
+// of accounts anonymized.
+func AnonymizeDeletedAccounts(dbHandler *gorm.DB) (int, error) {
+	var accounts []db.Account
+	err := dbHandler.Where("delete_after <= ?", time.Now()).Find(&accounts).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range accounts {
+		err = anonymizeAccount(dbHandler, &accounts[i])
+		if err != nil {
+			return i, fmt.Errorf("failed to anonymize account %s: %s", accounts[i].ID, err)
+		}
+	}
+	return len(accounts), nil
+}
+
+// anonymizeAccount scrubs a single account. The row itself is kept, soft
+// deleted, so references from other services stay valid.
+func anonymizeAccount(dbHandler *gorm.DB, account *db.Account) error {
+	return db.DoInTransaction(func(tx *gorm.DB) error {
+		userID := account.ID
+		for _, model := range []interface{}{
+			db.EmailVerificationCode{},
+			db.PasswordResetToken{},
+			db.MFACode{},
+			db.MFAMethod{},
+			db.RefreshToken{},
+			db.EmailChangeRequest{},
+			db.LoginLinkToken{},
+			db.WebAuthnCredential{},
+			db.WebAuthnChallenge{},
+			db.MFARecoveryCode{},
+			db.APIKey{},
+			db.RoleAssignment{},
+			db.SecurityEvent{},
+			db.KnownDevice{},
+			db.Session{},
+		} {
+			err := tx.Unscoped().Where("user_id = ?", userID).Delete(model).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		throttleKey := userThrottleKey(strings.ToLower(account.Username))
+		err := tx.Unscoped().Where("`key` = ?", throttleKey).Delete(db.LoginAttempt{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("`key` = ?", throttleKey).Delete(db.LoginLockout{}).Error
+		if err != nil {
+			return err
+		}
+
+		// Placeholders are unique since they are derived from the ID. The
+		// password hash can not match any password.
+		err = tx.Model(account).Updates(map[string]interface{}{
+			"username":          "deleted-" + userID.String(),
+			"email":             fmt.Sprintf("%s@%s", userID, anonymizedEmailDomain),
+			"password_hash":     "",
+			"accepted_terms_at": gorm.Expr("NULL"),
+			"email_verified_at": gorm.Expr("NULL"),
+			"delete_after":      gorm.Expr("NULL"),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(account).Error
+	}, dbHandler)
+}
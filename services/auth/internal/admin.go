@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/labstack/echo/v4"
+)
+
+// UnlockAccount lifts a lockout after too many failed login attempts. Only
+// available to admins.
+func (a *AuthAPI) UnlockAccount(ctx echo.Context) error {
+	var unlock auth.AccountUnlock
+	err := ctx.Bind(&unlock)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	username := strings.ToLower(unlock.Username)
+
+	var account db.Account
+	err = a.dbHandler.Where("username = ?", username).First(&account).Error
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusNotFound, "Account not found")
+	}
+
+	err = a.throttler.Unlock(username)
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	efanlog.GetLogger().Infof("Account '%s' unlocked by admin", username)
+	return ctx.JSON(http.StatusOK, map[string]int{})
+}
+
+// GetPasswordHashReport counts accounts per password hashing parameter set so
+// we know how many still wait for an upgrade on their next login
+func (a *AuthAPI) GetPasswordHashReport(ctx echo.Context) error {
+	policy := GetDefaultHashingParams()
+	report := newHashParamsReport(policy)
+
+	rows, err := a.dbHandler.Model(&db.Account{}).Select("password_hash").Rows()
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var encodedHash string
+		err = rows.Scan(&encodedHash)
+		if err != nil {
+			efanlog.GetLogger().Info(err)
+			return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+		}
+		report.add(encodedHash)
+	}
+
+	return ctx.JSON(http.StatusOK, auth.PasswordHashReport{
+		Current:       policy.String(),
+		ParameterSets: report.counts(),
+	})
+}
+
+// hashParamsReport counts encoded hashes per parameter set
+type hashParamsReport struct {
+	policy *Params
+	sets   map[string]*auth.HashParamsCount
+}
+
+func newHashParamsReport(policy *Params) *hashParamsReport {
+	return &hashParamsReport{
+		policy: policy,
+		sets:   map[string]*auth.HashParamsCount{},
+	}
+}
+
+func (r *hashParamsReport) add(encodedHash string) {
+	count := auth.HashParamsCount{Algorithm: "unknown", Outdated: true}
+
+	hasher, err := findPasswordHasher(encodedHash)
+	if err == nil {
+		params, err := hasher.Params(encodedHash)
+		if err == nil {
+			count.Algorithm = hasher.Algorithm()
+			count.Params = params
+		}
+	}
+
+	if count.Algorithm == "argon2id" {
+		count.Outdated, _ = NeedsRehash(encodedHash, r.policy)
+	}
+
+	key := count.Algorithm + "$" + count.Params
+	if _, ok := r.sets[key]; !ok {
+		r.sets[key] = &count
+	}
+	r.sets[key].Accounts++
+}
+
+// counts returns the parameter sets, most used first
+func (r *hashParamsReport) counts() []auth.HashParamsCount {
+	counts := []auth.HashParamsCount{}
+	for _, count := range r.sets {
+		counts = append(counts, *count)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Accounts != counts[j].Accounts {
+			return counts[i].Accounts > counts[j].Accounts
+		}
+		return counts[i].Params < counts[j].Params
+	})
+	return counts
+}
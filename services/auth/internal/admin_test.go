@@ -0,0 +1,30 @@
+package internal
+
+import "testing"
+
+func TestHashParamsReport(t *testing.T) {
+	policy := GetDefaultHashingParams()
+	weak := &Params{memory: 32 * 1024, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 32}
+
+	report := newHashParamsReport(policy)
+	for _, p := range []*Params{policy, policy, weak} {
+		hash, _ := GenerateFromPassword("bogus_password", p)
+		report.add(hash)
+	}
+	report.add("not a hash")
+
+	counts := report.counts()
+	if len(counts) != 3 {
+		t.Fatalf("Expected 3 parameter sets, got %d", len(counts))
+	}
+
+	// Most common first
+	if counts[0].Accounts != 2 || counts[0].Outdated {
+		t.Errorf("Current parameters counted wrong: %+v", counts[0])
+	}
+	for _, c := range counts[1:] {
+		if c.Accounts != 1 || !c.Outdated {
+			t.Errorf("Outdated parameters counted wrong: %+v", c)
+		}
+	}
+}
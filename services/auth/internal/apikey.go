@@ -0,0 +1,313 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	authlib "github.com/esportsdrafts/esportsdrafts/libs/authlib"
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// Keys look like 'edk_<prefix>_<secret>'. The prefix finds the stored
+	// key, the secret is 256 bits of randomness.
+	apiKeyPrefixBytes = 6
+	apiKeySecretBytes = 32
+	apiKeySeparator   = "_"
+
+	defaultAPIKeyLifetime = 90 * 24 * time.Hour
+	maxAPIKeyLifetime     = 365 * 24 * time.Hour
+	maxAPIKeys            = 20
+	maxAPIKeyNameLength   = 64
+	// Only write the last used time this often, keys may be used for every
+	// request of a bot
+	apiKeyLastUsedResolution = time.Minute
+	// API keys act on behalf of a user, never with elevated roles
+	apiKeyRole = "user"
+)
+
+// Scopes that can be granted to personal API keys
+var /* const */ apiKeyScopes = map[string]bool{
+	"profile:read":  true,
+	"lineups:read":  true,
+	"lineups:write": true,
+	"contests:read": true,
+}
+
+// generateAPIKey returns a new key and its lookup prefix
+func generateAPIKey() (string, string, error) {
+	prefixBytes, err := generateRandomBytes(apiKeyPrefixBytes)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := generateRandomBytes(apiKeySecretBytes)
+	if err != nil {
+		return "", "", err
+	}
+
+	prefix := hex.EncodeToString(prefixBytes)
+	key := authlib.APIKeyPrefix + prefix + apiKeySeparator + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, nil
+}
+
+// parseAPIKeyPrefix returns the lookup prefix of a key
+func parseAPIKeyPrefix(key string) (string, bool) {
+	if !authlib.IsAPIKey(key) {
+		return "", false
+	}
+
+	rest := strings.TrimPrefix(key, authlib.APIKeyPrefix)
+	prefixLength := hex.EncodedLen(apiKeyPrefixBytes)
+	if len(rest) <= prefixLength+len(apiKeySeparator) || rest[prefixLength:prefixLength+len(apiKeySeparator)] != apiKeySeparator {
+		return "", false
+	}
+	return rest[:prefixLength], true
+}
+
+// hashAPIKey hashes a key for storage. Keys are long and random so a fast
+// hash is enough, unlike passwords.
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// splitAPIKeyScopes turns stored scopes into a list
+func splitAPIKeyScopes(scopes string) []string {
+	if scopes == "" {
+		return []string{}
+	}
+	return strings.Split(scopes, ",")
+}
+
+func toAPIKey(key *db.APIKey) auth.APIKey {
+	return auth.APIKey{
+		Id:         key.ID.String(),
+		Name:       key.Name,
+		Prefix:     authlib.APIKeyPrefix + key.Prefix,
+		Scopes:     splitAPIKeyScopes(key.Scopes),
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.DeletedAt,
+	}
+}
+
+// ValidateAPIKey implements authlib.APIKeyValidator. Returns claims for the
+// owner of the key limited to the scopes of the key, or nil if the key is
+// not valid.
+func (a *AuthAPI) ValidateAPIKey(key string) (*authlib.JWTClaims, error) {
+	prefix, ok := parseAPIKeyPrefix(key)
+	if !ok {
+		return nil, nil
+	}
+
+	var apiKey db.APIKey
+	err := a.dbHandler.Preload("User").Where("prefix = ?", prefix).First(&apiKey).Error
+	if err != nil {
+		return nil, nil
+	}
+
+	hash := hashAPIKey(key)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(apiKey.KeyHash)) != 1 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	if apiKey.ExpiresAt.Before(now) {
+		return nil, nil
+	}
+
+	// Keys stop working as soon as the owner asks for deletion, the same as
+	// sessions do
+	account := apiKey.User
+	if account.ID != apiKey.UserID || account.DeleteAfter != nil {
+		return nil, nil
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyLastUsedResolution {
+		err = a.dbHandler.Model(&apiKey).UpdateColumn("last_used_at", now).Error
+		if err != nil {
+			efanlog.GetLogger().Errorf("Failed to update last use of API key %s: %s", apiKey.ID, err)
+		}
+	}
+
+	claims := &authlib.JWTClaims{
+		Username: account.Username,
+		UserID:   account.ID.String(),
+		Roles:    []string{apiKeyRole},
+		Scopes:   splitAPIKeyScopes(apiKey.Scopes),
+	}
+	claims.Id = apiKey.ID.String()
+	claims.ExpiresAt = apiKey.ExpiresAt.Unix()
+	return claims, nil
+}
+
+// Listapikeys lists the active API keys of the logged in user
+func (a *AuthAPI) Listapikeys(ctx echo.Context) error {
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	var keys []db.APIKey
+	err = a.dbHandler.Where("user_id = ? AND expires_at > ?", account.ID, time.Now()).
+		Order("created_at").Find(&keys).Error
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	result := make([]auth.APIKey, len(keys))
+	for i := range keys {
+		result[i] = toAPIKey(&keys[i])
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// Createapikey creates a personal API key for the logged in user. The key
+// is in the response and can not be retrieved again.
+func (a *AuthAPI) Createapikey(ctx echo.Context) error {
+	var request auth.NewAPIKey
+	err := ctx.Bind(&request)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		return sendAuthAPIError(ctx, http.StatusBadRequest,
+			fmt.Sprintf("Name must be between 1 and %d characters", maxAPIKeyNameLength))
+	}
+
+	if len(request.Scopes) == 0 {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "At least one scope is required")
+	}
+	scopes := map[string]bool{}
+	for _, scope := range request.Scopes {
+		if !apiKeyScopes[scope] {
+			return sendAuthAPIError(ctx, http.StatusBadRequest, fmt.Sprintf("Unknown scope '%s'", scope))
+		}
+		scopes[scope] = true
+	}
+	sortedScopes := make([]string, 0, len(scopes))
+	for scope := range scopes {
+		sortedScopes = append(sortedScopes, scope)
+	}
+	sort.Strings(sortedScopes)
+
+	now := time.Now()
+	expiresAt := now.Add(defaultAPIKeyLifetime)
+	if request.ExpiresAt != nil {
+		expiresAt = *request.ExpiresAt
+	}
+	if !expiresAt.After(now) || expiresAt.After(now.Add(maxAPIKeyLifetime)) {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Expiry must be in the future and at most a year away")
+	}
+
+	var count int
+	err = a.dbHandler.Model(&db.APIKey{}).Where("user_id = ? AND expires_at > ?", account.ID, now).Count(&count).Error
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	if count >= maxAPIKeys {
+		return sendAuthAPIError(ctx, http.StatusBadRequest,
+			fmt.Sprintf("At most %d API keys can be active", maxAPIKeys))
+	}
+
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	apiKey := &db.APIKey{
+		UserID:    account.ID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hashAPIKey(key),
+		Scopes:    strings.Join(sortedScopes, ","),
+		ExpiresAt: expiresAt,
+	}
+	err = a.dbHandler.Save(apiKey).Error
+	if err != nil {
+		efanlog.GetLogger().Errorf("Failed to store API key for '%s': %s", account.Username, err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	efanlog.GetLogger().Infof("Created API key %s for '%s'", apiKey.ID, account.Username)
+	return ctx.JSON(http.StatusOK, auth.CreatedAPIKey{
+		Key:    key,
+		ApiKey: toAPIKey(apiKey),
+	})
+}
+
+// revokeUserAPIKeys revokes every API key of a user. Used when the account
+// may have been taken over, keys created by an attacker must not outlive the
+// response to it.
+func revokeUserAPIKeys(dbHandler *gorm.DB, userID uuid.UUID) error {
+	return dbHandler.Where("user_id = ?", userID).Delete(db.APIKey{}).Error
+}
+
+// PruneAPIKeys removes revoked and expired API keys. Returns the number of
+// keys removed.
+func PruneAPIKeys(dbHandler *gorm.DB) (int64, error) {
+	res := dbHandler.Unscoped().Where("deleted_at IS NOT NULL OR expires_at < ?", time.Now()).Delete(db.APIKey{})
+	return res.RowsAffected, res.Error
+}
+
+// Deleteapikey revokes an API key of the logged in user
+func (a *AuthAPI) Deleteapikey(ctx echo.Context, id string) error {
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	res := a.dbHandler.Where("id = ? AND user_id = ?", id, account.ID).Delete(db.APIKey{})
+	if res.Error != nil {
+		efanlog.GetLogger().Info(res.Error)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	if res.RowsAffected == 0 {
+		return sendAuthAPIError(ctx, http.StatusNotFound, "API key not found")
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]int{})
+}
+
+// Getidentity returns who the token or API key of the request belongs to and
+// what it grants. Lets scripts check their key.
+func (a *AuthAPI) Getidentity(ctx echo.Context) error {
+	claims, ok := authlib.ClaimsFromContext(ctx)
+	if !ok {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	scopes := claims.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return ctx.JSON(http.StatusOK, auth.Identity{
+		UserId:   claims.UserID,
+		Username: claims.Username,
+		Roles:    claims.Roles,
+		Scopes:   scopes,
+	})
+}
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	authlib "github.com/esportsdrafts/esportsdrafts/libs/authlib"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !authlib.IsAPIKey(key) {
+		t.Errorf("Key '%s' not recognized as API key", key)
+	}
+
+	parsed, ok := parseAPIKeyPrefix(key)
+	if !ok || parsed != prefix {
+		t.Errorf("Parsed prefix '%s' of '%s', wanted '%s'", parsed, key, prefix)
+	}
+
+	other, otherPrefix, _ := generateAPIKey()
+	if other == key || otherPrefix == prefix {
+		t.Errorf("Keys should be random, got '%s' twice", key)
+	}
+
+	if hashAPIKey(key) == hashAPIKey(other) || hashAPIKey(key) != hashAPIKey(key) {
+		t.Errorf("Hash should be deterministic and differ between keys")
+	}
+}
+
+func TestParseAPIKeyPrefix(t *testing.T) {
+	invalid := []string{
+		"",
+		"eyJhbGciOiJIUzI1NiJ9.e30.sig",
+		authlib.APIKeyPrefix,
+		authlib.APIKeyPrefix + "0123456789ab",
+		authlib.APIKeyPrefix + "0123456789ab_",
+		authlib.APIKeyPrefix + "0123456789a_secret",
+		authlib.APIKeyPrefix + "0123456789abXsecret",
+	}
+	for _, key := range invalid {
+		if prefix, ok := parseAPIKeyPrefix(key); ok {
+			t.Errorf("Key '%s' should not parse, got prefix '%s'", key, prefix)
+		}
+	}
+
+	prefix, ok := parseAPIKeyPrefix(authlib.APIKeyPrefix + "0123456789ab_s_e-cret")
+	if !ok || prefix != "0123456789ab" {
+		t.Errorf("Valid key did not parse, got '%s'", prefix)
+	}
+
+	if scopes := splitAPIKeyScopes(""); scopes == nil || len(scopes) != 0 {
+		t.Errorf("No scopes should give an empty list, got %v", scopes)
+	}
+	if scopes := strings.Join(splitAPIKeyScopes("a,b"), " "); scopes != "a b" {
+		t.Errorf("Wrong scopes '%s'", scopes)
+	}
+}
@@ -21,6 +21,8 @@ const (
 	minUsernameLength   = 5
 	maxPasswordLength   = 128
 	minPasswordLength   = 12
+
+	breachedPasswordMessage = "This password has appeared in a data breach and can not be used, please choose another one"
 )
 
 // AuthAPI holds global handlers for the API like Databases.
@@ -28,11 +30,14 @@ type AuthAPI struct {
 	dbHandler        *gorm.DB
 	beanstalkHandler *beanstalkd_models.Client
 	inputValidator   InputValidator
-	jwtKey           []byte
+	keys             *authlib.KeySet
+	revocationStore  authlib.RevocationStore
+	throttler        *Throttler
+	relyingParty     RelyingParty
 }
 
 // NewAuthAPI constructs an API client
-func NewAuthAPI(dbHandler *gorm.DB, bClient *beanstalkd_models.Client, jwtKey []byte) *AuthAPI {
+func NewAuthAPI(dbHandler *gorm.DB, bClient *beanstalkd_models.Client, keys *authlib.KeySet, revocationStore authlib.RevocationStore, attemptStore AttemptStore, breachedPasswords *BreachedPasswordCorpus, relyingParty RelyingParty) *AuthAPI {
 	return &AuthAPI{
 		dbHandler:        dbHandler,
 		beanstalkHandler: bClient,
@@ -41,8 +46,12 @@ func NewAuthAPI(dbHandler *gorm.DB, bClient *beanstalkd_models.Client, jwtKey []
 			minUsernameLength: minUsernameLength,
 			maxPasswordLength: maxPasswordLength,
 			minPasswordLength: minPasswordLength,
+			breachedPasswords: breachedPasswords,
 		},
-		jwtKey: jwtKey,
+		keys:            keys,
+		revocationStore: revocationStore,
+		throttler:       NewThrottler(attemptStore),
+		relyingParty:    relyingParty,
 	}
 }
 
@@ -55,6 +64,35 @@ func sendAuthAPIError(ctx echo.Context, code int, message string) error {
 	return err
 }
 
+// toAPIPasswordStrength converts an estimate to the API format
+func toAPIPasswordStrength(strength PasswordStrength) *auth.PasswordStrength {
+	suggestions := strength.Suggestions
+	if suggestions == nil {
+		suggestions = []string{}
+	}
+	return &auth.PasswordStrength{
+		Score:       strength.Score,
+		Accepted:    strength.Score >= minPasswordScore,
+		Warning:     strength.Warning,
+		Suggestions: suggestions,
+	}
+}
+
+// rejectWeakPassword writes a 400 response with feedback if password is too
+// easy to guess. Returns true if a response was written.
+func (a *AuthAPI) rejectWeakPassword(ctx echo.Context, password string, userInputs ...string) (bool, error) {
+	strength := a.inputValidator.PasswordStrength(password, userInputs...)
+	if strength.Score >= minPasswordScore {
+		return false, nil
+	}
+
+	return true, ctx.JSON(http.StatusBadRequest, auth.Error{
+		Code:             http.StatusBadRequest,
+		Message:          "Password is too easy to guess",
+		PasswordFeedback: toAPIPasswordStrength(strength),
+	})
+}
+
 // PerformAuth performs an authentication request the auth path is based on
 // what claim the caller makes.
 func (a *AuthAPI) PerformAuth(ctx echo.Context) error {
@@ -64,71 +102,191 @@ func (a *AuthAPI) PerformAuth(ctx echo.Context) error {
 	if err != nil {
 		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
 	}
-	logger := efanlog.GetLogger()
 
 	switch newAuthClaim.Claim {
 	case "username+password":
-		var account db.Account
-		alwaysFail := false
+		return a.authWithPassword(ctx, &newAuthClaim)
+	case "mfa":
+		return a.authWithMFA(ctx, &newAuthClaim)
+	case "mfa_code":
+		return a.authWithMFACode(ctx, &newAuthClaim)
+	case "mfa_recovery":
+		return a.authWithRecoveryCode(ctx, &newAuthClaim)
+	case "refresh_token":
+		return a.authWithRefreshToken(ctx, &newAuthClaim)
+	case "email_link":
+		return a.authWithEmailLink(ctx, &newAuthClaim)
+	case "webauthn":
+		return a.authWithWebAuthn(ctx, &newAuthClaim)
+	case "client_credentials":
+		return a.authWithClientCredentials(ctx, &newAuthClaim)
+	default:
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid authentication claim")
+	}
+}
 
-		err := a.dbHandler.Where("username = ?", newAuthClaim.Username).First(&account).Error
-		// Verify username and password
-		if err != nil {
-			account = db.NullAccount
-			alwaysFail = true
-		}
+// authWithPassword handles the 'username+password' claim
+func (a *AuthAPI) authWithPassword(ctx echo.Context, claim *auth.AuthClaim) error {
+	logger := efanlog.GetLogger()
 
-		match, err := ComparePasswordAndHash(*newAuthClaim.Password, account.Password)
-		if err != nil {
-			logger.Info("Error hashing and comparing")
-			return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
-		}
+	if claim.Username == nil || claim.Password == nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Username and password required")
+	}
 
-		if !match || alwaysFail {
-			logger.Info("Username and password did not match")
-			return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid username or password")
-		}
+	// Checked before touching the password so throttled guesses cost nothing
+	if rejected, err := a.rejectThrottled(ctx, *claim.Username); rejected {
+		return err
+	}
+
+	var account db.Account
+	alwaysFail := false
+
+	err := a.dbHandler.Where("username = ?", *claim.Username).First(&account).Error
+	// Verify username and password
+	if err != nil {
+		account = db.NullAccount
+		alwaysFail = true
+	}
 
-		var roles []string
-		if !account.IsEmailVerified() {
-			roles = append(roles, "email_verify")
+	match, err := ComparePasswordAndHash(*claim.Password, account.Password)
+	if err != nil {
+		logger.Info("Error hashing and comparing")
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	if !match || alwaysFail {
+		logger.Info("Username and password did not match")
+		if alwaysFail {
+			a.recordAuthFailure(ctx, *claim.Username, nil, "username+password")
 		} else {
-			roles = append(roles, "user")
+			a.recordAuthFailure(ctx, *claim.Username, &account, "username+password")
 		}
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid username or password")
+	}
 
-		// Create the JWT claims, which includes the username and expiry time
-		claims := &authlib.JWTClaims{
-			Username: account.Username,
-			UserID:   account.ID.String(),
-			// Would be something more useful depending on the user type
-			Roles: roles,
-		}
+	a.upgradePasswordHash(&account, *claim.Password)
 
-		tokenString, expirationTime, err := authlib.GenerateAuthToken(claims, authlib.DefaultCookiePayloadTimeout, a.jwtKey)
+	return a.completeFirstFactor(ctx, &account, "username+password")
+}
+
+// upgradePasswordHash re-hashes the password with the current default
+// parameters if the stored hash was made with weaker ones. Failures are only
+// logged, the old hash keeps working and the next login tries again.
+func (a *AuthAPI) upgradePasswordHash(account *db.Account, password string) {
+	logger := efanlog.GetLogger()
+	policy := GetDefaultHashingParams()
+
+	needsRehash, err := NeedsRehash(account.Password, policy)
+	if err != nil || !needsRehash {
+		return
+	}
+
+	hash, err := GenerateFromPassword(password, policy)
+	if err != nil {
+		logger.Errorf("Failed to rehash password for '%s': %s", account.Username, err)
+		return
+	}
+
+	// Only replace the hash we just verified, the password may have been
+	// changed in the meantime
+	err = a.dbHandler.Model(&db.Account{}).
+		Where("id = ? AND password_hash = ?", account.ID, account.Password).
+		Update("password_hash", hash).Error
+	if err != nil {
+		logger.Errorf("Failed to store rehashed password for '%s': %s", account.Username, err)
+		return
+	}
+
+	logger.Infof("Upgraded password hash parameters for '%s'", account.Username)
+	account.Password = hash
+}
+
+// completeFirstFactor is called once the user has proven who they are with
+// the first factor. Issues a MFA challenge if the account has MFA enabled,
+// otherwise the auth token is handed out directly. method is the claim used,
+// recorded with the login.
+func (a *AuthAPI) completeFirstFactor(ctx echo.Context, account *db.Account, method string) error {
+	mfaMethod, err := account.GetMFAMethod(a.dbHandler)
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	if mfaMethod != nil {
+		return a.sendMFAChallenge(ctx, account, mfaMethod)
+	}
+	return a.sendAuthToken(ctx, account, method)
+}
+
+// sendAuthToken starts a new session for the account, handing out an access
+// token and a refresh token from a new token family.
+func (a *AuthAPI) sendAuthToken(ctx echo.Context, account *db.Account, method string) error {
+	// Only reset once all factors passed, otherwise knowing the password
+	// would allow unlimited MFA guesses
+	err := a.throttler.RecordSuccess(account.Username)
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+	}
+
+	session, refreshToken, err := a.startSession(ctx, account)
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	a.recordSecurityEvent(ctx, account.ID, db.LoginSucceeded, method)
+	a.checkLoginDevice(ctx, account)
+	return a.writeAuthTokens(ctx, account, session, refreshToken)
+}
+
+// writeAuthTokens generates a JWT for the account tied to session. Browsers
+// get the tokens in cookies, other clients in the response body.
+func (a *AuthAPI) writeAuthTokens(ctx echo.Context, account *db.Account, session *db.Session, refreshToken string) error {
+	roles, err := a.accountRoles(account)
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	// Create the JWT claims, which includes the username and expiry time
+	claims := &authlib.JWTClaims{
+		Username:  account.Username,
+		UserID:    account.ID.String(),
+		Roles:     roles,
+		SessionID: session.ID.String(),
+	}
+
+	tokenString, expirationTime, err := authlib.GenerateAuthTokenWithKeys(claims, authlib.DefaultCookiePayloadTimeout, a.keys)
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		// If there is an error in creating the JWT return an internal server error
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	// Lets revoking the session revoke the token right away
+	err = a.dbHandler.Model(session).UpdateColumn("token_id", claims.Id).Error
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	// Web client so set cookies
+	if authlib.HasRequestedWithHeader(ctx) {
+		err = authlib.SetAuthCookies(ctx, tokenString)
 		if err != nil {
-			logger.Info(err)
-			// If there is an error in creating the JWT return an internal server error
 			return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
 		}
+		writeRefreshTokenCookie(ctx, refreshToken, refreshTokenTimeout)
+		return ctx.JSON(http.StatusOK, map[string]int{})
+	}
 
-		// Web client so set cookies
-		if authlib.HasRequestedWithHeader(ctx) {
-			err = authlib.SetAuthCookies(ctx, tokenString)
-			if err != nil {
-				return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
-			}
-			return ctx.JSON(http.StatusOK, map[string]int{})
-		}
-
-		result := auth.JWT{}
-		result.AccessToken = tokenString
-		result.ExpiresIn = int(expirationTime.Unix())
+	result := auth.JWT{}
+	result.AccessToken = tokenString
+	result.ExpiresIn = int(expirationTime.Unix())
+	result.RefreshToken = &refreshToken
 
-		// Otherwise just give token
-		return ctx.JSON(http.StatusOK, result)
-	default:
-		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid authentication claim")
-	}
+	// Otherwise just give token
+	return ctx.JSON(http.StatusOK, result)
 }
 
 // CreateAccount creates a new Account
@@ -155,6 +313,14 @@ func (a *AuthAPI) CreateAccount(ctx echo.Context) error {
 			"Password has to be between 12 and 127 characters inclusive")
 	}
 
+	if a.inputValidator.IsBreachedPassword(newPassword) {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, breachedPasswordMessage)
+	}
+
+	if rejected, err := a.rejectWeakPassword(ctx, newPassword, newUsername, newEmail); rejected {
+		return err
+	}
+
 	if !a.inputValidator.ValidateEmail(newEmail) {
 		return sendAuthAPIError(ctx, http.StatusBadRequest,
 			"Invalid email format")
@@ -200,7 +366,7 @@ func (a *AuthAPI) CreateAccount(ctx echo.Context) error {
 			return fmt.Errorf("%s", defaultErrorMessage)
 		}
 
-		expirationTime := time.Now().Add(48 * time.Hour)
+		expirationTime := time.Now().Add(emailVerificationTimeout)
 		verifyCode := &db.EmailVerificationCode{
 			UserID:    dbAccount.ID,
 			ExpiresAt: expirationTime,
@@ -260,6 +426,8 @@ func (a *AuthAPI) Verify(ctx echo.Context) error {
 		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
 	}
 
+	a.recordSecurityEvent(ctx, account.ID, db.EmailVerified, "")
+
 	return ctx.JSON(http.StatusOK, map[string]int{})
 }
 
@@ -279,6 +447,27 @@ func (a *AuthAPI) Check(ctx echo.Context, params auth.CheckParams) error {
 	return ctx.JSON(http.StatusUnauthorized, map[string]int{})
 }
 
+// CheckPassword estimates the strength of a password so registration forms
+// can show a live meter. Uses the same rules as account creation.
+func (a *AuthAPI) CheckPassword(ctx echo.Context) error {
+	var check auth.PasswordCheck
+	err := ctx.Bind(&check)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	var userInputs []string
+	if check.Username != nil {
+		userInputs = append(userInputs, *check.Username)
+	}
+	if check.Email != nil {
+		userInputs = append(userInputs, *check.Email)
+	}
+
+	strength := a.inputValidator.PasswordStrength(check.Password, userInputs...)
+	return ctx.JSON(http.StatusOK, toAPIPasswordStrength(strength))
+}
+
 // Passwordresetrequest initiates a password reset
 func (a *AuthAPI) Passwordresetrequest(ctx echo.Context) error {
 	var request auth.PasswordResetRequest
@@ -308,6 +497,7 @@ func (a *AuthAPI) Passwordresetrequest(ctx echo.Context) error {
 		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
 	}
 
+	a.recordSecurityEvent(ctx, account.ID, db.PasswordResetRequested, "")
 	go SchedulePasswordResetEmail(a.beanstalkHandler, account.Username, account.Email, verifyCode.ID.String())
 
 	return ctx.JSON(http.StatusOK, map[string]int{})
@@ -329,6 +519,10 @@ func (a *AuthAPI) Passwordresetverify(ctx echo.Context) error {
 			"Password has to be between 12 and 127 characters inclusive")
 	}
 
+	if a.inputValidator.IsBreachedPassword(request.Password) {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, breachedPasswordMessage)
+	}
+
 	var account db.Account
 	// TODO: add email as well?
 	err = a.dbHandler.Where("username = ?", request.Username).First(&account).Error
@@ -336,6 +530,10 @@ func (a *AuthAPI) Passwordresetverify(ctx echo.Context) error {
 		return sendAuthAPIError(ctx, http.StatusBadRequest, fmt.Sprint("Username not found"))
 	}
 
+	if rejected, err := a.rejectWeakPassword(ctx, request.Password, account.Username, account.Email); rejected {
+		return err
+	}
+
 	var token db.PasswordResetToken
 	err = a.dbHandler.Where("id = ? AND user_id = ?", request.Token, account.ID).First(&token).Error
 	if err != nil {
@@ -360,5 +558,13 @@ func (a *AuthAPI) Passwordresetverify(ctx echo.Context) error {
 		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
 	}
 
+	// Whoever knows the old password can not use it any more, no need to
+	// keep the account locked
+	err = a.throttler.Unlock(account.Username)
+	if err != nil {
+		efanlog.GetLogger().Errorf("Failed to unlock '%s' after password reset: %s", account.Username, err)
+	}
+
+	a.recordSecurityEvent(ctx, account.ID, db.PasswordResetCompleted, "")
 	return ctx.JSON(http.StatusOK, map[string]int{})
 }
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// Long enough for a SHA-1 hex hash, a count and CRLF
+	maxBreachedLineLength = 128
+	sha1HexLength         = 2 * sha1.Size
+)
+
+// BreachedPasswordCorpus looks up passwords in a local copy of the Have I Been
+// Pwned password list, the version ordered by hash. Every line is an
+// uppercase SHA-1 hex hash optionally followed by ':<count>'. The file is
+// never loaded into memory, lookups binary search it on disk.
+type BreachedPasswordCorpus struct {
+	file *os.File
+	size int64
+}
+
+// OpenBreachedPasswordCorpus opens a sorted hash file for lookups
+func OpenBreachedPasswordCorpus(path string) (*BreachedPasswordCorpus, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &BreachedPasswordCorpus{file: file, size: info.Size()}, nil
+}
+
+// Close closes the underlying file
+func (c *BreachedPasswordCorpus) Close() error {
+	return c.file.Close()
+}
+
+// Contains returns true if password is in the corpus
+func (c *BreachedPasswordCorpus) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	target := []byte(strings.ToUpper(hex.EncodeToString(sum[:])))
+
+	// Binary search over byte offsets, every probe is widened to the line
+	// it falls on
+	lo, hi := int64(0), c.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, end, line, err := c.lineAt(mid)
+		if err != nil {
+			return false, err
+		}
+
+		if len(line) < sha1HexLength {
+			return false, fmt.Errorf("invalid line at offset %d in breached password file", start)
+		}
+
+		switch bytes.Compare(bytes.ToUpper(line[:sha1HexLength]), target) {
+		case 0:
+			return true, nil
+		case -1:
+			lo = end
+		default:
+			hi = start
+		}
+	}
+	return false, nil
+}
+
+// lineAt returns the line containing offset. End is the offset of the next
+// line and line has no trailing newline.
+func (c *BreachedPasswordCorpus) lineAt(offset int64) (start int64, end int64, line []byte, err error) {
+	// Read backwards to the start of the line
+	bufStart := offset - maxBreachedLineLength
+	if bufStart < 0 {
+		bufStart = 0
+	}
+	buf := make([]byte, 2*maxBreachedLineLength)
+	n, err := c.file.ReadAt(buf, bufStart)
+	if err != nil && err != io.EOF {
+		return 0, 0, nil, err
+	}
+	buf = buf[:n]
+
+	rel := int(offset - bufStart)
+	lineStart := bytes.LastIndexByte(buf[:rel], '\n') + 1
+	if lineStart == 0 && bufStart != 0 {
+		return 0, 0, nil, fmt.Errorf("line too long at offset %d in breached password file", offset)
+	}
+
+	lineEnd := bytes.IndexByte(buf[lineStart:], '\n')
+	if lineEnd == -1 {
+		if bufStart+int64(n) < c.size {
+			return 0, 0, nil, fmt.Errorf("line too long at offset %d in breached password file", offset)
+		}
+		lineEnd = n - lineStart
+		end = c.size
+	} else {
+		end = bufStart + int64(lineStart+lineEnd) + 1
+	}
+
+	line = bytes.TrimRight(buf[lineStart:lineStart+lineEnd], "\r")
+	return bufStart + int64(lineStart), end, line, nil
+}
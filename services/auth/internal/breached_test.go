@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeBreachedFile(t *testing.T, passwords []string, lineEnding string) string {
+	var lines []string
+	for i, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), i+1))
+	}
+	sort.Strings(lines)
+
+	file, err := ioutil.TempFile("", "breached")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %+v", err)
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(strings.Join(lines, lineEnding) + lineEnding)
+	if err != nil {
+		t.Fatalf("Failed to write temp file: %+v", err)
+	}
+	return file.Name()
+}
+
+func TestBreachedPasswordCorpus(t *testing.T) {
+	var breached []string
+	for i := 0; i < 1000; i++ {
+		breached = append(breached, fmt.Sprintf("password%d", i))
+	}
+
+	for _, lineEnding := range []string{"\n", "\r\n"} {
+		path := writeBreachedFile(t, breached, lineEnding)
+		defer os.Remove(path)
+
+		corpus, err := OpenBreachedPasswordCorpus(path)
+		if err != nil {
+			t.Fatalf("Failed to open corpus: %+v", err)
+		}
+		defer corpus.Close()
+
+		for _, password := range breached {
+			found, err := corpus.Contains(password)
+			if err != nil || !found {
+				t.Errorf("Breached password '%s' not found: %+v", password, err)
+			}
+		}
+
+		for _, password := range []string{"password1000", "veryStr0ngP4ssw0rd", ""} {
+			found, err := corpus.Contains(password)
+			if err != nil || found {
+				t.Errorf("Password '%s' should not be found: %+v", password, err)
+			}
+		}
+	}
+}
+
+func TestBreachedPasswordValidator(t *testing.T) {
+	path := writeBreachedFile(t, []string{"password1234"}, "\n")
+	defer os.Remove(path)
+
+	corpus, err := OpenBreachedPasswordCorpus(path)
+	if err != nil {
+		t.Fatalf("Failed to open corpus: %+v", err)
+	}
+	defer corpus.Close()
+
+	validator := GetDefaultValidator()
+	if validator.IsBreachedPassword("password1234") {
+		t.Errorf("Validator without corpus should never report breaches")
+	}
+
+	validator.breachedPasswords = corpus
+	if !validator.IsBreachedPassword("password1234") {
+		t.Errorf("Breached password not reported")
+	}
+	if validator.IsBreachedPassword("veryStr0ngP4ssw0rd") {
+		t.Errorf("Unknown password reported as breached")
+	}
+}
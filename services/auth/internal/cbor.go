@@ -0,0 +1,177 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Minimal CBOR (RFC 7049) decoder for the structures used by WebAuthn:
+// attestation objects and COSE keys. Authenticators must use definite
+// lengths so indefinite length items are rejected, as are floats.
+
+const (
+	cborUnsigned = 0
+	cborNegative = 1
+	cborBytes    = 2
+	cborText     = 3
+	cborArray    = 4
+	cborMap      = 5
+	cborTag      = 6
+	cborSimple   = 7
+
+	// Nesting in attestation objects is shallow, anything deeper is garbage
+	cborMaxDepth = 16
+)
+
+var errCBORTruncated = errors.New("cbor: unexpected end of data")
+
+// decodeCBOR decodes the first CBOR item in data. Returns the item and the
+// number of bytes it used. Integers are returned as int64, byte strings as
+// []byte, text as string, arrays as []interface{} and maps as
+// map[interface{}]interface{}.
+func decodeCBOR(data []byte) (interface{}, int, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, int, error) {
+	if depth > cborMaxDepth {
+		return nil, 0, errors.New("cbor: nested too deep")
+	}
+
+	major, arg, n, err := decodeCBORHead(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		if arg > 1<<63-1 {
+			return nil, 0, errors.New("cbor: integer overflow")
+		}
+		return int64(arg), n, nil
+
+	case cborNegative:
+		if arg > 1<<63-1 {
+			return nil, 0, errors.New("cbor: integer overflow")
+		}
+		return -1 - int64(arg), n, nil
+
+	case cborBytes, cborText:
+		if arg > uint64(len(data)-n) {
+			return nil, 0, errCBORTruncated
+		}
+		end := n + int(arg)
+		if major == cborText {
+			return string(data[n:end]), end, nil
+		}
+		return append([]byte{}, data[n:end]...), end, nil
+
+	case cborArray:
+		// Every item is at least one byte, bounds the allocation
+		if arg > uint64(len(data)-n) {
+			return nil, 0, errCBORTruncated
+		}
+		items := make([]interface{}, arg)
+		for i := range items {
+			item, used, err := decodeCBORItem(data[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			items[i] = item
+			n += used
+		}
+		return items, n, nil
+
+	case cborMap:
+		if arg > uint64(len(data)-n)/2 {
+			return nil, 0, errCBORTruncated
+		}
+		items := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, used, err := decodeCBORItem(data[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			n += used
+
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, 0, fmt.Errorf("cbor: unsupported map key type %T", key)
+			}
+			if _, ok := items[key]; ok {
+				return nil, 0, fmt.Errorf("cbor: duplicate map key %v", key)
+			}
+
+			value, used, err := decodeCBORItem(data[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			n += used
+			items[key] = value
+		}
+		return items, n, nil
+
+	case cborTag:
+		// Tags only add meaning to the next item, not needed here
+		item, used, err := decodeCBORItem(data[n:], depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		return item, n + used, nil
+
+	default:
+		switch arg {
+		case 20:
+			return false, n, nil
+		case 21:
+			return true, n, nil
+		case 22, 23:
+			return nil, n, nil
+		}
+		return nil, 0, fmt.Errorf("cbor: unsupported simple value %d", arg)
+	}
+}
+
+// decodeCBORHead decodes the initial byte and argument of an item
+func decodeCBORHead(data []byte) (major byte, arg uint64, n int, err error) {
+	if len(data) < 1 {
+		return 0, 0, 0, errCBORTruncated
+	}
+
+	major = data[0] >> 5
+	info := data[0] & 0x1f
+
+	if major == cborSimple && info >= 25 && info <= 27 {
+		return 0, 0, 0, errors.New("cbor: floats are not supported")
+	}
+
+	switch {
+	case info < 24:
+		return major, uint64(info), 1, nil
+	case info == 24:
+		if len(data) < 2 {
+			return 0, 0, 0, errCBORTruncated
+		}
+		return major, uint64(data[1]), 2, nil
+	case info == 25:
+		if len(data) < 3 {
+			return 0, 0, 0, errCBORTruncated
+		}
+		return major, uint64(binary.BigEndian.Uint16(data[1:3])), 3, nil
+	case info == 26:
+		if len(data) < 5 {
+			return 0, 0, 0, errCBORTruncated
+		}
+		return major, uint64(binary.BigEndian.Uint32(data[1:5])), 5, nil
+	case info == 27:
+		if len(data) < 9 {
+			return 0, 0, 0, errCBORTruncated
+		}
+		return major, binary.BigEndian.Uint64(data[1:9]), 9, nil
+	case info == 31:
+		return 0, 0, 0, errors.New("cbor: indefinite lengths are not supported")
+	}
+	return 0, 0, 0, fmt.Errorf("cbor: reserved additional information %d", info)
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultTrustedProxies are the private networks the ingress forwards from
+const DefaultTrustedProxies = "10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,127.0.0.0/8,::1/128"
+
+// ParseTrustedProxies parses a comma separated list of CIDRs
+func ParseTrustedProxies(list string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, cidr := range strings.Split(list, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy '%s': %s", cidr, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+func isTrustedProxy(proxies []*net.IPNet, ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range proxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveClientIP returns the address of the client that connected to the
+// first trusted proxy. X-Forwarded-For is read right to left, entries left
+// of the first untrusted address are set by the client and ignored.
+func resolveClientIP(proxies []*net.IPNet, remoteAddr string, forwardedFor string, realIP string) string {
+	ip, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		ip = remoteAddr
+	}
+	if !isTrustedProxy(proxies, ip) {
+		return ip
+	}
+
+	if forwardedFor != "" {
+		hops := strings.Split(forwardedFor, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip = strings.TrimSpace(hops[i])
+			if !isTrustedProxy(proxies, ip) {
+				return ip
+			}
+		}
+		return ip
+	}
+
+	if realIP != "" {
+		return strings.TrimSpace(realIP)
+	}
+	return ip
+}
+
+// TrustedProxyMiddleware makes ctx.RealIP() safe to use for throttling and
+// auditing. echo trusts the first X-Forwarded-For entry, which any client can
+// set, so the forwarding headers are replaced with the address resolved from
+// the trusted proxies before any handler runs.
+func TrustedProxyMiddleware(proxies []*net.IPNet) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			header := ctx.Request().Header
+			ip := resolveClientIP(proxies, ctx.Request().RemoteAddr,
+				strings.Join(header[echo.HeaderXForwardedFor], ","), header.Get(echo.HeaderXRealIP))
+			header.Del(echo.HeaderXForwardedFor)
+			header.Set(echo.HeaderXRealIP, ip)
+			return next(ctx)
+		}
+	}
+}
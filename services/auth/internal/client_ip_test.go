@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestResolveClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies(DefaultTrustedProxies)
+	if err != nil {
+		t.Fatalf("Failed to parse default trusted proxies: %+v", err)
+	}
+
+	cases := []struct {
+		remoteAddr   string
+		forwardedFor string
+		realIP       string
+		expected     string
+	}{
+		// Direct connections can not claim another address
+		{"203.0.113.7:4000", "198.51.100.1", "198.51.100.2", "203.0.113.7"},
+		{"10.0.0.5:4000", "203.0.113.7", "", "203.0.113.7"},
+		// The client prepends a fake address, the ingress appends the real one
+		{"10.0.0.5:4000", "198.51.100.1, 203.0.113.7", "", "203.0.113.7"},
+		{"10.0.0.5:4000", "198.51.100.1, 203.0.113.7, 10.0.0.9", "", "203.0.113.7"},
+		{"10.0.0.5:4000", "", "203.0.113.7", "203.0.113.7"},
+		{"10.0.0.5:4000", "", "", "10.0.0.5"},
+	}
+	for _, c := range cases {
+		ip := resolveClientIP(proxies, c.remoteAddr, c.forwardedFor, c.realIP)
+		if ip != c.expected {
+			t.Errorf("Request %+v resolved to '%s', wanted '%s'", c, ip, c.expected)
+		}
+	}
+
+	if _, err := ParseTrustedProxies("10.0.0.0/8,not-a-cidr"); err == nil {
+		t.Errorf("Invalid CIDR should be rejected")
+	}
+}
+
+func TestTrustedProxyMiddleware(t *testing.T) {
+	proxies, _ := ParseTrustedProxies(DefaultTrustedProxies)
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.7:4000"
+	req.Header.Set(echo.HeaderXForwardedFor, "198.51.100.1")
+	ctx := e.NewContext(req, httptest.NewRecorder())
+
+	var seen string
+	handler := TrustedProxyMiddleware(proxies)(func(c echo.Context) error {
+		seen = c.RealIP()
+		return nil
+	})
+	if err := handler(ctx); err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+	if seen != "203.0.113.7" {
+		t.Errorf("Spoofed X-Forwarded-For should be ignored, got '%s'", seen)
+	}
+}
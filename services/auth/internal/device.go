@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// Devices kept per account, the least recently seen are forgotten first
+	maxKnownDevices = 20
+	// Devices not seen for this long are forgotten
+	knownDeviceRetention = 90 * 24 * time.Hour
+	// How long the "this wasn't me" link in the new login email works
+	loginReportWindow = 7 * 24 * time.Hour
+	// Reported accounts stay locked until the password is reset, at most as
+	// long as the reset link is valid
+	reportedLoginLockout = 24 * time.Hour
+)
+
+var (
+	errLoginReportNotFound = errors.New("Invalid token provided")
+	errLoginReportExpired  = errors.New("Token has expired")
+)
+
+// deviceFingerprint identifies a device by its IP and user agent
+func deviceFingerprint(ip string, userAgent string) string {
+	hash := sha256.Sum256([]byte(ip + "\n" + userAgent))
+	return hex.EncodeToString(hash[:])
+}
+
+// checkLoginDevice remembers the device of a successful login and emails the
+// user if the account has not been used from it before. The first device of
+// an account is not reported. Failures are logged, they never fail the login.
+func (a *AuthAPI) checkLoginDevice(ctx echo.Context, account *db.Account) {
+	logger := efanlog.GetLogger()
+	ip := ctx.RealIP()
+	userAgent := truncateString(ctx.Request().UserAgent(), maxUserAgentLength)
+	fingerprint := deviceFingerprint(ip, userAgent)
+	now := time.Now()
+
+	res := a.dbHandler.Model(&db.KnownDevice{}).Where("user_id = ? AND fingerprint = ?", account.ID, fingerprint).
+		UpdateColumn("last_seen_at", now)
+	if res.Error != nil {
+		logger.Errorf("Failed to look up device of '%s': %s", account.Username, res.Error)
+		return
+	}
+	if res.RowsAffected > 0 {
+		return
+	}
+
+	var known int
+	err := a.dbHandler.Model(&db.KnownDevice{}).Where("user_id = ?", account.ID).Count(&known).Error
+	if err != nil {
+		logger.Errorf("Failed to count devices of '%s': %s", account.Username, err)
+		return
+	}
+
+	device := &db.KnownDevice{
+		UserID:      account.ID,
+		Fingerprint: fingerprint,
+		LastSeenAt:  now,
+		ReportCode:  uuid.NewV4(),
+	}
+	// Fails on the unique index if a concurrent login stored the device
+	// first, that login sends the email
+	err = a.dbHandler.Save(device).Error
+	if err != nil {
+		logger.Infof("Failed to store device of '%s': %s", account.Username, err)
+		return
+	}
+
+	if known >= maxKnownDevices {
+		err = forgetOldestDevices(a.dbHandler, account.ID)
+		if err != nil {
+			logger.Errorf("Failed to forget old devices of '%s': %s", account.Username, err)
+		}
+	}
+
+	if known == 0 {
+		return
+	}
+
+	logger.Infof("User '%s' logged in from a new device", account.Username)
+	go ScheduleNewLoginEmail(a.beanstalkHandler, account.Username, account.Email, now, userAgent, ip, device.ReportCode.String())
+}
+
+// forgetOldestDevices removes the least recently seen devices of a user
+// above maxKnownDevices
+func forgetOldestDevices(dbHandler *gorm.DB, userID uuid.UUID) error {
+	var devices []db.KnownDevice
+	err := dbHandler.Where("user_id = ?", userID).Order("last_seen_at desc").
+		Offset(maxKnownDevices).Limit(maxKnownDevices).Find(&devices).Error
+	if err != nil || len(devices) == 0 {
+		return err
+	}
+
+	ids := make([]uuid.UUID, len(devices))
+	for i, device := range devices {
+		ids[i] = device.ID
+	}
+	return dbHandler.Unscoped().Where("id IN (?)", ids).Delete(db.KnownDevice{}).Error
+}
+
+// PruneKnownDevices removes devices not seen within the retention period.
+// Returns the number of devices removed.
+func PruneKnownDevices(dbHandler *gorm.DB) (int64, error) {
+	res := dbHandler.Unscoped().Where("last_seen_at < ?", time.Now().Add(-knownDeviceRetention)).Delete(db.KnownDevice{})
+	return res.RowsAffected, res.Error
+}
+
+// Reportlogin is used from the "this wasn't me" link in the new login email.
+// The device is forgotten, the account locked and logged out everywhere, its
+// API keys and sign-in links revoked, and a password reset email is sent.
+// Resetting the password lifts the lock.
+func (a *AuthAPI) Reportlogin(ctx echo.Context) error {
+	var request auth.EmailVerification
+	err := ctx.Bind(&request)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	var account db.Account
+	resetToken := &db.PasswordResetToken{ExpiresAt: time.Now().Add(reportedLoginLockout)}
+	err = db.DoInTransaction(func(tx *gorm.DB) error {
+		err := tx.Where("username = ?", request.Username).First(&account).Error
+		if err != nil {
+			return errLoginReportNotFound
+		}
+
+		var device db.KnownDevice
+		err = tx.Where("report_code = ? AND user_id = ?", request.Token, account.ID).First(&device).Error
+		if err != nil {
+			return errLoginReportNotFound
+		}
+
+		if device.CreatedAt.Add(loginReportWindow).Before(time.Now()) {
+			return errLoginReportExpired
+		}
+
+		// Deleting the device makes the code single use
+		res := tx.Unscoped().Where("id = ?", device.ID).Delete(db.KnownDevice{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != 1 {
+			return errLoginReportNotFound
+		}
+
+		err = revokeUserRefreshTokens(tx, account.ID)
+		if err != nil {
+			return err
+		}
+
+		err = revokeUserAPIKeys(tx, account.ID)
+		if err != nil {
+			return err
+		}
+
+		// Sign-in links would get around the lock
+		err = tx.Unscoped().Where("user_id = ?", account.ID).Delete(db.LoginLinkToken{}).Error
+		if err != nil {
+			return err
+		}
+
+		resetToken.UserID = account.ID
+		return tx.Save(resetToken).Error
+	}, a.dbHandler)
+
+	if err == errLoginReportNotFound || err == errLoginReportExpired {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	err = a.throttler.Lock(account.Username, time.Now().Add(reportedLoginLockout))
+	if err != nil {
+		efanlog.GetLogger().Errorf("Failed to lock '%s' after reported login: %s", account.Username, err)
+	}
+
+	a.recordSecurityEvent(ctx, account.ID, db.LoginReported, "")
+	efanlog.GetLogger().Warnf("User '%s' reported a login from a new device, account locked", account.Username)
+	go SchedulePasswordResetEmail(a.beanstalkHandler, account.Username, account.Email, resetToken.ID.String())
+
+	return ctx.JSON(http.StatusOK, map[string]int{})
+}
@@ -0,0 +1,23 @@
+package internal
+
+import "testing"
+
+func TestDeviceFingerprint(t *testing.T) {
+	fingerprint := deviceFingerprint("203.0.113.7", "Mozilla/5.0")
+	if len(fingerprint) != 64 {
+		t.Errorf("Expected hex SHA-256 fingerprint, got '%s'", fingerprint)
+	}
+	if deviceFingerprint("203.0.113.7", "Mozilla/5.0") != fingerprint {
+		t.Errorf("Fingerprint of the same device should be stable")
+	}
+
+	others := [][2]string{
+		{"203.0.113.8", "Mozilla/5.0"},
+		{"203.0.113.7", "curl/7.64.1"},
+	}
+	for _, other := range others {
+		if deviceFingerprint(other[0], other[1]) == fingerprint {
+			t.Errorf("Device %q should have a different fingerprint", other)
+		}
+	}
+}
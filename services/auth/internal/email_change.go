@@ -0,0 +1,252 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// How long the code sent to the new address is valid
+	emailChangeTimeout = 24 * time.Hour
+	// How long after a confirmed change the old address can revert it
+	emailChangeRevertWindow = 7 * 24 * time.Hour
+)
+
+var (
+	errEmailChangeNotFound = errors.New("Invalid token provided")
+	errEmailChangeExpired  = errors.New("Token has expired")
+	errEmailInUse          = errors.New("The provided email is already registered")
+	errEmailChangeStale    = errors.New("The email of the account has changed since this request was made")
+)
+
+// emailInUse returns true if email belongs to any account other than
+// accountID
+func emailInUse(tx *gorm.DB, email string, accountID uuid.UUID) (bool, error) {
+	var count int
+	err := tx.Model(&db.Account{}).Where("email = ? AND id <> ?", email, accountID).Count(&count).Error
+	return count > 0, err
+}
+
+// Requestemailchange starts a change of email for the logged in user. A
+// confirmation code is sent to the new address and a notice with a cancel
+// code to the current one. Any earlier pending request is replaced.
+func (a *AuthAPI) Requestemailchange(ctx echo.Context) error {
+	logger := efanlog.GetLogger()
+
+	var request auth.EmailChange
+	err := ctx.Bind(&request)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	if rejected, err := a.rejectWrongPassword(ctx, account, request.Password); rejected {
+		return err
+	}
+
+	newEmail := strings.TrimSpace(request.Email)
+	if !a.inputValidator.ValidateEmail(newEmail) {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid email format")
+	}
+
+	if strings.EqualFold(newEmail, account.Email) {
+		return sendAuthAPIError(ctx, http.StatusBadRequest,
+			"New email has to be different from the current email")
+	}
+
+	inUse, err := emailInUse(a.dbHandler, newEmail, account.ID)
+	if err != nil {
+		logger.Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	if inUse {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, errEmailInUse.Error())
+	}
+
+	changeRequest := &db.EmailChangeRequest{
+		UserID:             account.ID,
+		NewEmail:           newEmail,
+		OldEmail:           account.Email,
+		OldEmailVerifiedAt: account.EmailVerifiedAt,
+		CancelCode:         uuid.NewV4(),
+		ExpiresAt:          time.Now().Add(emailChangeTimeout),
+	}
+
+	err = db.DoInTransaction(func(tx *gorm.DB) error {
+		// Only one pending change at a time
+		err := tx.Where("user_id = ? AND confirmed_at IS NULL", account.ID).Delete(db.EmailChangeRequest{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Save(changeRequest).Error
+	}, a.dbHandler)
+	if err != nil {
+		logger.Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	go ScheduleEmailChangeConfirmEmail(a.beanstalkHandler, account.Username, changeRequest.NewEmail, changeRequest.ID.String())
+	go ScheduleEmailChangeNoticeEmail(a.beanstalkHandler, account.Username, changeRequest.OldEmail, changeRequest.NewEmail, changeRequest.CancelCode.String())
+
+	return ctx.JSON(http.StatusOK, map[string]int{})
+}
+
+// Confirmemailchange swaps the email of an account to the new address once
+// the code sent to it is confirmed. The new address counts as verified.
+func (a *AuthAPI) Confirmemailchange(ctx echo.Context) error {
+	var request auth.EmailVerification
+	err := ctx.Bind(&request)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	err = db.DoInTransaction(func(tx *gorm.DB) error {
+		var account db.Account
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Where("username = ?", request.Username).First(&account).Error
+		if err != nil {
+			return errEmailChangeNotFound
+		}
+
+		var changeRequest db.EmailChangeRequest
+		err = tx.Where("id = ? AND user_id = ? AND confirmed_at IS NULL", request.Token, account.ID).First(&changeRequest).Error
+		if err != nil {
+			return errEmailChangeNotFound
+		}
+
+		if changeRequest.ExpiresAt.Before(time.Now()) {
+			return errEmailChangeExpired
+		}
+
+		// The old values are needed to revert the change later
+		if account.Email != changeRequest.OldEmail {
+			return errEmailChangeStale
+		}
+
+		// Someone may have registered the address since the request was made
+		inUse, err := emailInUse(tx, changeRequest.NewEmail, account.ID)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return errEmailInUse
+		}
+
+		timeNow := time.Now()
+		err = tx.Model(&account).Updates(map[string]interface{}{
+			"email":             changeRequest.NewEmail,
+			"email_verified_at": timeNow,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		// Codes for the old address are of no use anymore
+		err = tx.Where("user_id = ?", account.ID).Delete(db.EmailVerificationCode{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&changeRequest).Update("confirmed_at", timeNow).Error
+	}, a.dbHandler)
+
+	switch err {
+	case nil:
+		return ctx.JSON(http.StatusOK, map[string]int{})
+	case errEmailChangeNotFound, errEmailChangeExpired, errEmailInUse, errEmailChangeStale:
+		return sendAuthAPIError(ctx, http.StatusBadRequest, err.Error())
+	default:
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+}
+
+// Cancelemailchange is used from the notice sent to the old address. A
+// pending change is dropped. A confirmed change is reverted for a while
+// after, restoring the old address and its verification state. Someone else
+// changed the email in that case so all sessions are logged out and API keys
+// revoked.
+func (a *AuthAPI) Cancelemailchange(ctx echo.Context) error {
+	var request auth.EmailVerification
+	err := ctx.Bind(&request)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	err = db.DoInTransaction(func(tx *gorm.DB) error {
+		var account db.Account
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Where("username = ?", request.Username).First(&account).Error
+		if err != nil {
+			return errEmailChangeNotFound
+		}
+
+		var changeRequest db.EmailChangeRequest
+		err = tx.Where("cancel_code = ? AND user_id = ?", request.Token, account.ID).First(&changeRequest).Error
+		if err != nil {
+			return errEmailChangeNotFound
+		}
+
+		if changeRequest.ConfirmedAt == nil {
+			return tx.Delete(&changeRequest).Error
+		}
+
+		if changeRequest.ConfirmedAt.Add(emailChangeRevertWindow).Before(time.Now()) {
+			return errEmailChangeExpired
+		}
+
+		// Changed again after this request, the old values are outdated
+		if account.Email != changeRequest.NewEmail {
+			return errEmailChangeStale
+		}
+
+		inUse, err := emailInUse(tx, changeRequest.OldEmail, account.ID)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return errEmailInUse
+		}
+
+		err = tx.Model(&account).Updates(map[string]interface{}{
+			"email":             changeRequest.OldEmail,
+			"email_verified_at": changeRequest.OldEmailVerifiedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		err = revokeUserRefreshTokens(tx, account.ID)
+		if err != nil {
+			return err
+		}
+
+		err = revokeUserAPIKeys(tx, account.ID)
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&changeRequest).Error
+	}, a.dbHandler)
+
+	switch err {
+	case nil:
+		return ctx.JSON(http.StatusOK, map[string]int{})
+	case errEmailChangeNotFound, errEmailChangeExpired, errEmailInUse, errEmailChangeStale:
+		return sendAuthAPIError(ctx, http.StatusBadRequest, err.Error())
+	default:
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+}
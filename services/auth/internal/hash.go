@@ -18,8 +18,16 @@ var (
 	// ErrIncompatibleVersion is returned if the metadata indicates that the
 	// argon hashing algorithm version is not the correct one.
 	ErrIncompatibleVersion = errors.New("incompatible version of argon2")
+
+	// ErrUnknownHashFormat is returned if no hasher is registered for the
+	// prefix of an encoded hash
+	ErrUnknownHashFormat = errors.New("no hasher registered for the encoded hash format")
 )
 
+// argon2Prefix is the prefix of hashes generated by this service. Every other
+// format is rehashed to argon2id on the next successful login.
+const argon2Prefix = "$argon2id$"
+
 // Params holds hashing parameters for Argon2
 type Params struct {
 	memory      uint32
@@ -40,6 +48,73 @@ type HashDecoder interface {
 	Decode(encodedHash string) (p *Params, salt, hash []byte, err error)
 }
 
+// PasswordHasher verifies passwords against one encoded hash format. Used to
+// accept hashes imported from other systems, new hashes are always argon2id.
+type PasswordHasher interface {
+	// Algorithm names the format in reports
+	Algorithm() string
+	// Compare checks password against encodedHash, in constant time
+	Compare(password, encodedHash string) (match bool, err error)
+	// Params describes the parameters encodedHash was generated with
+	Params(encodedHash string) (string, error)
+}
+
+// passwordHashers maps encoded hash prefixes to the hasher handling them
+var passwordHashers = map[string]PasswordHasher{}
+
+// RegisterPasswordHasher registers hasher for encoded hashes starting with
+// prefix, e.g. '$2b$'. Not safe to call concurrently with comparisons,
+// register hashers in init.
+func RegisterPasswordHasher(prefix string, hasher PasswordHasher) {
+	passwordHashers[prefix] = hasher
+}
+
+// findPasswordHasher returns the hasher with the longest prefix matching
+// encodedHash
+func findPasswordHasher(encodedHash string) (PasswordHasher, error) {
+	var found PasswordHasher
+	longest := 0
+	for prefix, hasher := range passwordHashers {
+		if len(prefix) > longest && strings.HasPrefix(encodedHash, prefix) {
+			found = hasher
+			longest = len(prefix)
+		}
+	}
+
+	if found == nil {
+		return nil, ErrUnknownHashFormat
+	}
+	return found, nil
+}
+
+func init() {
+	RegisterPasswordHasher(argon2Prefix, &Argon2Hasher{decoder: &Argon2HashDecoder{}})
+}
+
+// Argon2Hasher verifies argon2id hashes generated by 'GenerateFromPassword'
+type Argon2Hasher struct {
+	decoder HashDecoder
+}
+
+// Algorithm returns the name of the format
+func (h *Argon2Hasher) Algorithm() string {
+	return "argon2id"
+}
+
+// Compare checks password against an argon2id encoded hash
+func (h *Argon2Hasher) Compare(password, encodedHash string) (bool, error) {
+	return fComparePasswordAndHash(password, encodedHash, h.decoder)
+}
+
+// Params describes the argon2id parameters of encodedHash
+func (h *Argon2Hasher) Params(encodedHash string) (string, error) {
+	p, _, _, err := h.decoder.Decode(encodedHash)
+	if err != nil {
+		return "", err
+	}
+	return p.String(), nil
+}
+
 // RandReadGenerator standard cryptographically safe generator using rand.Read().
 type RandReadGenerator struct{}
 
@@ -84,6 +159,40 @@ func GetDefaultHashingParams() *Params {
 	}
 }
 
+// weakerThan returns true if any of the parameters is below policy
+func (p *Params) weakerThan(policy *Params) bool {
+	return p.memory < policy.memory ||
+		p.iterations < policy.iterations ||
+		p.parallelism < policy.parallelism ||
+		p.saltLength < policy.saltLength ||
+		p.keyLength < policy.keyLength
+}
+
+// NeedsRehash returns true if encodedHash was generated with weaker parameters
+// than policy or by another algorithm than argon2id. Such hashes should be
+// replaced the next time the plain-text password is known, i.e. on a
+// successful login.
+func NeedsRehash(encodedHash string, policy *Params) (bool, error) {
+	if !strings.HasPrefix(encodedHash, argon2Prefix) {
+		_, err := findPasswordHasher(encodedHash)
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	p, _, _, err := decodeHash(encodedHash)
+	if err != nil {
+		return false, err
+	}
+	return p.weakerThan(policy), nil
+}
+
+// String formats the parameters the way they appear in reports
+func (p *Params) String() string {
+	return fmt.Sprintf("m=%d,t=%d,p=%d,s=%d,k=%d", p.memory, p.iterations, p.parallelism, p.saltLength, p.keyLength)
+}
+
 func fGenFromPassword(password string, p *Params, saltGenerator SaltGenerator) (hash string, err error) {
 	// Generate a cryptographically secure random salt.
 	salt, err := saltGenerator.Generate(p.saltLength)
@@ -139,12 +248,17 @@ func fComparePasswordAndHash(password, encodedHash string, decoder HashDecoder)
 
 // ComparePasswordAndHash takes a plain-text password and a hashed password,
 // hashes the plain-text password using the parameters from the hashed one, and
-// then compares them. Returns true if they are equal; otherwise false.
+// then compares them. Returns true if they are equal; otherwise false. Any
+// format with a registered 'PasswordHasher' is accepted.
 // -
 // NOTE: Compares using a constant time comparison function. This means this
 // 		 function is deterministically slow.
 func ComparePasswordAndHash(password, encodedHash string) (match bool, err error) {
-	return fComparePasswordAndHash(password, encodedHash, &Argon2HashDecoder{})
+	hasher, err := findPasswordHasher(encodedHash)
+	if err != nil {
+		return false, err
+	}
+	return hasher.Compare(password, encodedHash)
 }
 
 func decodeHash(encodedHash string) (p *Params, salt, hash []byte, err error) {
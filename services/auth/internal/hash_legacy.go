@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Hashers for accounts imported from other systems. They can only verify
+// passwords, the hash is replaced with argon2id on the next successful login.
+
+func init() {
+	bcryptHasher := &BcryptHasher{}
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		RegisterPasswordHasher(prefix, bcryptHasher)
+	}
+	RegisterPasswordHasher("$scrypt$", &ScryptHasher{})
+}
+
+// BcryptHasher verifies bcrypt hashes in modular crypt format
+type BcryptHasher struct{}
+
+// Algorithm returns the name of the format
+func (h *BcryptHasher) Algorithm() string {
+	return "bcrypt"
+}
+
+// Compare checks password against a bcrypt hash
+func (h *BcryptHasher) Compare(password, encodedHash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Params describes the cost of a bcrypt hash
+func (h *BcryptHasher) Params(encodedHash string) (string, error) {
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("cost=%d", cost), nil
+}
+
+// ScryptHasher verifies scrypt hashes in the passlib format:
+// '$scrypt$ln=<log2 N>,r=<r>,p=<p>$<salt>$<hash>' where salt and hash are
+// unpadded base64 with '.' instead of '+'.
+type ScryptHasher struct{}
+
+// Bounds on imported scrypt parameters. A crafted or corrupted hash must not
+// make a login allocate or compute without limit, scrypt.Key needs 128*r*N
+// bytes and runs p times.
+const (
+	maxScryptLogN   = 20
+	maxScryptRP     = 64
+	maxScryptMemory = 1 << 30
+)
+
+type scryptParams struct {
+	logN int
+	r    int
+	p    int
+}
+
+// Algorithm returns the name of the format
+func (h *ScryptHasher) Algorithm() string {
+	return "scrypt"
+}
+
+// Compare checks password against a scrypt hash
+func (h *ScryptHasher) Compare(password, encodedHash string) (bool, error) {
+	p, salt, hash, err := decodeScryptHash(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	otherHash, err := scrypt.Key([]byte(password), salt, 1<<uint(p.logN), p.r, p.p, len(hash))
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(hash, otherHash) == 1, nil
+}
+
+// Params describes the cost parameters of a scrypt hash
+func (h *ScryptHasher) Params(encodedHash string) (string, error) {
+	p, _, _, err := decodeScryptHash(encodedHash)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("ln=%d,r=%d,p=%d", p.logN, p.r, p.p), nil
+}
+
+func decodeScryptHash(encodedHash string) (p *scryptParams, salt, hash []byte, err error) {
+	vals := strings.Split(encodedHash, "$")
+	if len(vals) != 5 || vals[1] != "scrypt" {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	p = &scryptParams{}
+	_, err = fmt.Sscanf(vals[2], "ln=%d,r=%d,p=%d", &p.logN, &p.r, &p.p)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if p.logN < 1 || p.logN > maxScryptLogN || p.r < 1 || p.p < 1 || p.r*p.p > maxScryptRP {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	if 128*p.r<<uint(p.logN) > maxScryptMemory {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	salt, err = decodeAdaptedBase64(vals[3])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	hash, err = decodeAdaptedBase64(vals[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(hash) == 0 {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	return p, salt, hash, nil
+}
+
+// decodeAdaptedBase64 decodes the base64 variant used by passlib
+func decodeAdaptedBase64(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.Replace(s, ".", "+", -1))
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+func TestCompareBcryptHash(t *testing.T) {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		hashed, err := bcrypt.GenerateFromPassword([]byte("legacy_password"), bcrypt.MinCost)
+		if err != nil {
+			t.Fatalf("Failed to generate bcrypt hash: %+v", err)
+		}
+		encoded := prefix + string(hashed[4:])
+
+		match, err := ComparePasswordAndHash("legacy_password", encoded)
+		if err != nil || !match {
+			t.Errorf("Valid %s password rejected: %+v", prefix, err)
+		}
+
+		match, err = ComparePasswordAndHash("wrong_password", encoded)
+		if err != nil || match {
+			t.Errorf("Invalid %s password accepted", prefix)
+		}
+
+		rehash, err := NeedsRehash(encoded, GetDefaultHashingParams())
+		if err != nil || !rehash {
+			t.Errorf("bcrypt hashes should be rehashed to argon2id")
+		}
+	}
+}
+
+func TestCompareScryptHash(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	key, err := scrypt.Key([]byte("legacy_password"), salt, 1<<10, 8, 1, 32)
+	if err != nil {
+		t.Fatalf("Failed to generate scrypt key: %+v", err)
+	}
+	encode := func(b []byte) string {
+		return strings.Replace(base64.RawStdEncoding.EncodeToString(b), "+", ".", -1)
+	}
+	encoded := fmt.Sprintf("$scrypt$ln=10,r=8,p=1$%s$%s", encode(salt), encode(key))
+
+	match, err := ComparePasswordAndHash("legacy_password", encoded)
+	if err != nil || !match {
+		t.Errorf("Valid scrypt password rejected: %+v", err)
+	}
+
+	match, err = ComparePasswordAndHash("wrong_password", encoded)
+	if err != nil || match {
+		t.Errorf("Invalid scrypt password accepted")
+	}
+
+	rehash, err := NeedsRehash(encoded, GetDefaultHashingParams())
+	if err != nil || !rehash {
+		t.Errorf("scrypt hashes should be rehashed to argon2id")
+	}
+
+	_, err = ComparePasswordAndHash("legacy_password", "$scrypt$ln=99,r=8,p=1$c2FsdA$aGFzaA")
+	if err == nil {
+		t.Errorf("Out of range scrypt parameters should fail")
+	}
+}
+
+func TestScryptHashLimits(t *testing.T) {
+	hashes := []string{
+		"$scrypt$ln=30,r=8,p=1$c2FsdA$aGFzaA",
+		"$scrypt$ln=21,r=1,p=1$c2FsdA$aGFzaA",
+		"$scrypt$ln=20,r=16,p=1$c2FsdA$aGFzaA",
+		"$scrypt$ln=10,r=8,p=1000000$c2FsdA$aGFzaA",
+		"$scrypt$ln=10,r=1073741824,p=4$c2FsdA$aGFzaA",
+		"$scrypt$ln=10,r=0,p=1$c2FsdA$aGFzaA",
+	}
+	for _, hash := range hashes {
+		_, err := ComparePasswordAndHash("legacy_password", hash)
+		if err != ErrInvalidHash {
+			t.Errorf("Expected ErrInvalidHash for %s, got %+v", hash, err)
+		}
+	}
+}
+
+func TestUnknownHashFormat(t *testing.T) {
+	_, err := ComparePasswordAndHash("password", "$md5$whatever")
+	if err != ErrUnknownHashFormat {
+		t.Errorf("Unknown format should fail with ErrUnknownHashFormat, got %+v", err)
+	}
+
+	_, err = NeedsRehash("$md5$whatever", GetDefaultHashingParams())
+	if err != ErrUnknownHashFormat {
+		t.Errorf("Unknown format can not be rehashed, got %+v", err)
+	}
+}
@@ -212,6 +212,41 @@ func TestComparePasswordAndHashError(t *testing.T) {
 	}
 }
 
+func TestNeedsRehash(t *testing.T) {
+	policy := GetDefaultHashingParams()
+
+	current, _ := GenerateFromPassword("bogus_password", policy)
+	rehash, err := NeedsRehash(current, policy)
+	if err != nil || rehash {
+		t.Errorf("Hash with current parameters should not need rehash")
+	}
+
+	weaker := []*Params{
+		{memory: policy.memory / 2, iterations: policy.iterations, parallelism: policy.parallelism, saltLength: policy.saltLength, keyLength: policy.keyLength},
+		{memory: policy.memory, iterations: policy.iterations - 1, parallelism: policy.parallelism, saltLength: policy.saltLength, keyLength: policy.keyLength},
+		{memory: policy.memory, iterations: policy.iterations, parallelism: policy.parallelism - 1, saltLength: policy.saltLength, keyLength: policy.keyLength},
+		{memory: policy.memory, iterations: policy.iterations, parallelism: policy.parallelism, saltLength: policy.saltLength, keyLength: policy.keyLength / 2},
+	}
+	for _, p := range weaker {
+		old, _ := GenerateFromPassword("bogus_password", p)
+		rehash, err := NeedsRehash(old, policy)
+		if err != nil || !rehash {
+			t.Errorf("Hash %s should need rehash", old)
+		}
+
+		// Old hashes keep working until replaced
+		match, _ := ComparePasswordAndHash("bogus_password", old)
+		if !match {
+			t.Errorf("Hash with old parameters no longer matches")
+		}
+	}
+
+	_, err = NeedsRehash("not a hash", policy)
+	if err == nil {
+		t.Errorf("NeedsRehash should fail on invalid hash")
+	}
+}
+
 //
 // Testing Helpers
 //
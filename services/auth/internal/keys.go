@@ -0,0 +1,13 @@
+package internal
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetJWKS publishes the public signing keys so other services can verify auth
+// tokens without ever holding the private key
+func (a *AuthAPI) GetJWKS(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, a.keys.JWKS())
+}
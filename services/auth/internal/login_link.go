@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"net/http"
+	"time"
+
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// How long a sign-in link is valid
+	loginLinkTimeout = 15 * time.Minute
+)
+
+// Sign-in links an account can be sent. Requests over the limit are dropped
+// silently so the response does not reveal that the account exists.
+var loginLinkLimit = emailRateLimit{
+	cooldown: time.Minute,
+	limit:    5,
+	window:   time.Hour,
+}
+
+// authWithEmailLink handles the 'email_link' claim. The response is the same
+// whether the account exists or not, the link is sent in the background.
+func (a *AuthAPI) authWithEmailLink(ctx echo.Context, claim *auth.AuthClaim) error {
+	var account db.Account
+	var err error
+	switch {
+	case claim.Username != nil:
+		err = a.dbHandler.Where("username = ?", *claim.Username).First(&account).Error
+	case claim.Email != nil:
+		err = a.dbHandler.Where("email = ?", *claim.Email).First(&account).Error
+	default:
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Username or email required")
+	}
+
+	if err == nil {
+		go a.sendLoginLink(account)
+	} else if !gorm.IsRecordNotFoundError(err) {
+		efanlog.GetLogger().Info(err)
+	}
+
+	return ctx.JSON(http.StatusAccepted, map[string]int{})
+}
+
+// sendLoginLink issues a new sign-in token for account and emails it, unless
+// the account was sent too many links recently
+func (a *AuthAPI) sendLoginLink(account db.Account) {
+	logger := efanlog.GetLogger()
+
+	var loginToken *db.LoginLinkToken
+	err := db.DoInTransaction(func(tx *gorm.DB) error {
+		var locked db.Account
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Where("id = ?", account.ID).First(&locked).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		// Used tokens are soft deleted, they still count towards the limit
+		var tokens []db.LoginLinkToken
+		err = tx.Unscoped().Where("user_id = ? AND created_at > ?", account.ID, now.Add(-loginLinkLimit.window)).
+			Order("created_at").Find(&tokens).Error
+		if err != nil {
+			return err
+		}
+
+		sent := make([]time.Time, len(tokens))
+		for i, token := range tokens {
+			sent[i] = token.CreatedAt
+		}
+
+		wait := loginLinkLimit.wait(sent, now)
+		if wait > 0 {
+			return errResendLimited{retryAfter: wait}
+		}
+
+		loginToken = &db.LoginLinkToken{
+			UserID:    account.ID,
+			ExpiresAt: now.Add(loginLinkTimeout),
+		}
+		return tx.Save(loginToken).Error
+	}, a.dbHandler)
+
+	if _, ok := err.(errResendLimited); ok {
+		logger.Infof("Not sending sign-in link to '%s', too many requested", account.Username)
+		return
+	}
+	if err != nil {
+		logger.Errorf("Failed to create sign-in link for '%s': %s", account.Username, err)
+		return
+	}
+
+	ScheduleLoginLinkEmail(a.beanstalkHandler, account.Username, account.Email, loginToken.ID.String())
+}
+
+// Consumeloginlink signs in with the token from a sign-in link. The token
+// is used up even if the account requires MFA.
+func (a *AuthAPI) Consumeloginlink(ctx echo.Context) error {
+	var request auth.EmailVerification
+	err := ctx.Bind(&request)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	var account db.Account
+	err = a.dbHandler.Where("username = ?", request.Username).First(&account).Error
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid or expired sign-in link")
+	}
+
+	// Locked accounts can not get around the lock with a link, the token is
+	// kept so it works once the lock is lifted
+	if rejected, err := a.rejectThrottled(ctx, account.Username); rejected {
+		return err
+	}
+
+	var token db.LoginLinkToken
+	err = a.dbHandler.Where("id = ? AND user_id = ?", request.Token, account.ID).First(&token).Error
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid or expired sign-in link")
+	}
+
+	// Conditional delete so the same link can not be used twice concurrently
+	res := a.dbHandler.Where("id = ? AND deleted_at IS NULL", token.ID).Delete(db.LoginLinkToken{})
+	if res.Error != nil {
+		efanlog.GetLogger().Info(res.Error)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	if res.RowsAffected != 1 {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid or expired sign-in link")
+	}
+
+	if token.ExpiresAt.Before(time.Now()) {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid or expired sign-in link")
+	}
+
+	return a.completeFirstFactor(ctx, &account, "email_link")
+}
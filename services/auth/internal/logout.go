@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"net/http"
+	"time"
+
+	authlib "github.com/esportsdrafts/esportsdrafts/libs/authlib"
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/labstack/echo/v4"
+)
+
+// Logout revokes the access token of the caller until it expires, ends its
+// session, revokes the refresh token family and clears all auth cookies.
+// Always succeeds so clients can use it to reset their state even with an
+// expired token.
+func (a *AuthAPI) Logout(ctx echo.Context) error {
+	logger := efanlog.GetLogger()
+
+	rawToken, err := authlib.ReadAuthToken(ctx)
+	if err == nil {
+		claims, err := authlib.ParseAuthTokenWithKeys(rawToken, a.keys)
+		// Expired or invalid tokens can not be used anyway
+		if err == nil {
+			err = a.revocationStore.Revoke(claims.Id, time.Unix(claims.ExpiresAt, 0))
+			if err != nil {
+				logger.Errorf("Failed to revoke token: %s", err)
+				return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+			}
+
+			// Clients sending the token in a header may not send the
+			// refresh token, the session still has to end
+			err = a.revokeTokenSession(claims)
+			if err != nil {
+				logger.Errorf("Failed to end session: %s", err)
+				return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+			}
+		}
+	}
+
+	var request auth.Logout
+	// Body is optional
+	_ = ctx.Bind(&request)
+
+	refreshToken := ""
+	if request.RefreshToken != nil {
+		refreshToken = *request.RefreshToken
+	} else if cookie, err := ctx.Cookie(refreshTokenCookie); err == nil {
+		refreshToken = cookie.Value
+	}
+
+	if refreshToken != "" {
+		var token db.RefreshToken
+		err = a.dbHandler.Where("token_hash = ?", hashRefreshToken(refreshToken)).First(&token).Error
+		if err == nil {
+			revokeRefreshTokenFamily(a.dbHandler, token.FamilyID)
+		}
+	}
+
+	authlib.ClearAuthCookies(ctx)
+	writeRefreshTokenCookie(ctx, "", -time.Hour)
+
+	return ctx.JSON(http.StatusOK, map[string]int{})
+}
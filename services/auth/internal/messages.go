@@ -13,25 +13,41 @@ import (
 // Priority 0 will be processed instantly(most urgent), higher number will be
 // processed with less urgency
 const (
-	welcomeEmailJobPriority = 1024 * 5
-	resetEmailJobPriority   = 1024 * 5
-	defaultJobTTR           = 30 * time.Second
-	defaultJobDelay         = 0
-	tubeName                = "email-notifications"
+	welcomeEmailJobPriority  = 1024 * 5
+	resetEmailJobPriority    = 1024 * 5
+	emailChangeJobPriority   = 1024 * 5
+	mfaCodeEmailJobPriority  = 1024
+	loginLinkJobPriority     = 1024
+	lockedEmailJobPriority   = 1024 * 2
+	securityEmailJobPriority = 1024 * 2
+	defaultJobTTR            = 30 * time.Second
+	defaultJobDelay          = 0
+	tubeName                 = "email-notifications"
 )
 
-// ScheduleNewUserEmail schedules a welcome email with email verification
-func ScheduleNewUserEmail(client *beanstalkd_models.Client, username string, email string, verificationCode string) (uint64, error) {
+// scheduleEmailJob marshals a job and puts it on the email notifications tube
+func scheduleEmailJob(client *beanstalkd_models.Client, job interface{}, priority uint32) (uint64, error) {
 	c, err := beanstalk.Dial("tcp", fmt.Sprintf("%s:%s", client.Address, client.Port))
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+
 	t := beanstalk.Tube{
 		Conn: c,
 		Name: tubeName,
 	}
+
+	marshalled, err := json.Marshal(job)
 	if err != nil {
-		efanlog.GetLogger().Errorf("failed to schedule welcome email for user %s", username)
-		return 0, fmt.Errorf("failed to schedule welcome email")
+		return 0, err
 	}
 
+	return t.Put(marshalled, priority, defaultJobDelay, defaultJobTTR)
+}
+
+// ScheduleNewUserEmail schedules a welcome email with email verification
+func ScheduleNewUserEmail(client *beanstalkd_models.Client, username string, email string, verificationCode string) (uint64, error) {
 	efanlog.GetLogger().Infof("Scheduling welcome email to %s (%s) with code %s", username, email, verificationCode)
 
 	emailJob := beanstalkd_models.WelcomeEmail{
@@ -43,14 +59,9 @@ func ScheduleNewUserEmail(client *beanstalkd_models.Client, username string, ema
 		VerificationCode: verificationCode,
 	}
 
-	marshalled, err := json.Marshal(emailJob)
-	if err != nil {
-		efanlog.GetLogger().Errorf("failed to marshal welcome email job")
-		return 0, fmt.Errorf("failed to schedule welcome email")
-	}
-
-	id, err := t.Put(marshalled, welcomeEmailJobPriority, defaultJobDelay, defaultJobTTR)
+	id, err := scheduleEmailJob(client, emailJob, welcomeEmailJobPriority)
 	if err != nil {
+		efanlog.GetLogger().Errorf("failed to schedule welcome email for user %s: %s", username, err)
 		return 0, fmt.Errorf("failed to schedule welcome email")
 	}
 
@@ -59,16 +70,6 @@ func ScheduleNewUserEmail(client *beanstalkd_models.Client, username string, ema
 
 // SchedulePasswordResetEmail schedules a password reset email
 func SchedulePasswordResetEmail(client *beanstalkd_models.Client, username string, email string, resetCode string) (uint64, error) {
-	c, err := beanstalk.Dial("tcp", fmt.Sprintf("%s:%s", client.Address, client.Port))
-	t := beanstalk.Tube{
-		Conn: c,
-		Name: tubeName,
-	}
-	if err != nil {
-		efanlog.GetLogger().Errorf("failed to schedule password reset email for user %s", username)
-		return 0, fmt.Errorf("failed to schedule password reset email")
-	}
-
 	efanlog.GetLogger().Infof("Scheduling password reset email to %s (%s) with code %s", username, email, resetCode)
 
 	emailJob := beanstalkd_models.ResetPasswordEmail{
@@ -80,15 +81,197 @@ func SchedulePasswordResetEmail(client *beanstalkd_models.Client, username strin
 		ResetCode: resetCode,
 	}
 
-	marshalled, err := json.Marshal(emailJob)
+	id, err := scheduleEmailJob(client, emailJob, resetEmailJobPriority)
 	if err != nil {
-		efanlog.GetLogger().Errorf("failed to marshal reset password email job")
+		efanlog.GetLogger().Errorf("failed to schedule password reset email for user %s: %s", username, err)
 		return 0, fmt.Errorf("failed to schedule reset password email")
 	}
 
-	id, err := t.Put(marshalled, resetEmailJobPriority, defaultJobDelay, defaultJobTTR)
+	return id, nil
+}
+
+// ScheduleMFACodeEmail schedules an email with a one-time login code
+func ScheduleMFACodeEmail(client *beanstalkd_models.Client, username string, email string, code string) (uint64, error) {
+	efanlog.GetLogger().Infof("Scheduling MFA code email to %s (%s)", username, email)
+
+	emailJob := beanstalkd_models.MFACodeEmail{
+		Job: beanstalkd_models.Job{
+			JobType: "mfa_code_email",
+		},
+		Username: username,
+		Email:    email,
+		Code:     code,
+	}
+
+	id, err := scheduleEmailJob(client, emailJob, mfaCodeEmailJobPriority)
 	if err != nil {
-		return 0, fmt.Errorf("failed to schedule reset password email")
+		efanlog.GetLogger().Errorf("failed to schedule MFA code email for user %s: %s", username, err)
+		return 0, fmt.Errorf("failed to schedule MFA code email")
+	}
+
+	return id, nil
+}
+
+// ScheduleAccountLockedEmail schedules an email telling the user their account
+// was locked after too many failed login attempts
+func ScheduleAccountLockedEmail(client *beanstalkd_models.Client, username string, email string, lockedUntil time.Time) (uint64, error) {
+	efanlog.GetLogger().Infof("Scheduling account locked email to %s (%s)", username, email)
+
+	emailJob := beanstalkd_models.AccountLockedEmail{
+		Job: beanstalkd_models.Job{
+			JobType: "account_locked_email",
+		},
+		Username:    username,
+		Email:       email,
+		LockedUntil: lockedUntil,
+	}
+
+	id, err := scheduleEmailJob(client, emailJob, lockedEmailJobPriority)
+	if err != nil {
+		efanlog.GetLogger().Errorf("failed to schedule account locked email for user %s: %s", username, err)
+		return 0, fmt.Errorf("failed to schedule account locked email")
+	}
+
+	return id, nil
+}
+
+// SchedulePasswordChangedEmail schedules an email telling the user their
+// password was changed
+func SchedulePasswordChangedEmail(client *beanstalkd_models.Client, username string, email string, changedAt time.Time) (uint64, error) {
+	efanlog.GetLogger().Infof("Scheduling password changed email to %s (%s)", username, email)
+
+	emailJob := beanstalkd_models.PasswordChangedEmail{
+		Job: beanstalkd_models.Job{
+			JobType: "password_changed_email",
+		},
+		Username:  username,
+		Email:     email,
+		ChangedAt: changedAt,
+	}
+
+	id, err := scheduleEmailJob(client, emailJob, securityEmailJobPriority)
+	if err != nil {
+		efanlog.GetLogger().Errorf("failed to schedule password changed email for user %s: %s", username, err)
+		return 0, fmt.Errorf("failed to schedule password changed email")
+	}
+
+	return id, nil
+}
+
+// ScheduleEmailChangeConfirmEmail schedules an email to a new address with the
+// code confirming an email change
+func ScheduleEmailChangeConfirmEmail(client *beanstalkd_models.Client, username string, email string, confirmCode string) (uint64, error) {
+	efanlog.GetLogger().Infof("Scheduling email change confirmation to %s (%s) with code %s", username, email, confirmCode)
+
+	emailJob := beanstalkd_models.EmailChangeConfirmEmail{
+		Job: beanstalkd_models.Job{
+			JobType: "email_change_confirm_email",
+		},
+		Username:    username,
+		Email:       email,
+		ConfirmCode: confirmCode,
+	}
+
+	id, err := scheduleEmailJob(client, emailJob, emailChangeJobPriority)
+	if err != nil {
+		efanlog.GetLogger().Errorf("failed to schedule email change confirmation for user %s: %s", username, err)
+		return 0, fmt.Errorf("failed to schedule email change confirmation email")
+	}
+
+	return id, nil
+}
+
+// ScheduleEmailChangeNoticeEmail schedules an email to the old address telling
+// the user their email is being changed, with a code to cancel the change
+func ScheduleEmailChangeNoticeEmail(client *beanstalkd_models.Client, username string, email string, newEmail string, cancelCode string) (uint64, error) {
+	efanlog.GetLogger().Infof("Scheduling email change notice to %s (%s)", username, email)
+
+	emailJob := beanstalkd_models.EmailChangeNoticeEmail{
+		Job: beanstalkd_models.Job{
+			JobType: "email_change_notice_email",
+		},
+		Username:   username,
+		Email:      email,
+		NewEmail:   newEmail,
+		CancelCode: cancelCode,
+	}
+
+	id, err := scheduleEmailJob(client, emailJob, securityEmailJobPriority)
+	if err != nil {
+		efanlog.GetLogger().Errorf("failed to schedule email change notice for user %s: %s", username, err)
+		return 0, fmt.Errorf("failed to schedule email change notice email")
+	}
+
+	return id, nil
+}
+
+// ScheduleLoginLinkEmail schedules an email with a single-use sign-in link
+func ScheduleLoginLinkEmail(client *beanstalkd_models.Client, username string, email string, loginCode string) (uint64, error) {
+	efanlog.GetLogger().Infof("Scheduling sign-in link email to %s (%s)", username, email)
+
+	emailJob := beanstalkd_models.LoginLinkEmail{
+		Job: beanstalkd_models.Job{
+			JobType: "login_link_email",
+		},
+		Username:  username,
+		Email:     email,
+		LoginCode: loginCode,
+	}
+
+	id, err := scheduleEmailJob(client, emailJob, loginLinkJobPriority)
+	if err != nil {
+		efanlog.GetLogger().Errorf("failed to schedule sign-in link email for user %s: %s", username, err)
+		return 0, fmt.Errorf("failed to schedule sign-in link email")
+	}
+
+	return id, nil
+}
+
+// ScheduleRecoveryCodeUsedEmail schedules an email telling the user a MFA
+// recovery code was used to log in to their account
+func ScheduleRecoveryCodeUsedEmail(client *beanstalkd_models.Client, username string, email string, usedAt time.Time, remaining int) (uint64, error) {
+	efanlog.GetLogger().Infof("Scheduling recovery code used email to %s (%s)", username, email)
+
+	emailJob := beanstalkd_models.RecoveryCodeUsedEmail{
+		Job: beanstalkd_models.Job{
+			JobType: "recovery_code_used_email",
+		},
+		Username:  username,
+		Email:     email,
+		UsedAt:    usedAt,
+		Remaining: remaining,
+	}
+
+	id, err := scheduleEmailJob(client, emailJob, securityEmailJobPriority)
+	if err != nil {
+		efanlog.GetLogger().Errorf("failed to schedule recovery code used email for user %s: %s", username, err)
+		return 0, fmt.Errorf("failed to schedule recovery code used email")
+	}
+
+	return id, nil
+}
+
+// ScheduleNewLoginEmail schedules an email telling the user about a login
+// from a device not seen before, with a link to report it
+func ScheduleNewLoginEmail(client *beanstalkd_models.Client, username string, email string, loginAt time.Time, userAgent string, ip string, reportCode string) (uint64, error) {
+	efanlog.GetLogger().Infof("Scheduling new login email to %s (%s)", username, email)
+
+	emailJob := beanstalkd_models.NewLoginEmail{
+		Job: beanstalkd_models.Job{
+			JobType: "new_login_email",
+		},
+		Username:   username,
+		Email:      email,
+		LoginAt:    loginAt,
+		UserAgent:  userAgent,
+		IP:         ip,
+		ReportCode: reportCode,
+	}
+
+	id, err := scheduleEmailJob(client, emailJob, securityEmailJobPriority)
+	if err != nil {
+		efanlog.GetLogger().Errorf("failed to schedule new login email for user %s: %s", username, err)
+		return 0, fmt.Errorf("failed to schedule new login email")
 	}
 
 	return id, nil
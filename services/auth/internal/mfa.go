@@ -0,0 +1,342 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	authlib "github.com/esportsdrafts/esportsdrafts/libs/authlib"
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// Role of the short-lived token handed out after the first factor. It is
+	// only good for completing the MFA challenge.
+	mfaChallengeRole    = "mfa_challenge"
+	mfaChallengeTimeout = 5 * time.Minute
+	mfaCodeTimeout      = 10 * time.Minute
+	mfaCodeLength       = 8
+	// No 0/O or 1/I, codes are typed by hand from an email
+	mfaCodeAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+)
+
+// generateMFACode generates a random human-readable one-time code
+func generateMFACode() (string, error) {
+	random, err := generateRandomBytes(mfaCodeLength)
+	if err != nil {
+		return "", err
+	}
+
+	code := make([]byte, mfaCodeLength)
+	for i, b := range random {
+		// Alphabet length is a power of two so there is no modulo bias
+		code[i] = mfaCodeAlphabet[int(b)%len(mfaCodeAlphabet)]
+	}
+	return string(code), nil
+}
+
+// sendMFACodeEmail replaces any outstanding codes for the account with a new
+// one and emails it to the user
+func (a *AuthAPI) sendMFACodeEmail(account *db.Account) error {
+	code, err := generateMFACode()
+	if err != nil {
+		return err
+	}
+
+	a.dbHandler.Where("user_id = ?", account.ID).Delete(db.MFACode{})
+
+	mfaCode := &db.MFACode{
+		UserID:    account.ID,
+		Code:      code,
+		ExpiresAt: time.Now().Add(mfaCodeTimeout),
+	}
+
+	err = a.dbHandler.Save(mfaCode).Error
+	if err != nil {
+		return err
+	}
+
+	go ScheduleMFACodeEmail(a.beanstalkHandler, account.Username, account.Email, code)
+	return nil
+}
+
+// getAccountFromContext loads the account of the user authenticated by
+// JWTMiddleware
+func (a *AuthAPI) getAccountFromContext(ctx echo.Context) (*db.Account, error) {
+	claims, ok := authlib.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("no user in context")
+	}
+
+	var account db.Account
+	err := a.dbHandler.Where("id = ?", claims.UserID).First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// sendMFAChallenge responds with a challenge token instead of a JWT. The
+// client exchanges the challenge and a valid code for the real token.
+func (a *AuthAPI) sendMFAChallenge(ctx echo.Context, account *db.Account, method *db.MFAMethod) error {
+	if method.Type == db.EmailMFA {
+		err := a.sendMFACodeEmail(account)
+		if err != nil {
+			efanlog.GetLogger().Info(err)
+			return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+		}
+	}
+
+	claims := &authlib.JWTClaims{
+		Username: account.Username,
+		UserID:   account.ID.String(),
+		Roles:    []string{mfaChallengeRole},
+	}
+
+	tokenString, expirationTime, err := authlib.GenerateAuthTokenWithKeys(claims, mfaChallengeTimeout, a.keys)
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	result := auth.JWT{
+		AccessToken: tokenString,
+		ExpiresIn:   int(expirationTime.Unix()),
+		MfaRequired: true,
+		MfaType:     string(method.Type),
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// getMFAChallengeAccount validates a challenge token and returns the account
+// it was issued for
+func (a *AuthAPI) getMFAChallengeAccount(token *string) (*db.Account, error) {
+	if token == nil {
+		return nil, fmt.Errorf("missing challenge token")
+	}
+
+	claims, err := authlib.ParseAuthTokenWithKeys(*token, a.keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.HasRole(mfaChallengeRole) {
+		return nil, fmt.Errorf("token is not a MFA challenge")
+	}
+
+	var account db.Account
+	err = a.dbHandler.Where("id = ?", claims.UserID).First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// authWithMFA handles the 'mfa' claim, exchanging a MFA challenge token and
+// an authenticator code for an auth token
+func (a *AuthAPI) authWithMFA(ctx echo.Context, claim *auth.AuthClaim) error {
+	account, err := a.getMFAChallengeAccount(claim.Token)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid or expired MFA challenge")
+	}
+
+	if claim.MfaCode == nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "MFA code required")
+	}
+
+	if rejected, err := a.rejectThrottled(ctx, account.Username); rejected {
+		return err
+	}
+
+	method, err := account.GetMFAMethod(a.dbHandler)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	if method == nil || method.Type != db.TOTPMFA {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid authentication claim")
+	}
+
+	step, ok, err := ValidateTOTPCode(method.Secret, *claim.MfaCode, time.Now(), method.LastUsedStep)
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	if !ok {
+		a.recordAuthFailure(ctx, account.Username, account, "mfa")
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid MFA code")
+	}
+
+	// Store the step so the same code can not be used again. Conditional so
+	// two concurrent requests can not both use it.
+	res := a.dbHandler.Model(&db.MFAMethod{}).
+		Where("id = ? AND last_used_step < ?", method.ID, step).
+		Update("last_used_step", step)
+	if res.Error != nil {
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	if res.RowsAffected != 1 {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid MFA code")
+	}
+
+	return a.sendAuthToken(ctx, account, "mfa")
+}
+
+// authWithMFACode handles the 'mfa_code' claim, exchanging a MFA challenge
+// token and the code sent by email for an auth token
+func (a *AuthAPI) authWithMFACode(ctx echo.Context, claim *auth.AuthClaim) error {
+	account, err := a.getMFAChallengeAccount(claim.Token)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid or expired MFA challenge")
+	}
+
+	if claim.MfaCode == nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "MFA code required")
+	}
+
+	if rejected, err := a.rejectThrottled(ctx, account.Username); rejected {
+		return err
+	}
+
+	var mfaCode db.MFACode
+	code := strings.ToUpper(strings.TrimSpace(*claim.MfaCode))
+	err = a.dbHandler.Where("user_id = ? AND code = ?", account.ID, code).First(&mfaCode).Error
+	if err != nil {
+		a.recordAuthFailure(ctx, account.Username, account, "mfa_code")
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid MFA code")
+	}
+
+	if mfaCode.ExpiresAt.Before(time.Now()) {
+		a.dbHandler.Delete(&mfaCode)
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "MFA code has expired")
+	}
+
+	// Codes are single use. Only the request that deletes the code may use
+	// it, so two concurrent requests can not both log in.
+	res := a.dbHandler.Delete(&mfaCode)
+	if res.Error != nil {
+		efanlog.GetLogger().Info(res.Error)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	if res.RowsAffected != 1 {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid MFA code")
+	}
+
+	return a.sendAuthToken(ctx, account, "mfa_code")
+}
+
+// EnableEmailMFA turns on one-time codes by email for the logged in user.
+// The account email is already verified so no confirmation step is needed.
+// Responds with the recovery codes of the account.
+func (a *AuthAPI) EnableEmailMFA(ctx echo.Context) error {
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	method, err := account.GetMFAMethod(a.dbHandler)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	if method != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "MFA is already enabled for this account")
+	}
+
+	err = account.SetMFAMethod(a.dbHandler, &db.MFAMethod{Type: db.EmailMFA})
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	a.recordSecurityEvent(ctx, account.ID, db.MFAEnrolled, string(db.EmailMFA))
+	return a.sendNewRecoveryCodes(ctx, account)
+}
+
+// EnrollTOTP starts TOTP enrollment for the logged in user. The returned
+// secret is pending until confirmed with a valid code.
+func (a *AuthAPI) EnrollTOTP(ctx echo.Context) error {
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	method, err := account.GetMFAMethod(a.dbHandler)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	if method != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "MFA is already enabled for this account")
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	// Only keep the latest pending enrollment around
+	a.dbHandler.Where("user_id = ? AND confirmed_at IS NULL", account.ID).Delete(db.MFAMethod{})
+
+	pending := &db.MFAMethod{
+		UserID: account.ID,
+		Type:   db.TOTPMFA,
+		Secret: secret,
+	}
+
+	err = a.dbHandler.Save(pending).Error
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	result := auth.TOTPEnrollment{
+		Secret: secret,
+		Uri:    TOTPKeyURI(secret, account.Username),
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// ConfirmTOTP finishes a pending TOTP enrollment. From now on logins require
+// a code from the authenticator app. Responds with the recovery codes of the
+// account.
+func (a *AuthAPI) ConfirmTOTP(ctx echo.Context) error {
+	var request auth.MFACode
+	err := ctx.Bind(&request)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	var pending db.MFAMethod
+	err = a.dbHandler.Where("user_id = ? AND type = ? AND confirmed_at IS NULL", account.ID, db.TOTPMFA).
+		Order("created_at desc").First(&pending).Error
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "No pending TOTP enrollment")
+	}
+
+	step, ok, err := ValidateTOTPCode(pending.Secret, request.Code, time.Now(), 0)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	if !ok {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid MFA code")
+	}
+
+	pending.LastUsedStep = step
+	err = account.SetMFAMethod(a.dbHandler, &pending)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	a.recordSecurityEvent(ctx, account.ID, db.MFAEnrolled, string(db.TOTPMFA))
+	return a.sendNewRecoveryCodes(ctx, account)
+}
@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateMFACode(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		code, err := generateMFACode()
+		if err != nil {
+			t.Fatalf("Failed to generate MFA code: %+v", err)
+		}
+		if len(code) != mfaCodeLength {
+			t.Errorf("Expected code of length %d, got '%s'", mfaCodeLength, code)
+		}
+		for _, c := range code {
+			if !strings.ContainsRune(mfaCodeAlphabet, c) {
+				t.Errorf("Code '%s' has character '%c' outside the alphabet", code, c)
+			}
+		}
+		seen[code] = true
+	}
+
+	if len(seen) < 100 {
+		t.Errorf("Expected unique codes, got %d out of 100", len(seen))
+	}
+
+	// Easily confused characters are left out
+	for _, c := range "01IO" {
+		if strings.ContainsRune(mfaCodeAlphabet, c) {
+			t.Errorf("Alphabet should not contain '%c'", c)
+		}
+	}
+}
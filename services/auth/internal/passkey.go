@@ -0,0 +1,421 @@
+package internal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	webAuthnChallengeTimeout = 5 * time.Minute
+	webAuthnRegisterCeremony = "register"
+	webAuthnLoginCeremony    = "login"
+	// Credential IDs are stored base64url encoded in a varchar(255)
+	maxWebAuthnCredentialIDLength = 255
+	maxWebAuthnNameLength         = 64
+	maxWebAuthnCredentials        = 10
+	defaultWebAuthnName           = "Passkey"
+)
+
+// Transport hints passed back to clients, anything else is dropped
+var /* const */ webAuthnTransports = map[string]bool{
+	"usb":        true,
+	"nfc":        true,
+	"ble":        true,
+	"internal":   true,
+	"hybrid":     true,
+	"smart-card": true,
+}
+
+func splitWebAuthnTransports(transports string) []string {
+	if transports == "" {
+		return []string{}
+	}
+	return strings.Split(transports, ",")
+}
+
+func toAPIWebAuthnCredential(credential *db.WebAuthnCredential) auth.WebAuthnCredential {
+	return auth.WebAuthnCredential{
+		Id:         credential.ID.String(),
+		Name:       credential.Name,
+		Transports: splitWebAuthnTransports(credential.Transports),
+		CreatedAt:  credential.CreatedAt,
+		LastUsedAt: credential.LastUsedAt,
+	}
+}
+
+func toWebAuthnDescriptors(credentials []db.WebAuthnCredential) []auth.WebAuthnCredentialDescriptor {
+	descriptors := make([]auth.WebAuthnCredentialDescriptor, len(credentials))
+	for i, credential := range credentials {
+		transports := splitWebAuthnTransports(credential.Transports)
+		descriptors[i] = auth.WebAuthnCredentialDescriptor{
+			Id:         credential.CredentialID,
+			Transports: &transports,
+		}
+	}
+	return descriptors
+}
+
+// createWebAuthnChallenge stores a new challenge for a ceremony. Pass
+// uuid.Nil as userID for logins where the user is not known yet.
+func (a *AuthAPI) createWebAuthnChallenge(userID uuid.UUID, ceremony string) (string, error) {
+	challenge, err := newWebAuthnChallenge()
+	if err != nil {
+		return "", err
+	}
+
+	err = a.dbHandler.Save(&db.WebAuthnChallenge{
+		UserID:    userID,
+		Challenge: challenge,
+		Ceremony:  ceremony,
+		ExpiresAt: time.Now().Add(webAuthnChallengeTimeout),
+	}).Error
+	if err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+// consumeWebAuthnChallenge looks up the challenge a client response was made
+// for and removes it so it can only be answered once
+func (a *AuthAPI) consumeWebAuthnChallenge(clientDataJSON []byte, ceremony string) (*db.WebAuthnChallenge, error) {
+	var clientData webAuthnClientData
+	err := json.Unmarshal(clientDataJSON, &clientData)
+	if err != nil {
+		return nil, webAuthnError("invalid client data: %s", err)
+	}
+
+	var challenge db.WebAuthnChallenge
+	err = a.dbHandler.Where("challenge = ?", clientData.Challenge).First(&challenge).Error
+	if err != nil {
+		return nil, webAuthnError("unknown challenge")
+	}
+
+	// Conditional delete so concurrent responses can not both use it
+	res := a.dbHandler.Unscoped().Where("id = ?", challenge.ID).Delete(db.WebAuthnChallenge{})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected != 1 {
+		return nil, webAuthnError("challenge already used")
+	}
+
+	if challenge.Ceremony != ceremony {
+		return nil, webAuthnError("challenge is for %s", challenge.Ceremony)
+	}
+
+	if challenge.ExpiresAt.Before(time.Now()) {
+		return nil, webAuthnError("challenge expired")
+	}
+	return &challenge, nil
+}
+
+// Beginwebauthnregistration returns the options for creating a new passkey
+// for the logged in user
+func (a *AuthAPI) Beginwebauthnregistration(ctx echo.Context) error {
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	var credentials []db.WebAuthnCredential
+	err = a.dbHandler.Where("user_id = ?", account.ID).Find(&credentials).Error
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	if len(credentials) >= maxWebAuthnCredentials {
+		return sendAuthAPIError(ctx, http.StatusBadRequest,
+			fmt.Sprintf("At most %d passkeys can be registered", maxWebAuthnCredentials))
+	}
+
+	challenge, err := a.createWebAuthnChallenge(account.ID, webAuthnRegisterCeremony)
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	userID := base64.RawURLEncoding.EncodeToString(account.ID.Bytes())
+	options := auth.WebAuthnOptions{
+		Challenge: challenge,
+		RpId:      a.relyingParty.ID,
+		RpName:    &a.relyingParty.Name,
+		UserId:    &userID,
+		UserName:  &account.Username,
+		Timeout:   int(webAuthnChallengeTimeout / time.Millisecond),
+		// Stops the same authenticator from being registered twice
+		Credentials: toWebAuthnDescriptors(credentials),
+	}
+	return ctx.JSON(http.StatusOK, options)
+}
+
+// Finishwebauthnregistration verifies the authenticator response and stores
+// the new passkey
+func (a *AuthAPI) Finishwebauthnregistration(ctx echo.Context) error {
+	logger := efanlog.GetLogger()
+
+	var request auth.WebAuthnRegistration
+	err := ctx.Bind(&request)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	clientDataJSON, err := decodeWebAuthnBase64(request.ClientDataJson)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+	attestationObject, err := decodeWebAuthnBase64(request.AttestationObject)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	challenge, err := a.consumeWebAuthnChallenge(clientDataJSON, webAuthnRegisterCeremony)
+	if err == nil && !uuid.Equal(challenge.UserID, account.ID) {
+		err = webAuthnError("challenge belongs to another user")
+	}
+	if err != nil {
+		logger.Infof("Passkey registration failed for '%s': %s", account.Username, err)
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Passkey could not be verified")
+	}
+
+	verified, err := a.relyingParty.verifyRegistration(challenge.Challenge, clientDataJSON, attestationObject)
+	if err != nil {
+		logger.Infof("Passkey registration failed for '%s': %s", account.Username, err)
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Passkey could not be verified")
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(verified.ID)
+	if len(credentialID) > maxWebAuthnCredentialIDLength {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Passkey credential ID is too long")
+	}
+
+	var transports []string
+	if request.Transports != nil {
+		for _, transport := range *request.Transports {
+			if webAuthnTransports[transport] {
+				transports = append(transports, transport)
+			}
+		}
+	}
+
+	name := defaultWebAuthnName
+	if request.Name != nil && strings.TrimSpace(*request.Name) != "" {
+		name = strings.TrimSpace(*request.Name)
+		if len(name) > maxWebAuthnNameLength {
+			name = name[:maxWebAuthnNameLength]
+		}
+	}
+
+	credential := &db.WebAuthnCredential{
+		UserID:       account.ID,
+		CredentialID: credentialID,
+		PublicKey:    verified.PublicKey,
+		SignCount:    verified.SignCount,
+		Transports:   strings.Join(transports, ","),
+		Name:         name,
+	}
+
+	err = db.DoInTransaction(func(tx *gorm.DB) error {
+		var count int
+		err := tx.Model(&db.WebAuthnCredential{}).Where("credential_id = ?", credentialID).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("Passkey is already registered")
+		}
+		return tx.Save(credential).Error
+	}, a.dbHandler)
+	if err != nil {
+		logger.Infof("Failed to store passkey for '%s': %s", account.Username, err)
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Passkey could not be registered")
+	}
+
+	return ctx.JSON(http.StatusOK, toAPIWebAuthnCredential(credential))
+}
+
+// Listwebauthncredentials lists the passkeys of the logged in user
+func (a *AuthAPI) Listwebauthncredentials(ctx echo.Context) error {
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	var credentials []db.WebAuthnCredential
+	err = a.dbHandler.Where("user_id = ?", account.ID).Order("created_at").Find(&credentials).Error
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	result := make([]auth.WebAuthnCredential, len(credentials))
+	for i := range credentials {
+		result[i] = toAPIWebAuthnCredential(&credentials[i])
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// Deletewebauthncredential removes a passkey from the logged in user
+func (a *AuthAPI) Deletewebauthncredential(ctx echo.Context, id string) error {
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	// Hard delete, the credential ID is unique and may be registered again
+	res := a.dbHandler.Unscoped().Where("id = ? AND user_id = ?", id, account.ID).Delete(db.WebAuthnCredential{})
+	if res.Error != nil {
+		efanlog.GetLogger().Info(res.Error)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	if res.RowsAffected == 0 {
+		return sendAuthAPIError(ctx, http.StatusNotFound, "Passkey not found")
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]int{})
+}
+
+// authWithWebAuthn handles the 'webauthn' claim. Without an assertion it
+// returns a challenge, restricted to the passkeys of username if given. With
+// an assertion it verifies it and hands out tokens. Passkeys verify the user
+// so no further MFA is asked for.
+func (a *AuthAPI) authWithWebAuthn(ctx echo.Context, claim *auth.AuthClaim) error {
+	if claim.Assertion == nil {
+		return a.sendWebAuthnLoginOptions(ctx, claim.Username)
+	}
+
+	logger := efanlog.GetLogger()
+	assertion := claim.Assertion
+
+	clientDataJSON, err := decodeWebAuthnBase64(assertion.ClientDataJson)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+	authenticatorData, err := decodeWebAuthnBase64(assertion.AuthenticatorData)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+	signature, err := decodeWebAuthnBase64(assertion.Signature)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+	credentialID, err := decodeWebAuthnBase64(assertion.CredentialId)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	challenge, err := a.consumeWebAuthnChallenge(clientDataJSON, webAuthnLoginCeremony)
+	if err != nil {
+		logger.Infof("Passkey login failed: %s", err)
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid passkey")
+	}
+
+	var credential db.WebAuthnCredential
+	err = a.dbHandler.Where("credential_id = ?", base64.RawURLEncoding.EncodeToString(credentialID)).First(&credential).Error
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid passkey")
+	}
+
+	// A challenge for a username can only be answered by that user's keys
+	if !uuid.Equal(challenge.UserID, uuid.Nil) && !uuid.Equal(challenge.UserID, credential.UserID) {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid passkey")
+	}
+
+	if assertion.UserHandle != nil && *assertion.UserHandle != "" {
+		userHandle, err := decodeWebAuthnBase64(*assertion.UserHandle)
+		if err != nil || !uuid.Equal(uuid.FromBytesOrNil(userHandle), credential.UserID) {
+			return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid passkey")
+		}
+	}
+
+	var account db.Account
+	err = a.dbHandler.Where("id = ?", credential.UserID).First(&account).Error
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid passkey")
+	}
+
+	// Locked accounts stay locked whatever the factor
+	if rejected, err := a.rejectThrottled(ctx, account.Username); rejected {
+		return err
+	}
+
+	signCount, err := a.relyingParty.verifyAssertion(challenge.Challenge, credential.PublicKey, credential.SignCount,
+		clientDataJSON, authenticatorData, signature)
+	if err == ErrWebAuthnSignCount {
+		logger.Warnf("Passkey %s of '%s' may be cloned, signature counter did not increase", credential.ID, account.Username)
+		a.recordSecurityEvent(ctx, account.ID, db.LoginFailed, "webauthn")
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid passkey")
+	}
+	if err != nil {
+		logger.Infof("Passkey login failed for '%s': %s", account.Username, err)
+		a.recordSecurityEvent(ctx, account.ID, db.LoginFailed, "webauthn")
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid passkey")
+	}
+
+	// Conditional update so two logins racing with the same counter can not
+	// both succeed
+	res := a.dbHandler.Model(&db.WebAuthnCredential{}).
+		Where("id = ? AND sign_count = ?", credential.ID, credential.SignCount).
+		Updates(map[string]interface{}{"sign_count": signCount, "last_used_at": time.Now()})
+	if res.Error != nil {
+		logger.Info(res.Error)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	if res.RowsAffected != 1 {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid passkey")
+	}
+
+	return a.sendAuthToken(ctx, &account, "webauthn")
+}
+
+// sendWebAuthnLoginOptions responds with a new login challenge. Unknown
+// usernames get the same response as logins without a username so the
+// response does not reveal which accounts exist.
+func (a *AuthAPI) sendWebAuthnLoginOptions(ctx echo.Context, username *string) error {
+	userID := uuid.Nil
+	credentials := []db.WebAuthnCredential{}
+
+	if username != nil {
+		var account db.Account
+		err := a.dbHandler.Where("username = ?", *username).First(&account).Error
+		if err == nil {
+			userID = account.ID
+			err = a.dbHandler.Where("user_id = ?", account.ID).Find(&credentials).Error
+		}
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			efanlog.GetLogger().Info(err)
+			return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+		}
+	}
+
+	challenge, err := a.createWebAuthnChallenge(userID, webAuthnLoginCeremony)
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	options := auth.WebAuthnOptions{
+		Challenge:   challenge,
+		RpId:        a.relyingParty.ID,
+		RpName:      &a.relyingParty.Name,
+		Timeout:     int(webAuthnChallengeTimeout / time.Millisecond),
+		Credentials: toWebAuthnDescriptors(credentials),
+	}
+	return ctx.JSON(http.StatusOK, options)
+}
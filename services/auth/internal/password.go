@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"net/http"
+	"time"
+
+	authlib "github.com/esportsdrafts/esportsdrafts/libs/authlib"
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+)
+
+// rejectWrongPassword writes an error response unless password is the
+// current password of account. Used to re-authenticate logged in users before
+// sensitive changes, wrong guesses count as failed logins. Returns true if a
+// response was written.
+func (a *AuthAPI) rejectWrongPassword(ctx echo.Context, account *db.Account, password string) (bool, error) {
+	if rejected, err := a.rejectThrottled(ctx, account.Username); rejected {
+		return true, err
+	}
+
+	match, err := ComparePasswordAndHash(password, account.Password)
+	if err != nil {
+		efanlog.GetLogger().Info("Error hashing and comparing")
+		return true, sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	if !match {
+		a.recordAuthFailure(ctx, account.Username, account, "password_confirmation")
+		return true, sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid password")
+	}
+	return false, nil
+}
+
+// Changepassword sets a new password for the logged in user. The current
+// password is required so a stolen access token is not enough to take over
+// the account. Every session and API key of the user is revoked and the
+// caller gets new tokens in a new session.
+func (a *AuthAPI) Changepassword(ctx echo.Context) error {
+	logger := efanlog.GetLogger()
+
+	var request auth.PasswordChange
+	err := ctx.Bind(&request)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	if rejected, err := a.rejectWrongPassword(ctx, account, request.CurrentPassword); rejected {
+		return err
+	}
+
+	if request.NewPassword == request.CurrentPassword {
+		return sendAuthAPIError(ctx, http.StatusBadRequest,
+			"New password has to be different from the current password")
+	}
+
+	if !a.inputValidator.ValidatePassword(request.NewPassword) {
+		return sendAuthAPIError(ctx, http.StatusBadRequest,
+			"Password has to be between 12 and 127 characters inclusive")
+	}
+
+	if a.inputValidator.IsBreachedPassword(request.NewPassword) {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, breachedPasswordMessage)
+	}
+
+	if rejected, err := a.rejectWeakPassword(ctx, request.NewPassword, account.Username, account.Email); rejected {
+		return err
+	}
+
+	hashedPassword, err := GenerateFromPassword(request.NewPassword, GetDefaultHashingParams())
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	err = db.DoInTransaction(func(tx *gorm.DB) error {
+		err := tx.Model(account).Update("password_hash", hashedPassword).Error
+		if err != nil {
+			return err
+		}
+		err = revokeUserAPIKeys(tx, account.ID)
+		if err != nil {
+			return err
+		}
+		return revokeUserRefreshTokens(tx, account.ID)
+	}, a.dbHandler)
+	if err != nil {
+		logger.Errorf("Failed to change password for '%s': %s", account.Username, err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	// Other sessions are signed out right away, not once their access tokens
+	// expire
+	a.revokeUserAccessTokens(account.ID)
+
+	// The caller gets a new access token below, the old one is not needed
+	if claims, ok := authlib.ClaimsFromContext(ctx); ok {
+		err = a.revocationStore.Revoke(claims.Id, time.Unix(claims.ExpiresAt, 0))
+		if err != nil {
+			logger.Errorf("Failed to revoke token: %s", err)
+		}
+	}
+
+	go SchedulePasswordChangedEmail(a.beanstalkHandler, account.Username, account.Email, time.Now())
+
+	session, refreshToken, err := a.startSession(ctx, account)
+	if err != nil {
+		logger.Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	return a.writeAuthTokens(ctx, account, session, refreshToken)
+}
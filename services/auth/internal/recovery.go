@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	recoveryCodeCount = 10
+	// Two groups of five, e.g. 'K7M2Q-9XHRT'
+	recoveryCodeLength    = 10
+	recoveryCodeSeparator = "-"
+)
+
+// generateRecoveryCode generates a random recovery code from the same
+// alphabet as emailed MFA codes
+func generateRecoveryCode() (string, error) {
+	random, err := generateRandomBytes(recoveryCodeLength)
+	if err != nil {
+		return "", err
+	}
+
+	code := make([]byte, recoveryCodeLength)
+	for i, b := range random {
+		code[i] = mfaCodeAlphabet[int(b)%len(mfaCodeAlphabet)]
+	}
+	half := recoveryCodeLength / 2
+	return string(code[:half]) + recoveryCodeSeparator + string(code[half:]), nil
+}
+
+// normalizeRecoveryCode makes codes typed without the separator or in lower
+// case match
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	code = strings.Replace(code, recoveryCodeSeparator, "", -1)
+	return strings.Replace(code, " ", "", -1)
+}
+
+// replaceRecoveryCodes generates a new set of recovery codes for the user,
+// removing any old codes. Returns the codes in plain text, they can not be
+// recovered later.
+func (a *AuthAPI) replaceRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	// Hash before starting the transaction, argon2 is deliberately slow
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		hash, err := GenerateFromPassword(normalizeRecoveryCode(code), GetDefaultHashingParams())
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = hash
+	}
+
+	err := db.DoInTransaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Where("user_id = ?", userID).Delete(db.MFARecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+		for _, hash := range hashes {
+			err = tx.Save(&db.MFARecoveryCode{UserID: userID, CodeHash: hash}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}, a.dbHandler)
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// sendNewRecoveryCodes responds with a new set of recovery codes for account
+func (a *AuthAPI) sendNewRecoveryCodes(ctx echo.Context, account *db.Account) error {
+	codes, err := a.replaceRecoveryCodes(account.ID)
+	if err != nil {
+		efanlog.GetLogger().Errorf("Failed to create recovery codes for '%s': %s", account.Username, err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	return ctx.JSON(http.StatusOK, auth.MFARecoveryCodes{Codes: codes})
+}
+
+// authWithRecoveryCode handles the 'mfa_recovery' claim, exchanging a MFA
+// challenge token and an unused recovery code for an auth token. Works with
+// any MFA method.
+func (a *AuthAPI) authWithRecoveryCode(ctx echo.Context, claim *auth.AuthClaim) error {
+	logger := efanlog.GetLogger()
+
+	account, err := a.getMFAChallengeAccount(claim.Token)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid or expired MFA challenge")
+	}
+
+	if claim.MfaCode == nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Recovery code required")
+	}
+
+	if rejected, err := a.rejectThrottled(ctx, account.Username); rejected {
+		return err
+	}
+
+	var codes []db.MFARecoveryCode
+	err = a.dbHandler.Where("user_id = ? AND used_at IS NULL", account.ID).Find(&codes).Error
+	if err != nil {
+		logger.Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	code := normalizeRecoveryCode(*claim.MfaCode)
+	var used *db.MFARecoveryCode
+	for i := range codes {
+		match, err := ComparePasswordAndHash(code, codes[i].CodeHash)
+		if err != nil {
+			logger.Errorf("Invalid recovery code hash %s: %s", codes[i].ID, err)
+			continue
+		}
+		if match {
+			used = &codes[i]
+			break
+		}
+	}
+
+	if used == nil {
+		a.recordAuthFailure(ctx, account.Username, account, "mfa_recovery")
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid recovery code")
+	}
+
+	// Conditional update so the same code can not be used twice concurrently
+	usedAt := time.Now()
+	res := a.dbHandler.Model(&db.MFARecoveryCode{}).Where("id = ? AND used_at IS NULL", used.ID).Update("used_at", usedAt)
+	if res.Error != nil {
+		logger.Info(res.Error)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	if res.RowsAffected != 1 {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid recovery code")
+	}
+
+	logger.Infof("User '%s' logged in with a recovery code", account.Username)
+	go ScheduleRecoveryCodeUsedEmail(a.beanstalkHandler, account.Username, account.Email, usedAt, len(codes)-1)
+
+	return a.sendAuthToken(ctx, account, "mfa_recovery")
+}
+
+// GetRecoveryCodeStatus returns how many unused recovery codes the logged in
+// user has left
+func (a *AuthAPI) GetRecoveryCodeStatus(ctx echo.Context) error {
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	var remaining int
+	err = a.dbHandler.Model(&db.MFARecoveryCode{}).Where("user_id = ? AND used_at IS NULL", account.ID).Count(&remaining).Error
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	return ctx.JSON(http.StatusOK, auth.MFARecoveryStatus{Remaining: remaining})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the logged in user
+// with a new set. Requires the current password since the codes bypass MFA.
+func (a *AuthAPI) RegenerateRecoveryCodes(ctx echo.Context) error {
+	var request auth.RecoveryCodeRegeneration
+	err := ctx.Bind(&request)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	if rejected, err := a.rejectWrongPassword(ctx, account, request.Password); rejected {
+		return err
+	}
+
+	method, err := account.GetMFAMethod(a.dbHandler)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	if method == nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "MFA is not enabled for this account")
+	}
+
+	return a.sendNewRecoveryCodes(ctx, account)
+}
@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateRecoveryCode(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(code) != recoveryCodeLength+len(recoveryCodeSeparator) {
+			t.Errorf("Wrong length of code '%s'", code)
+		}
+		if strings.Index(code, recoveryCodeSeparator) != recoveryCodeLength/2 {
+			t.Errorf("Separator missing from code '%s'", code)
+		}
+		for _, c := range normalizeRecoveryCode(code) {
+			if !strings.ContainsRune(mfaCodeAlphabet, c) {
+				t.Errorf("Code '%s' contains '%c' outside the alphabet", code, c)
+			}
+		}
+
+		if seen[code] {
+			t.Errorf("Duplicate code '%s'", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestRecoveryCodeMatchesTypedVariants(t *testing.T) {
+	code, err := generateRecoveryCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := GenerateFromPassword(normalizeRecoveryCode(code), GetDefaultHashingParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	variants := []string{
+		code,
+		strings.ToLower(code),
+		strings.Replace(code, recoveryCodeSeparator, "", -1),
+		" " + strings.Replace(code, recoveryCodeSeparator, " ", -1) + " ",
+	}
+	for _, variant := range variants {
+		match, err := ComparePasswordAndHash(normalizeRecoveryCode(variant), hash)
+		if err != nil || !match {
+			t.Errorf("Variant '%s' of '%s' should match", variant, code)
+		}
+	}
+
+	other, _ := generateRecoveryCode()
+	if match, _ := ComparePasswordAndHash(normalizeRecoveryCode(other), hash); match {
+		t.Errorf("Different code '%s' should not match '%s'", other, code)
+	}
+}
@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	refreshTokenTimeout = 30 * 24 * time.Hour
+	refreshTokenLength  = 32
+	refreshTokenCookie  = "refresh_token"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned if a refresh token is unknown or
+	// expired
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+	// ErrRefreshTokenReuse is returned if an already rotated refresh token is
+	// used again. Either the client is buggy or the token was stolen, in both
+	// cases the whole token family is revoked.
+	ErrRefreshTokenReuse = errors.New("refresh token reuse detected")
+)
+
+// hashRefreshToken hashes a refresh token for storage. Tokens are random with
+// high entropy so a fast hash is enough.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueRefreshToken creates a new refresh token for a user. Pass uuid.Nil as
+// familyID to start a new token family.
+func issueRefreshToken(dbHandler *gorm.DB, userID uuid.UUID, familyID uuid.UUID) (string, error) {
+	random, err := generateRandomBytes(refreshTokenLength)
+	if err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(random)
+
+	if uuid.Equal(familyID, uuid.Nil) {
+		familyID = uuid.NewV4()
+	}
+
+	refreshToken := &db.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashRefreshToken(token),
+		ExpiresAt: time.Now().Add(refreshTokenTimeout),
+	}
+
+	err = dbHandler.Save(refreshToken).Error
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// checkRefreshToken returns ErrRefreshTokenReuse if the token was already
+// rotated or revoked, in which case the family has to be revoked, and
+// ErrInvalidRefreshToken if it has expired
+func checkRefreshToken(refreshToken *db.RefreshToken, now time.Time) error {
+	if refreshToken.UsedAt != nil || refreshToken.RevokedAt != nil {
+		return ErrRefreshTokenReuse
+	}
+	if refreshToken.ExpiresAt.Before(now) {
+		return ErrInvalidRefreshToken
+	}
+	return nil
+}
+
+// rotateRefreshToken marks a refresh token as used and issues the next token
+// in the same family. Returns the owning account, the family and the new
+// token.
+func rotateRefreshToken(dbHandler *gorm.DB, token string) (*db.Account, uuid.UUID, string, error) {
+	var refreshToken db.RefreshToken
+	err := dbHandler.Where("token_hash = ?", hashRefreshToken(token)).First(&refreshToken).Error
+	if err != nil {
+		return nil, uuid.Nil, "", ErrInvalidRefreshToken
+	}
+
+	err = checkRefreshToken(&refreshToken, time.Now())
+	if err == ErrRefreshTokenReuse {
+		revokeRefreshTokenFamily(dbHandler, refreshToken.FamilyID)
+	}
+	if err != nil {
+		return nil, uuid.Nil, "", err
+	}
+
+	// Conditional update so two concurrent requests can not both rotate the
+	// same token
+	res := dbHandler.Model(&db.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", refreshToken.ID).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return nil, uuid.Nil, "", res.Error
+	}
+	if res.RowsAffected != 1 {
+		revokeRefreshTokenFamily(dbHandler, refreshToken.FamilyID)
+		return nil, uuid.Nil, "", ErrRefreshTokenReuse
+	}
+
+	var account db.Account
+	err = dbHandler.Where("id = ?", refreshToken.UserID).First(&account).Error
+	if err != nil {
+		return nil, uuid.Nil, "", ErrInvalidRefreshToken
+	}
+
+	newToken, err := issueRefreshToken(dbHandler, account.ID, refreshToken.FamilyID)
+	if err != nil {
+		return nil, uuid.Nil, "", err
+	}
+	return &account, refreshToken.FamilyID, newToken, nil
+}
+
+// revokeRefreshTokenFamily revokes every token rotated from the same login
+// and ends the session of it
+func revokeRefreshTokenFamily(dbHandler *gorm.DB, familyID uuid.UUID) error {
+	now := time.Now()
+	err := dbHandler.Model(&db.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+	return dbHandler.Model(&db.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+}
+
+// revokeUserRefreshTokens revokes every refresh token of a user and ends all
+// of their sessions, their access tokens are rejected from then on
+func revokeUserRefreshTokens(dbHandler *gorm.DB, userID uuid.UUID) error {
+	now := time.Now()
+	err := dbHandler.Model(&db.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+	return dbHandler.Model(&db.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
+
+// writeRefreshTokenCookie stores the refresh token for browsers. Only sent to
+// the auth endpoints and never readable from JS.
+func writeRefreshTokenCookie(ctx echo.Context, token string, expiry time.Duration) {
+	cookie := new(http.Cookie)
+	cookie.Name = refreshTokenCookie
+	cookie.Value = token
+	cookie.Path = "/v1/auth"
+	cookie.Secure = true
+	cookie.HttpOnly = true
+	cookie.Expires = time.Now().Add(expiry)
+	ctx.SetCookie(cookie)
+}
+
+// authWithRefreshToken handles the 'refresh_token' claim. Browsers send the
+// refresh token as a cookie, other clients in the token field.
+func (a *AuthAPI) authWithRefreshToken(ctx echo.Context, claim *auth.AuthClaim) error {
+	var token string
+	if claim.Token != nil {
+		token = *claim.Token
+	} else if cookie, err := ctx.Cookie(refreshTokenCookie); err == nil {
+		token = cookie.Value
+	}
+
+	if token == "" {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Refresh token required")
+	}
+
+	account, familyID, newToken, err := rotateRefreshToken(a.dbHandler, token)
+	if err == ErrRefreshTokenReuse {
+		efanlog.GetLogger().Warn("Refresh token reuse detected, token family revoked")
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Refresh token is no longer valid, please log in again")
+	}
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid or expired refresh token")
+	}
+
+	session, err := a.continueSession(ctx, account.ID, familyID)
+	if err == errSessionNotFound {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Refresh token is no longer valid, please log in again")
+	}
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	return a.writeAuthTokens(ctx, account, session, newToken)
+}
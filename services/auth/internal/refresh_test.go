@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+)
+
+func TestCheckRefreshToken(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Minute)
+	cases := []struct {
+		name     string
+		token    db.RefreshToken
+		expected error
+	}{
+		{"valid", db.RefreshToken{ExpiresAt: now.Add(refreshTokenTimeout)}, nil},
+		{"rotated", db.RefreshToken{ExpiresAt: now.Add(refreshTokenTimeout), UsedAt: &earlier}, ErrRefreshTokenReuse},
+		{"revoked", db.RefreshToken{ExpiresAt: now.Add(refreshTokenTimeout), RevokedAt: &earlier}, ErrRefreshTokenReuse},
+		{"expired", db.RefreshToken{ExpiresAt: earlier}, ErrInvalidRefreshToken},
+		// Reuse wins so the family is revoked even if the token expired
+		{"rotated and expired", db.RefreshToken{ExpiresAt: earlier, UsedAt: &earlier}, ErrRefreshTokenReuse},
+	}
+	for _, c := range cases {
+		err := checkRefreshToken(&c.token, now)
+		if err != c.expected {
+			t.Errorf("Token %s gave %v, wanted %v", c.name, err, c.expected)
+		}
+	}
+}
+
+func TestHashRefreshToken(t *testing.T) {
+	hash := hashRefreshToken("token")
+	if len(hash) != 64 || hash != hashRefreshToken("token") {
+		t.Errorf("Expected stable hex SHA-256 hash, got '%s'", hash)
+	}
+	if hash == hashRefreshToken("other") {
+		t.Errorf("Different tokens should have different hashes")
+	}
+}
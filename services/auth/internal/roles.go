@@ -0,0 +1,359 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	roleGrantAction  = "grant"
+	roleRevokeAction = "revoke"
+
+	adminRole           = "admin"
+	maxRoleReasonLength = 255
+	// Newest entries returned by the audit log endpoint
+	roleAuditLimit = 500
+)
+
+// Roles that can be granted on top of 'user'
+var /* const */ assignableRoles = map[string]bool{
+	adminRole:   true,
+	"moderator": true,
+	"support":   true,
+}
+
+var (
+	errRoleAlreadyHeld = errors.New("role already held")
+	errRoleNotHeld     = errors.New("role not held")
+)
+
+// accountRoles returns the roles to put in tokens issued to account. Assigned
+// roles only count once the email is verified.
+func (a *AuthAPI) accountRoles(account *db.Account) ([]string, error) {
+	if !account.IsEmailVerified() {
+		return []string{"email_verify"}, nil
+	}
+
+	var assignments []db.RoleAssignment
+	err := a.dbHandler.Where("user_id = ?", account.ID).Order("role").Find(&assignments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []string{"user"}
+	for _, assignment := range assignments {
+		roles = append(roles, assignment.Role)
+	}
+	return roles, nil
+}
+
+// changeRole grants or revokes a role and records the change in the audit
+// log. Revoking ends the sessions of the account. actorID is uuid.Nil for
+// changes not made by an admin.
+func changeRole(dbHandler *gorm.DB, userID uuid.UUID, role string, action string, actorID uuid.UUID, reason string) error {
+	return db.DoInTransaction(func(tx *gorm.DB) error {
+		switch action {
+		case roleGrantAction:
+			var count int
+			err := tx.Model(&db.RoleAssignment{}).Where("user_id = ? AND role = ?", userID, role).Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return errRoleAlreadyHeld
+			}
+
+			err = tx.Save(&db.RoleAssignment{
+				UserID:    userID,
+				Role:      role,
+				GrantedBy: actorID,
+				Reason:    reason,
+			}).Error
+			if err != nil {
+				return err
+			}
+		case roleRevokeAction:
+			res := tx.Unscoped().Where("user_id = ? AND role = ?", userID, role).Delete(db.RoleAssignment{})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errRoleNotHeld
+			}
+
+			// Tokens carry the roles, end the sessions so the role is not
+			// kept until they expire
+			err := revokeUserRefreshTokens(tx, userID)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown role action '%s'", action)
+		}
+
+		return tx.Save(&db.RoleAuditEntry{
+			UserID:  userID,
+			Role:    role,
+			Action:  action,
+			ActorID: actorID,
+			Reason:  reason,
+		}).Error
+	}, dbHandler)
+}
+
+// BootstrapAdmin grants the admin role to username unless some account is
+// admin already. Lets the first admin in, after that admins grant roles.
+func BootstrapAdmin(dbHandler *gorm.DB, username string) error {
+	var count int
+	err := dbHandler.Model(&db.RoleAssignment{}).Where("role = ?", adminRole).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var account db.Account
+	err = dbHandler.Where("username = ?", strings.ToLower(username)).First(&account).Error
+	if err != nil {
+		return fmt.Errorf("account '%s' not found: %s", username, err)
+	}
+
+	err = changeRole(dbHandler, account.ID, adminRole, roleGrantAction, uuid.Nil, "Granted on startup")
+	if err != nil && err != errRoleAlreadyHeld {
+		return err
+	}
+	efanlog.GetLogger().Infof("Granted admin role to '%s' on startup", account.Username)
+	return nil
+}
+
+// usernamesByID looks up the usernames of accounts, including deleted ones
+func (a *AuthAPI) usernamesByID(ids []uuid.UUID) (map[uuid.UUID]string, error) {
+	usernames := map[uuid.UUID]string{}
+	if len(ids) == 0 {
+		return usernames, nil
+	}
+
+	var accounts []db.Account
+	err := a.dbHandler.Unscoped().Where("id IN (?)", ids).Find(&accounts).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		usernames[account.ID] = account.Username
+	}
+	return usernames, nil
+}
+
+// optionalUsername returns the username for id, nil for changes not made by
+// an admin
+func optionalUsername(usernames map[uuid.UUID]string, id uuid.UUID) *string {
+	if uuid.Equal(id, uuid.Nil) {
+		return nil
+	}
+	username := usernames[id]
+	return &username
+}
+
+// roleUsernames looks up the usernames referenced by role assignments and
+// audit entries
+func (a *AuthAPI) roleUsernames(assignments []db.RoleAssignment, entries []db.RoleAuditEntry) (map[uuid.UUID]string, error) {
+	var ids []uuid.UUID
+	for _, assignment := range assignments {
+		ids = append(ids, assignment.UserID, assignment.GrantedBy)
+	}
+	for _, entry := range entries {
+		ids = append(ids, entry.UserID, entry.ActorID)
+	}
+	return a.usernamesByID(ids)
+}
+
+func toAPIRoleAssignment(assignment *db.RoleAssignment, usernames map[uuid.UUID]string) auth.RoleAssignment {
+	return auth.RoleAssignment{
+		Username:  usernames[assignment.UserID],
+		Role:      assignment.Role,
+		Reason:    assignment.Reason,
+		GrantedAt: assignment.CreatedAt,
+		GrantedBy: optionalUsername(usernames, assignment.GrantedBy),
+	}
+}
+
+func toAPIRoleAuditEntry(entry *db.RoleAuditEntry, usernames map[uuid.UUID]string) auth.RoleAuditEntry {
+	return auth.RoleAuditEntry{
+		Username:  usernames[entry.UserID],
+		Role:      entry.Role,
+		Action:    entry.Action,
+		Reason:    entry.Reason,
+		Actor:     optionalUsername(usernames, entry.ActorID),
+		CreatedAt: entry.CreatedAt,
+	}
+}
+
+// rejectInvalidRoleChange validates a role change request and returns the
+// account it is for
+func (a *AuthAPI) rejectInvalidRoleChange(ctx echo.Context, change *auth.RoleChange) (*db.Account, bool, error) {
+	if !assignableRoles[change.Role] {
+		return nil, true, sendAuthAPIError(ctx, http.StatusBadRequest, "Unknown role")
+	}
+
+	change.Reason = strings.TrimSpace(change.Reason)
+	if change.Reason == "" || len(change.Reason) > maxRoleReasonLength {
+		return nil, true, sendAuthAPIError(ctx, http.StatusBadRequest,
+			fmt.Sprintf("Reason must be between 1 and %d characters", maxRoleReasonLength))
+	}
+
+	var account db.Account
+	err := a.dbHandler.Where("username = ?", strings.ToLower(change.Username)).First(&account).Error
+	if err != nil {
+		return nil, true, sendAuthAPIError(ctx, http.StatusNotFound, "Account not found")
+	}
+	return &account, false, nil
+}
+
+// GrantRole grants a role to an account. Only available to admins.
+func (a *AuthAPI) GrantRole(ctx echo.Context) error {
+	var change auth.RoleChange
+	err := ctx.Bind(&change)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	admin, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	account, rejected, err := a.rejectInvalidRoleChange(ctx, &change)
+	if rejected {
+		return err
+	}
+
+	err = changeRole(a.dbHandler, account.ID, change.Role, roleGrantAction, admin.ID, change.Reason)
+	if err == errRoleAlreadyHeld {
+		return ctx.JSON(http.StatusOK, map[string]int{})
+	}
+	if err != nil {
+		efanlog.GetLogger().Errorf("Failed to grant role '%s' to '%s': %s", change.Role, account.Username, err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	efanlog.GetLogger().Infof("Role '%s' granted to '%s' by '%s'", change.Role, account.Username, admin.Username)
+	return ctx.JSON(http.StatusOK, map[string]int{})
+}
+
+// RevokeRole revokes a role from an account and signs it out everywhere, so
+// the role is gone right away. Only available to admins.
+func (a *AuthAPI) RevokeRole(ctx echo.Context) error {
+	var change auth.RoleChange
+	err := ctx.Bind(&change)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	admin, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	account, rejected, err := a.rejectInvalidRoleChange(ctx, &change)
+	if rejected {
+		return err
+	}
+
+	// Another admin has to do it, so there is always at least one admin left
+	if uuid.Equal(account.ID, admin.ID) && change.Role == adminRole {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Admins can not revoke their own admin role")
+	}
+
+	err = changeRole(a.dbHandler, account.ID, change.Role, roleRevokeAction, admin.ID, change.Reason)
+	if err == errRoleNotHeld {
+		return sendAuthAPIError(ctx, http.StatusNotFound, "Account does not have the role")
+	}
+	if err != nil {
+		efanlog.GetLogger().Errorf("Failed to revoke role '%s' from '%s': %s", change.Role, account.Username, err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	a.revokeUserAccessTokens(account.ID)
+
+	efanlog.GetLogger().Infof("Role '%s' revoked from '%s' by '%s'", change.Role, account.Username, admin.Username)
+	return ctx.JSON(http.StatusOK, map[string]int{})
+}
+
+// filterByUsername narrows query to the records of username if given.
+// Returns false if there is no such account.
+func (a *AuthAPI) filterByUsername(query *gorm.DB, username *string) (*gorm.DB, bool) {
+	if username == nil {
+		return query, true
+	}
+
+	var account db.Account
+	err := a.dbHandler.Where("username = ?", strings.ToLower(*username)).First(&account).Error
+	if err != nil {
+		return nil, false
+	}
+	return query.Where("user_id = ?", account.ID), true
+}
+
+// ListRoleAssignments lists granted roles. Only available to admins.
+func (a *AuthAPI) ListRoleAssignments(ctx echo.Context, params auth.ListRoleAssignmentsParams) error {
+	query, ok := a.filterByUsername(a.dbHandler, params.Username)
+	if !ok {
+		return sendAuthAPIError(ctx, http.StatusNotFound, "Account not found")
+	}
+
+	var assignments []db.RoleAssignment
+	err := query.Order("created_at").Find(&assignments).Error
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	usernames, err := a.roleUsernames(assignments, nil)
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	result := make([]auth.RoleAssignment, len(assignments))
+	for i := range assignments {
+		result[i] = toAPIRoleAssignment(&assignments[i], usernames)
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// ListRoleAudit lists the latest role changes. Only available to admins.
+func (a *AuthAPI) ListRoleAudit(ctx echo.Context, params auth.ListRoleAuditParams) error {
+	query, ok := a.filterByUsername(a.dbHandler, params.Username)
+	if !ok {
+		return sendAuthAPIError(ctx, http.StatusNotFound, "Account not found")
+	}
+
+	var entries []db.RoleAuditEntry
+	err := query.Order("created_at desc").Limit(roleAuditLimit).Find(&entries).Error
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	usernames, err := a.roleUsernames(nil, entries)
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	result := make([]auth.RoleAuditEntry, len(entries))
+	for i := range entries {
+		result[i] = toAPIRoleAuditEntry(&entries[i], usernames)
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+func TestAssignableRoles(t *testing.T) {
+	// These are derived from the account state and must never be stored
+	for _, role := range []string{"user", "email_verify", "service:notifications"} {
+		if assignableRoles[role] {
+			t.Errorf("Role '%s' should not be assignable", role)
+		}
+	}
+	if !assignableRoles[adminRole] {
+		t.Errorf("Admin role should be assignable")
+	}
+}
+
+func TestOptionalUsername(t *testing.T) {
+	id := uuid.NewV4()
+	usernames := map[uuid.UUID]string{id: "someadmin"}
+
+	if username := optionalUsername(usernames, uuid.Nil); username != nil {
+		t.Errorf("Changes made on startup should have no actor, got '%s'", *username)
+	}
+	if username := optionalUsername(usernames, id); username == nil || *username != "someadmin" {
+		t.Errorf("Expected actor 'someadmin', got %v", username)
+	}
+}
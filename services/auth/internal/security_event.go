@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// Newest events shown to users about their own account
+	userSecurityEventLimit = 100
+	// Newest events returned by the admin endpoint
+	adminSecurityEventLimit = 500
+	// Events older than this are removed, they hold IP addresses
+	securityEventRetention = 180 * 24 * time.Hour
+
+	maxUserAgentLength   = 255
+	maxRequestIDLength   = 64
+	maxEventDetailLength = 64
+)
+
+// truncateString shortens s to at most n bytes without splitting a character
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// requestID returns the ID the RequestID middleware gave the request
+func requestID(ctx echo.Context) string {
+	id := ctx.Response().Header().Get(echo.HeaderXRequestID)
+	if id == "" {
+		id = ctx.Request().Header.Get(echo.HeaderXRequestID)
+	}
+	return id
+}
+
+// newSecurityEvent describes an event caused by the request in ctx. userID
+// is uuid.Nil if the event is not tied to a known account.
+func newSecurityEvent(ctx echo.Context, userID uuid.UUID, eventType db.SecurityEventType, detail string) *db.SecurityEvent {
+	return &db.SecurityEvent{
+		UserID:    userID,
+		Type:      eventType,
+		Detail:    truncateString(detail, maxEventDetailLength),
+		IP:        ctx.RealIP(),
+		UserAgent: truncateString(ctx.Request().UserAgent(), maxUserAgentLength),
+		RequestID: truncateString(requestID(ctx), maxRequestIDLength),
+	}
+}
+
+// recordSecurityEvent stores an event caused by the request in ctx. Failing
+// to store it is logged but does not fail the request.
+func (a *AuthAPI) recordSecurityEvent(ctx echo.Context, userID uuid.UUID, eventType db.SecurityEventType, detail string) {
+	event := newSecurityEvent(ctx, userID, eventType, detail)
+	err := a.dbHandler.Save(event).Error
+	if err != nil {
+		efanlog.GetLogger().Errorf("Failed to record security event '%s' for %s: %s", eventType, userID, err)
+	}
+}
+
+// PruneSecurityEvents removes events past the retention period. Returns the
+// number of events removed.
+func PruneSecurityEvents(dbHandler *gorm.DB) (int64, error) {
+	res := dbHandler.Unscoped().Where("created_at < ?", time.Now().Add(-securityEventRetention)).Delete(db.SecurityEvent{})
+	return res.RowsAffected, res.Error
+}
+
+func toAPISecurityEvent(event *db.SecurityEvent) auth.SecurityEvent {
+	result := auth.SecurityEvent{
+		Type:      string(event.Type),
+		Ip:        event.IP,
+		UserAgent: event.UserAgent,
+		RequestId: event.RequestID,
+		CreatedAt: event.CreatedAt,
+	}
+	if event.Detail != "" {
+		detail := event.Detail
+		result.Detail = &detail
+	}
+	return result
+}
+
+// Listactivity lists the recent security events of the logged in user so
+// they can spot logins they do not recognize
+func (a *AuthAPI) Listactivity(ctx echo.Context) error {
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	var events []db.SecurityEvent
+	err = a.dbHandler.Where("user_id = ?", account.ID).Order("created_at desc").
+		Limit(userSecurityEventLimit).Find(&events).Error
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	result := make([]auth.SecurityEvent, len(events))
+	for i := range events {
+		result[i] = toAPISecurityEvent(&events[i])
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// ListSecurityEvents lists security events of an account, from an IP or
+// both. Only available to admins.
+func (a *AuthAPI) ListSecurityEvents(ctx echo.Context, params auth.ListSecurityEventsParams) error {
+	ip := ""
+	if params.Ip != nil {
+		ip = strings.TrimSpace(*params.Ip)
+	}
+	if params.Username == nil && ip == "" {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Username or IP required")
+	}
+
+	query, ok := a.filterByUsername(a.dbHandler, params.Username)
+	if !ok {
+		return sendAuthAPIError(ctx, http.StatusNotFound, "Account not found")
+	}
+	if ip != "" {
+		query = query.Where("ip = ?", ip)
+	}
+
+	var events []db.SecurityEvent
+	err := query.Order("created_at desc").Limit(adminSecurityEventLimit).Find(&events).Error
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	var ids []uuid.UUID
+	for _, event := range events {
+		ids = append(ids, event.UserID)
+	}
+	usernames, err := a.usernamesByID(ids)
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	result := make([]auth.SecurityEvent, len(events))
+	for i := range events {
+		result[i] = toAPISecurityEvent(&events[i])
+		result[i].Username = optionalUsername(usernames, events[i].UserID)
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/labstack/echo/v4"
+	uuid "github.com/satori/go.uuid"
+)
+
+func TestTruncateString(t *testing.T) {
+	cases := []struct {
+		input string
+		n     int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"exactly", 7, "exactly"},
+		{"truncated", 5, "trunc"},
+		// 'ö' is two bytes and must not be split
+		{"aö", 2, "a"},
+		{"", 3, ""},
+	}
+	for _, c := range cases {
+		if got := truncateString(c.input, c.n); got != c.want {
+			t.Errorf("Truncating '%s' to %d gave '%s', wanted '%s'", c.input, c.n, got, c.want)
+		}
+	}
+}
+
+func TestNewSecurityEvent(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/auth", nil)
+	req.Header.Set("User-Agent", strings.Repeat("x", 300))
+	req.Header.Set(echo.HeaderXRealIP, "203.0.113.7")
+	res := httptest.NewRecorder()
+	ctx := e.NewContext(req, res)
+	ctx.Response().Header().Set(echo.HeaderXRequestID, "request-1")
+
+	userID := uuid.NewV4()
+	event := newSecurityEvent(ctx, userID, db.LoginSucceeded, "webauthn")
+	if !uuid.Equal(event.UserID, userID) || event.Type != db.LoginSucceeded || event.Detail != "webauthn" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+	if event.IP != "203.0.113.7" {
+		t.Errorf("Expected IP from the request, got '%s'", event.IP)
+	}
+	if event.RequestID != "request-1" {
+		t.Errorf("Expected request ID set by the middleware, got '%s'", event.RequestID)
+	}
+	if len(event.UserAgent) != maxUserAgentLength {
+		t.Errorf("User agent should be cut to %d characters, got %d", maxUserAgentLength, len(event.UserAgent))
+	}
+}
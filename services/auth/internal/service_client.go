@@ -0,0 +1,229 @@
+package internal
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	authlib "github.com/esportsdrafts/esportsdrafts/libs/authlib"
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// Services ask for a new token when it runs out, no refresh tokens
+	serviceTokenTimeout = 15 * time.Minute
+
+	serviceClientIDPrefix    = "svc_"
+	serviceClientIDBytes     = 8
+	serviceClientSecretBytes = 32
+	// Same as for API keys, secrets are random so no slow hash is needed
+	serviceClientLastUsedResolution = time.Minute
+)
+
+var (
+	// Names end up in the role, e.g. 'service:notifications'
+	serviceClientNameRegex = regexp.MustCompile(`^[a-z][a-z0-9-]{1,31}$`)
+	// Scopes are defined by the services that check them, e.g. 'accounts:read'
+	serviceScopeRegex = regexp.MustCompile(`^[a-z_]+:[a-z_]+$`)
+)
+
+func toAPIServiceClient(client *db.ServiceClient) auth.ServiceClient {
+	return auth.ServiceClient{
+		Id:         client.ID.String(),
+		Name:       client.Name,
+		ClientId:   client.ClientID,
+		Scopes:     splitAPIKeyScopes(client.Scopes),
+		CreatedAt:  client.CreatedAt,
+		LastUsedAt: client.LastUsedAt,
+	}
+}
+
+// requestedServiceScopes returns the scopes a client asked for in the space
+// separated scope parameter. Returns all scopes of the client if none were
+// asked for, and false if it asked for scopes it does not have.
+func requestedServiceScopes(client *db.ServiceClient, scope *string) ([]string, bool) {
+	granted := splitAPIKeyScopes(client.Scopes)
+	if scope == nil || strings.TrimSpace(*scope) == "" {
+		return granted, true
+	}
+
+	requested := strings.Fields(*scope)
+	for _, s := range requested {
+		found := false
+		for _, g := range granted {
+			if s == g {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return requested, true
+}
+
+// authWithClientCredentials handles the 'client_credentials' claim, giving a
+// registered service a token with its service role and scopes
+func (a *AuthAPI) authWithClientCredentials(ctx echo.Context, claim *auth.AuthClaim) error {
+	logger := efanlog.GetLogger()
+
+	if claim.ClientId == nil || claim.ClientSecret == nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Client ID and secret required")
+	}
+
+	var client db.ServiceClient
+	err := a.dbHandler.Where("client_id = ?", *claim.ClientId).First(&client).Error
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid client credentials")
+	}
+
+	hash := hashAPIKey(*claim.ClientSecret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(client.SecretHash)) != 1 {
+		logger.Warnf("Invalid secret for service client '%s'", client.Name)
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "Invalid client credentials")
+	}
+
+	scopes, ok := requestedServiceScopes(&client, claim.Scope)
+	if !ok {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Requested scope not granted to client")
+	}
+
+	claims := &authlib.JWTClaims{
+		Username: client.Name,
+		UserID:   client.ClientID,
+		Roles:    []string{authlib.ServiceRole(client.Name)},
+		Scopes:   scopes,
+	}
+
+	tokenString, expirationTime, err := authlib.GenerateAuthTokenWithKeys(claims, serviceTokenTimeout, a.keys)
+	if err != nil {
+		logger.Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	now := time.Now()
+	if client.LastUsedAt == nil || now.Sub(*client.LastUsedAt) > serviceClientLastUsedResolution {
+		err = a.dbHandler.Model(&client).UpdateColumn("last_used_at", now).Error
+		if err != nil {
+			logger.Errorf("Failed to update last use of service client '%s': %s", client.Name, err)
+		}
+	}
+
+	return ctx.JSON(http.StatusOK, auth.JWT{
+		AccessToken: tokenString,
+		ExpiresIn:   int(expirationTime.Unix()),
+	})
+}
+
+// ListServiceClients lists the registered service clients. Only available to
+// admins.
+func (a *AuthAPI) ListServiceClients(ctx echo.Context) error {
+	var clients []db.ServiceClient
+	err := a.dbHandler.Order("name").Find(&clients).Error
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	result := make([]auth.ServiceClient, len(clients))
+	for i := range clients {
+		result[i] = toAPIServiceClient(&clients[i])
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// CreateServiceClient registers a service client. The secret is in the
+// response and can not be retrieved again. Only available to admins.
+func (a *AuthAPI) CreateServiceClient(ctx echo.Context) error {
+	var request auth.NewServiceClient
+	err := ctx.Bind(&request)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Invalid request format")
+	}
+
+	if !serviceClientNameRegex.MatchString(request.Name) {
+		return sendAuthAPIError(ctx, http.StatusBadRequest,
+			"Name must be 2 to 32 lowercase letters, digits or dashes, starting with a letter")
+	}
+
+	if len(request.Scopes) == 0 {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "At least one scope is required")
+	}
+	scopes := map[string]bool{}
+	for _, scope := range request.Scopes {
+		if !serviceScopeRegex.MatchString(scope) {
+			return sendAuthAPIError(ctx, http.StatusBadRequest, fmt.Sprintf("Invalid scope '%s'", scope))
+		}
+		scopes[scope] = true
+	}
+	sortedScopes := make([]string, 0, len(scopes))
+	for scope := range scopes {
+		sortedScopes = append(sortedScopes, scope)
+	}
+	sort.Strings(sortedScopes)
+
+	var count int
+	err = a.dbHandler.Model(&db.ServiceClient{}).Where("name = ?", request.Name).Count(&count).Error
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	if count > 0 {
+		return sendAuthAPIError(ctx, http.StatusBadRequest, "Service client name already taken")
+	}
+
+	clientID, err := generateRandomBytes(serviceClientIDBytes)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	secret, err := generateRandomBytes(serviceClientSecretBytes)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+
+	client := &db.ServiceClient{
+		Name:       request.Name,
+		ClientID:   serviceClientIDPrefix + hex.EncodeToString(clientID),
+		SecretHash: hashAPIKey(encodedSecret),
+		Scopes:     strings.Join(sortedScopes, ","),
+	}
+	err = a.dbHandler.Save(client).Error
+	if err != nil {
+		efanlog.GetLogger().Errorf("Failed to store service client '%s': %s", request.Name, err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	efanlog.GetLogger().Infof("Service client '%s' registered by admin", client.Name)
+	return ctx.JSON(http.StatusOK, auth.CreatedServiceClient{
+		Client:       toAPIServiceClient(client),
+		ClientSecret: encodedSecret,
+	})
+}
+
+// DeleteServiceClient removes a service client. Tokens already issued stay
+// valid until they expire. Only available to admins.
+func (a *AuthAPI) DeleteServiceClient(ctx echo.Context, id string) error {
+	// Hard delete so the name can be registered again
+	res := a.dbHandler.Unscoped().Where("id = ?", id).Delete(db.ServiceClient{})
+	if res.Error != nil {
+		efanlog.GetLogger().Info(res.Error)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+	if res.RowsAffected == 0 {
+		return sendAuthAPIError(ctx, http.StatusNotFound, "Service client not found")
+	}
+
+	efanlog.GetLogger().Infof("Service client %s removed by admin", id)
+	return ctx.JSON(http.StatusOK, map[string]int{})
+}
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+)
+
+func TestRequestedServiceScopes(t *testing.T) {
+	client := &db.ServiceClient{Scopes: "accounts:read,emails:send"}
+	cases := []struct {
+		scope string
+		want  string
+		ok    bool
+	}{
+		{"", "accounts:read emails:send", true},
+		{"  ", "accounts:read emails:send", true},
+		{"emails:send", "emails:send", true},
+		{"emails:send  accounts:read", "emails:send accounts:read", true},
+		{"accounts:write", "", false},
+		{"emails:send accounts:write", "", false},
+	}
+	for _, c := range cases {
+		scope := c.scope
+		scopes, ok := requestedServiceScopes(client, &scope)
+		if ok != c.ok || strings.Join(scopes, " ") != c.want {
+			t.Errorf("Scope '%s' gave %v (%t), wanted '%s' (%t)", c.scope, scopes, ok, c.want, c.ok)
+		}
+	}
+
+	if scopes, ok := requestedServiceScopes(client, nil); !ok || len(scopes) != 2 {
+		t.Errorf("No scope should grant all scopes of the client, got %v", scopes)
+	}
+}
+
+func TestServiceClientNames(t *testing.T) {
+	valid := []string{"notifications", "lineup-optimizer", "s3"}
+	for _, name := range valid {
+		if !serviceClientNameRegex.MatchString(name) {
+			t.Errorf("Name '%s' should be valid", name)
+		}
+	}
+
+	invalid := []string{"", "a", "Notifications", "3d", "service:x", "with space", strings.Repeat("a", 33)}
+	for _, name := range invalid {
+		if serviceClientNameRegex.MatchString(name) {
+			t.Errorf("Name '%s' should be invalid", name)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	authlib "github.com/esportsdrafts/esportsdrafts/libs/authlib"
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	auth "github.com/esportsdrafts/esportsdrafts/services/auth/api"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// Sessions are marked as seen at most this often, not on every request
+	sessionSeenInterval = time.Minute
+)
+
+var errSessionNotFound = errors.New("session not found")
+
+// newSession describes a login of a user from the request in ctx, tied to
+// the refresh token family familyID
+func newSession(ctx echo.Context, userID uuid.UUID, familyID uuid.UUID) *db.Session {
+	now := time.Now()
+	return &db.Session{
+		UserID:     userID,
+		FamilyID:   familyID,
+		IP:         ctx.RealIP(),
+		UserAgent:  truncateString(ctx.Request().UserAgent(), maxUserAgentLength),
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(refreshTokenTimeout),
+	}
+}
+
+// startSession records a new login of account and issues the first refresh
+// token of it
+func (a *AuthAPI) startSession(ctx echo.Context, account *db.Account) (*db.Session, string, error) {
+	session := newSession(ctx, account.ID, uuid.NewV4())
+	var refreshToken string
+	err := db.DoInTransaction(func(tx *gorm.DB) error {
+		err := tx.Save(session).Error
+		if err != nil {
+			return err
+		}
+		refreshToken, err = issueRefreshToken(tx, account.ID, session.FamilyID)
+		return err
+	}, a.dbHandler)
+	if err != nil {
+		return nil, "", err
+	}
+	return session, refreshToken, nil
+}
+
+// continueSession looks up the session of a rotated refresh token family and
+// extends it as long as the new refresh token is valid. Families issued
+// before sessions were tracked get a session on their first refresh.
+func (a *AuthAPI) continueSession(ctx echo.Context, userID uuid.UUID, familyID uuid.UUID) (*db.Session, error) {
+	var session db.Session
+	err := a.dbHandler.Where("family_id = ?", familyID).First(&session).Error
+	if gorm.IsRecordNotFoundError(err) {
+		legacy := newSession(ctx, userID, familyID)
+		return legacy, a.dbHandler.Save(legacy).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Revoking the session revokes its refresh tokens, so this only happens
+	// if the two were revoked separately
+	if session.RevokedAt != nil {
+		return nil, errSessionNotFound
+	}
+
+	now := time.Now()
+	err = a.dbHandler.Model(&session).Updates(map[string]interface{}{
+		"ip":           ctx.RealIP(),
+		"user_agent":   truncateString(ctx.Request().UserAgent(), maxUserAgentLength),
+		"last_seen_at": now,
+		"expires_at":   now.Add(refreshTokenTimeout),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ValidateSession implements authlib.SessionValidator. Returns false if the
+// session is unknown, revoked or expired.
+func (a *AuthAPI) ValidateSession(sessionID string) (bool, error) {
+	id, err := uuid.FromString(sessionID)
+	if err != nil {
+		return false, nil
+	}
+
+	var session db.Session
+	err = a.dbHandler.Where("id = ?", id).First(&session).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	if session.RevokedAt != nil || session.ExpiresAt.Before(now) {
+		return false, nil
+	}
+
+	if now.Sub(session.LastSeenAt) > sessionSeenInterval {
+		err = a.dbHandler.Model(&session).UpdateColumn("last_seen_at", now).Error
+		if err != nil {
+			efanlog.GetLogger().Errorf("Failed to mark session %s as seen: %s", session.ID, err)
+		}
+	}
+	return true, nil
+}
+
+// revokeSession ends a session. Its refresh tokens and latest access token
+// are revoked, other access tokens of it are rejected by the session check.
+func (a *AuthAPI) revokeSession(session *db.Session) error {
+	err := revokeRefreshTokenFamily(a.dbHandler, session.FamilyID)
+	if err != nil {
+		return err
+	}
+
+	if session.TokenID != "" {
+		err = a.revocationStore.Revoke(session.TokenID, time.Now().Add(authlib.DefaultCookiePayloadTimeout))
+		if err != nil {
+			efanlog.GetLogger().Errorf("Failed to revoke token of session %s: %s", session.ID, err)
+		}
+	}
+	return nil
+}
+
+// revokeTokenSession ends the session a token was issued for, if it has one
+func (a *AuthAPI) revokeTokenSession(claims *authlib.JWTClaims) error {
+	if claims.SessionID == "" {
+		return nil
+	}
+
+	var session db.Session
+	err := a.dbHandler.Where("id = ? AND user_id = ?", claims.SessionID, claims.UserID).First(&session).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return a.revokeSession(&session)
+}
+
+// revokeUserAccessTokens revokes the latest access token of every session of
+// a user right away, for services that only check the revocation store.
+// Failures are logged, the sessions are ended separately.
+func (a *AuthAPI) revokeUserAccessTokens(userID uuid.UUID) {
+	var sessions []db.Session
+	err := a.dbHandler.Where("user_id = ? AND token_id <> ''", userID).Find(&sessions).Error
+	if err != nil {
+		efanlog.GetLogger().Errorf("Failed to look up sessions of %s: %s", userID, err)
+		return
+	}
+
+	expiresAt := time.Now().Add(authlib.DefaultCookiePayloadTimeout)
+	for _, session := range sessions {
+		err = a.revocationStore.Revoke(session.TokenID, expiresAt)
+		if err != nil {
+			efanlog.GetLogger().Errorf("Failed to revoke token of session %s: %s", session.ID, err)
+		}
+	}
+}
+
+// activeSessions returns the sessions of a user that have not been revoked
+// or expired, most recently seen first
+func (a *AuthAPI) activeSessions(userID uuid.UUID) ([]db.Session, error) {
+	var sessions []db.Session
+	err := a.dbHandler.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at desc").Find(&sessions).Error
+	return sessions, err
+}
+
+// currentSessionID returns the session of the token in the request
+func currentSessionID(ctx echo.Context) string {
+	claims, ok := authlib.ClaimsFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return claims.SessionID
+}
+
+// PruneSessions removes revoked and expired sessions. Returns the number of
+// sessions removed.
+func PruneSessions(dbHandler *gorm.DB) (int64, error) {
+	res := dbHandler.Unscoped().Where("revoked_at IS NOT NULL OR expires_at < ?", time.Now()).Delete(db.Session{})
+	return res.RowsAffected, res.Error
+}
+
+func toAPISession(session *db.Session, currentID string) auth.Session {
+	return auth.Session{
+		Id:         session.ID.String(),
+		Ip:         session.IP,
+		UserAgent:  session.UserAgent,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		Current:    session.ID.String() == currentID,
+	}
+}
+
+// Listsessions lists the active sessions of the logged in user
+func (a *AuthAPI) Listsessions(ctx echo.Context) error {
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	sessions, err := a.activeSessions(account.ID)
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	currentID := currentSessionID(ctx)
+	result := make([]auth.Session, len(sessions))
+	for i := range sessions {
+		result[i] = toAPISession(&sessions[i], currentID)
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// Revokesession signs out a session of the logged in user. Signing out the
+// current session also clears the auth cookies, like Logout.
+func (a *AuthAPI) Revokesession(ctx echo.Context, id string) error {
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	sessionID, err := uuid.FromString(id)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusNotFound, "Session not found")
+	}
+
+	var session db.Session
+	err = a.dbHandler.Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?",
+		sessionID, account.ID, time.Now()).First(&session).Error
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusNotFound, "Session not found")
+	}
+
+	err = a.revokeSession(&session)
+	if err != nil {
+		efanlog.GetLogger().Errorf("Failed to revoke session of '%s': %s", account.Username, err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	if session.ID.String() == currentSessionID(ctx) {
+		authlib.ClearAuthCookies(ctx)
+		writeRefreshTokenCookie(ctx, "", -time.Hour)
+	}
+	return ctx.JSON(http.StatusOK, map[string]int{})
+}
+
+// Revokeothersessions signs out every session of the logged in user except
+// the one making the request
+func (a *AuthAPI) Revokeothersessions(ctx echo.Context) error {
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	sessions, err := a.activeSessions(account.ID)
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	currentID := currentSessionID(ctx)
+	revoked := 0
+	for i := range sessions {
+		if sessions[i].ID.String() == currentID {
+			continue
+		}
+		err = a.revokeSession(&sessions[i])
+		if err != nil {
+			efanlog.GetLogger().Errorf("Failed to revoke session of '%s': %s", account.Username, err)
+			return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+		}
+		revoked++
+	}
+
+	efanlog.GetLogger().Infof("User '%s' signed out %d other sessions", account.Username, revoked)
+	return ctx.JSON(http.StatusOK, map[string]int{})
+}
@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authlib "github.com/esportsdrafts/esportsdrafts/libs/authlib"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/labstack/echo/v4"
+	uuid "github.com/satori/go.uuid"
+)
+
+func TestCurrentSession(t *testing.T) {
+	e := echo.New()
+	ctx := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	if currentSessionID(ctx) != "" {
+		t.Errorf("Request without a token has no session")
+	}
+
+	current := &db.Session{}
+	current.ID = uuid.NewV4()
+	other := &db.Session{}
+	other.ID = uuid.NewV4()
+
+	ctx.Set("user", &authlib.JWTClaims{SessionID: current.ID.String()})
+	currentID := currentSessionID(ctx)
+	if currentID != current.ID.String() {
+		t.Errorf("Expected session %s, got '%s'", current.ID, currentID)
+	}
+
+	if !toAPISession(current, currentID).Current {
+		t.Errorf("Session of the token should be marked current")
+	}
+	if toAPISession(other, currentID).Current {
+		t.Errorf("Other sessions should not be marked current")
+	}
+	if toAPISession(current, "").Current {
+		t.Errorf("No session should be current without a token")
+	}
+}
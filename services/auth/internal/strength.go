@@ -0,0 +1,767 @@
+package internal
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Password strength estimation modeled after zxcvbn. The password is split
+// into the sequence of patterns (dictionary words, keyboard walks, repeats,
+// sequences, years and brute-forced characters) that needs the fewest guesses
+// to crack, and the number of guesses is mapped to a score.
+
+const (
+	// Lowest score accepted for new passwords
+	minPasswordScore = 3
+
+	bruteforceCardinality   = 10.0
+	minGuessesSingleChar    = 10.0
+	minGuessesMultiChar     = 50.0
+	minYearSpace            = 20
+	maxDictionaryWordLength = 20
+
+	// Number of keys on a QWERTY keyboard, used as spatial walk starting points
+	qwertyStartingPositions = 47
+)
+
+// PasswordStrength is the estimated strength of a password with feedback on
+// how to improve it
+type PasswordStrength struct {
+	// Score from 0 (too guessable) to 4 (very unguessable)
+	Score int
+	// GuessesLog10 is the estimated number of guesses needed, as log10
+	GuessesLog10 float64
+	// Warning explains what makes the password weak, empty if nothing stands out
+	Warning string
+	// Suggestions to make the password stronger
+	Suggestions []string
+}
+
+// strengthMatch is a part of the password matching a known pattern. I and J
+// are inclusive rune indexes.
+type strengthMatch struct {
+	pattern string
+	i, j    int
+	token   string
+	guesses float64
+
+	// Details used for feedback
+	dictionary string
+	rank       int
+	reversed   bool
+	l33t       bool
+	turns      int
+	baseLength int
+}
+
+var (
+	rankedDictionaries = map[string]map[string]int{
+		"passwords": buildRankedDictionary(commonPasswords),
+		"words":     buildRankedDictionary(commonWords),
+		"site":      buildRankedDictionary(siteWords),
+	}
+	qwertyPositions, qwertyAverageDegree = buildKeyboardGraph(qwertyRows)
+)
+
+func buildRankedDictionary(words []string) map[string]int {
+	ranked := map[string]int{}
+	for i, word := range words {
+		if _, ok := ranked[word]; !ok {
+			ranked[word] = i + 1
+		}
+	}
+	return ranked
+}
+
+type keyPosition struct {
+	row int
+	// In half key widths, rows are staggered by half a key
+	x int
+}
+
+func buildKeyboardGraph(rows []string) (map[rune]keyPosition, float64) {
+	// Offset of the first key in each row, in half key widths
+	offsets := []int{0, 3, 4, 5}
+
+	positions := map[rune]keyPosition{}
+	for row, keys := range rows {
+		for col, key := range keys {
+			positions[key] = keyPosition{row: row, x: 2*col + offsets[row]}
+		}
+	}
+
+	neighbors := 0
+	for a, posA := range positions {
+		for b, posB := range positions {
+			if a != b && keyDirection(posA, posB) >= 0 {
+				neighbors++
+			}
+		}
+	}
+	return positions, float64(neighbors) / float64(len(positions))
+}
+
+// keyDirection returns the direction from a to b if they are adjacent keys,
+// otherwise -1
+func keyDirection(a keyPosition, b keyPosition) int {
+	dRow := b.row - a.row
+	dx := b.x - a.x
+	switch {
+	case dRow == 0 && dx == -2:
+		return 0
+	case dRow == 0 && dx == 2:
+		return 1
+	case dRow == -1 && dx == -1:
+		return 2
+	case dRow == -1 && dx == 1:
+		return 3
+	case dRow == 1 && dx == -1:
+		return 4
+	case dRow == 1 && dx == 1:
+		return 5
+	}
+	return -1
+}
+
+// EstimatePasswordStrength scores password. UserInputs are strings that are
+// easy to guess for someone targeting the user, like their username or email.
+func EstimatePasswordStrength(password string, userInputs []string) PasswordStrength {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return PasswordStrength{
+			Suggestions: []string{
+				"Use a few words, avoid common phrases",
+				"No need for symbols, digits, or uppercase letters",
+			},
+		}
+	}
+
+	sequence, guessesLog10 := mostGuessableSequence(runes, omnimatch(runes, userInputs))
+	score := scoreFromGuesses(guessesLog10)
+
+	strength := PasswordStrength{
+		Score:        score,
+		GuessesLog10: guessesLog10,
+	}
+	if score < minPasswordScore {
+		strength.Warning, strength.Suggestions = strengthFeedback(sequence, len(runes))
+	}
+	return strength
+}
+
+func scoreFromGuesses(guessesLog10 float64) int {
+	switch {
+	case guessesLog10 < 3:
+		return 0
+	case guessesLog10 < 6:
+		return 1
+	case guessesLog10 < 8:
+		return 2
+	case guessesLog10 < 10:
+		return 3
+	}
+	return 4
+}
+
+// omnimatch finds every pattern match in the password, overlapping matches
+// included
+func omnimatch(runes []rune, userInputs []string) []*strengthMatch {
+	dictionaries := map[string]map[string]int{}
+	for name, dictionary := range rankedDictionaries {
+		dictionaries[name] = dictionary
+	}
+	dictionaries["user_inputs"] = buildRankedDictionary(splitUserInputs(userInputs))
+
+	var matches []*strengthMatch
+	matches = append(matches, dictionaryMatches(runes, dictionaries)...)
+	matches = append(matches, reverseDictionaryMatches(runes, dictionaries)...)
+	matches = append(matches, l33tMatches(runes, dictionaries)...)
+	matches = append(matches, spatialMatches(runes)...)
+	matches = append(matches, repeatMatches(runes, userInputs)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, yearMatches(runes)...)
+
+	for _, match := range matches {
+		minGuesses := minGuessesMultiChar
+		if match.j == match.i {
+			minGuesses = minGuessesSingleChar
+		}
+		match.guesses = math.Max(match.guesses, minGuesses)
+	}
+	return matches
+}
+
+// splitUserInputs lowercases user inputs and splits emails into their parts
+func splitUserInputs(userInputs []string) []string {
+	var words []string
+	for _, input := range userInputs {
+		input = strings.ToLower(input)
+		words = append(words, input)
+
+		parts := strings.FieldsFunc(input, func(r rune) bool {
+			return r == '@' || r == '.' || r == '_' || r == '-' || r == '+'
+		})
+		for _, part := range parts {
+			if len([]rune(part)) >= 3 {
+				words = append(words, part)
+			}
+		}
+	}
+	return words
+}
+
+func dictionaryMatches(runes []rune, dictionaries map[string]map[string]int) []*strengthMatch {
+	lower := []rune(strings.ToLower(string(runes)))
+
+	var matches []*strengthMatch
+	for i := range lower {
+		for j := i + 2; j < len(lower) && j-i < maxDictionaryWordLength; j++ {
+			word := string(lower[i : j+1])
+			for name, dictionary := range dictionaries {
+				rank, ok := dictionary[word]
+				if !ok {
+					continue
+				}
+				token := string(runes[i : j+1])
+				matches = append(matches, &strengthMatch{
+					pattern:    "dictionary",
+					i:          i,
+					j:          j,
+					token:      token,
+					guesses:    float64(rank) * uppercaseVariations(token),
+					dictionary: name,
+					rank:       rank,
+				})
+			}
+		}
+	}
+	return matches
+}
+
+func reverseDictionaryMatches(runes []rune, dictionaries map[string]map[string]int) []*strengthMatch {
+	n := len(runes)
+	reversed := make([]rune, n)
+	for i, r := range runes {
+		reversed[n-1-i] = r
+	}
+
+	matches := dictionaryMatches(reversed, dictionaries)
+	for _, match := range matches {
+		match.i, match.j = n-1-match.j, n-1-match.i
+		match.token = string(runes[match.i : match.j+1])
+		match.reversed = true
+		match.guesses *= 2
+	}
+	return matches
+}
+
+// l33tMatches finds dictionary words with common substitutions like 'p4ssw0rd'
+func l33tMatches(runes []rune, dictionaries map[string]map[string]int) []*strengthMatch {
+	lower := []rune(strings.ToLower(string(runes)))
+
+	// Try the first and the second reading of ambiguous substitutions
+	var matches []*strengthMatch
+	seen := map[[2]int]bool{}
+	for reading := 0; reading < 2; reading++ {
+		unleeted := make([]rune, len(lower))
+		for i, r := range lower {
+			unleeted[i] = r
+			if subs, ok := l33tTable[r]; ok {
+				unleeted[i] = subs[len(subs)-1]
+				if reading < len(subs) {
+					unleeted[i] = subs[reading]
+				}
+			}
+		}
+
+		for _, match := range dictionaryMatches(unleeted, dictionaries) {
+			original := lower[match.i : match.j+1]
+			if string(original) == string(unleeted[match.i:match.j+1]) || seen[[2]int{match.i, match.j}] {
+				continue
+			}
+			seen[[2]int{match.i, match.j}] = true
+
+			match.token = string(runes[match.i : match.j+1])
+			match.l33t = true
+			match.guesses = float64(match.rank) * uppercaseVariations(match.token) *
+				l33tVariations(original, unleeted[match.i:match.j+1])
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// uppercaseVariations estimates the extra guesses needed for capitalization
+func uppercaseVariations(token string) float64 {
+	upper, lower := 0, 0
+	for _, r := range token {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+
+	if upper == 0 {
+		return 1
+	}
+
+	// Capitalized, all caps and last letter upper are the common cases
+	runes := []rune(token)
+	if lower == 0 ||
+		(upper == 1 && (unicode.IsUpper(runes[0]) || unicode.IsUpper(runes[len(runes)-1]))) {
+		return 2
+	}
+
+	variations := 0.0
+	for i := 1; i <= upper && i <= lower; i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return variations
+}
+
+// l33tVariations estimates the extra guesses needed for substitutions
+func l33tVariations(original []rune, unleeted []rune) float64 {
+	subbed := map[rune]rune{}
+	for i := range original {
+		if original[i] != unleeted[i] {
+			subbed[original[i]] = unleeted[i]
+		}
+	}
+
+	variations := 1.0
+	for sub, plain := range subbed {
+		s, u := 0, 0
+		for _, r := range original {
+			if r == sub {
+				s++
+			} else if r == plain {
+				u++
+			}
+		}
+
+		if u == 0 {
+			variations *= 2
+			continue
+		}
+		possibilities := 0.0
+		for i := 1; i <= s && i <= u; i++ {
+			possibilities += binomial(s+u, i)
+		}
+		variations *= possibilities
+	}
+	return variations
+}
+
+// keyboardKey returns the unshifted key for r and whether shift is needed
+func keyboardKey(r rune) (rune, bool) {
+	if unshifted, ok := qwertyShifted[r]; ok {
+		return unshifted, true
+	}
+	if unicode.IsUpper(r) {
+		return unicode.ToLower(r), true
+	}
+	return r, false
+}
+
+// spatialMatches finds walks over adjacent keys like 'qwerty' or 'zaq1'
+func spatialMatches(runes []rune) []*strengthMatch {
+	var matches []*strengthMatch
+
+	i := 0
+	for i < len(runes)-2 {
+		j := i
+		turns := 0
+		shifted := 0
+		lastDirection := -1
+
+		key, shift := keyboardKey(runes[i])
+		if shift {
+			shifted++
+		}
+
+		for j+1 < len(runes) {
+			next, nextShift := keyboardKey(runes[j+1])
+			posA, okA := qwertyPositions[key]
+			posB, okB := qwertyPositions[next]
+			if !okA || !okB {
+				break
+			}
+
+			direction := keyDirection(posA, posB)
+			if direction < 0 {
+				break
+			}
+			if direction != lastDirection {
+				turns++
+				lastDirection = direction
+			}
+			if nextShift {
+				shifted++
+			}
+			key = next
+			j++
+		}
+
+		if j-i+1 >= 3 {
+			matches = append(matches, &strengthMatch{
+				pattern: "spatial",
+				i:       i,
+				j:       j,
+				token:   string(runes[i : j+1]),
+				guesses: spatialGuesses(j-i+1, turns, shifted),
+				turns:   turns,
+			})
+			i = j
+		} else {
+			i++
+		}
+	}
+	return matches
+}
+
+func spatialGuesses(length int, turns int, shifted int) float64 {
+	guesses := 0.0
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= turns && j <= i-1; j++ {
+			guesses += binomial(i-1, j-1) * qwertyStartingPositions * math.Pow(qwertyAverageDegree, float64(j))
+		}
+	}
+
+	if shifted > 0 {
+		unshifted := length - shifted
+		if unshifted == 0 {
+			guesses *= 2
+		} else {
+			variations := 0.0
+			for i := 1; i <= shifted && i <= unshifted; i++ {
+				variations += binomial(length, i)
+			}
+			guesses *= variations
+		}
+	}
+	return guesses
+}
+
+// repeatMatches finds repeated characters or substrings like 'aaa' or
+// 'abcabc'
+func repeatMatches(runes []rune, userInputs []string) []*strengthMatch {
+	var matches []*strengthMatch
+	n := len(runes)
+
+	for i := 0; i < n-2; i++ {
+		bestLength, bestCount := 0, 0
+		for length := 1; i+2*length <= n; length++ {
+			count := 1
+			for i+(count+1)*length <= n &&
+				string(runes[i+count*length:i+(count+1)*length]) == string(runes[i:i+length]) {
+				count++
+			}
+
+			// Prefer the longest span, then the shortest base
+			if count >= 2 && count*length >= 3 && count*length > bestCount*bestLength {
+				bestLength, bestCount = length, count
+			}
+		}
+
+		if bestCount == 0 {
+			continue
+		}
+
+		base := runes[i : i+bestLength]
+		_, baseGuessesLog10 := mostGuessableSequence(base, omnimatch(base, userInputs))
+		matches = append(matches, &strengthMatch{
+			pattern:    "repeat",
+			i:          i,
+			j:          i + bestCount*bestLength - 1,
+			token:      string(runes[i : i+bestCount*bestLength]),
+			guesses:    math.Pow(10, baseGuessesLog10) * float64(bestCount),
+			baseLength: bestLength,
+		})
+	}
+	return matches
+}
+
+func runeClass(r rune) int {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return 1
+	case r >= 'A' && r <= 'Z':
+		return 2
+	case r >= '0' && r <= '9':
+		return 3
+	}
+	return 0
+}
+
+// sequenceMatches finds runs like 'abcd', '9876' or 'XYZ'
+func sequenceMatches(runes []rune) []*strengthMatch {
+	var matches []*strengthMatch
+
+	i := 0
+	for i < len(runes)-2 {
+		class := runeClass(runes[i])
+		delta := runes[i+1] - runes[i]
+		if class == 0 || (delta != 1 && delta != -1) || runeClass(runes[i+1]) != class {
+			i++
+			continue
+		}
+
+		j := i + 1
+		for j+1 < len(runes) && runes[j+1]-runes[j] == delta && runeClass(runes[j+1]) == class {
+			j++
+		}
+
+		if j-i+1 >= 3 {
+			var base float64
+			switch {
+			case strings.ContainsRune("aAzZ019", runes[i]):
+				base = 4
+			case class == 3:
+				base = 10
+			case class == 2:
+				base = 26 * 2
+			default:
+				base = 26
+			}
+			if delta < 0 {
+				base *= 2
+			}
+
+			matches = append(matches, &strengthMatch{
+				pattern: "sequence",
+				i:       i,
+				j:       j,
+				token:   string(runes[i : j+1]),
+				guesses: base * float64(j-i+1),
+			})
+		}
+		i = j
+	}
+	return matches
+}
+
+// yearMatches finds recent years, people love adding them to passwords
+func yearMatches(runes []rune) []*strengthMatch {
+	var matches []*strengthMatch
+	currentYear := time.Now().Year()
+
+	for i := 0; i+4 <= len(runes); i++ {
+		year := 0
+		for _, r := range runes[i : i+4] {
+			if r < '0' || r > '9' {
+				year = -1
+				break
+			}
+			year = year*10 + int(r-'0')
+		}
+
+		if year < 1900 || year > currentYear+30 {
+			continue
+		}
+
+		space := year - currentYear
+		if space < 0 {
+			space = -space
+		}
+		if space < minYearSpace {
+			space = minYearSpace
+		}
+
+		matches = append(matches, &strengthMatch{
+			pattern: "year",
+			i:       i,
+			j:       i + 3,
+			token:   string(runes[i : i+4]),
+			guesses: float64(space),
+		})
+	}
+	return matches
+}
+
+// mostGuessableSequence finds the sequence of non-overlapping matches that
+// covers the password with the fewest guesses. Gaps are brute-forced. The
+// number of guesses is multiplied by the factorial of the sequence length,
+// an attacker does not know how many patterns were combined.
+func mostGuessableSequence(runes []rune, matches []*strengthMatch) ([]*strengthMatch, float64) {
+	n := len(runes)
+
+	byEnd := make([][]*strengthMatch, n)
+	for _, match := range matches {
+		byEnd[match.j] = append(byEnd[match.j], match)
+	}
+	// Brute force any substring
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			guesses := math.Pow(bruteforceCardinality, float64(j-i+1))
+			if j == i {
+				guesses = math.Max(guesses, minGuessesSingleChar+1)
+			} else {
+				guesses = math.Max(guesses, minGuessesMultiChar+1)
+			}
+			byEnd[j] = append(byEnd[j], &strengthMatch{
+				pattern: "bruteforce",
+				i:       i,
+				j:       j,
+				token:   string(runes[i : j+1]),
+				guesses: guesses,
+			})
+		}
+	}
+
+	// best[k][j] is the lowest log10 product of guesses covering runes[0..j]
+	// with k+1 matches
+	best := make([][]float64, n)
+	last := make([][]*strengthMatch, n)
+	for k := range best {
+		best[k] = make([]float64, n)
+		last[k] = make([]*strengthMatch, n)
+		for j := range best[k] {
+			best[k][j] = math.Inf(1)
+		}
+	}
+
+	for j := 0; j < n; j++ {
+		for _, match := range byEnd[j] {
+			guessesLog10 := math.Log10(match.guesses)
+			if match.i == 0 {
+				if guessesLog10 < best[0][j] {
+					best[0][j] = guessesLog10
+					last[0][j] = match
+				}
+				continue
+			}
+			for k := 1; k < n; k++ {
+				previous := best[k-1][match.i-1]
+				if math.IsInf(previous, 1) {
+					continue
+				}
+				if previous+guessesLog10 < best[k][j] {
+					best[k][j] = previous + guessesLog10
+					last[k][j] = match
+				}
+			}
+		}
+	}
+
+	bestK := 0
+	bestGuesses := math.Inf(1)
+	factorialLog10 := 0.0
+	for k := 0; k < n; k++ {
+		factorialLog10 += math.Log10(float64(k + 1))
+		if best[k][n-1]+factorialLog10 < bestGuesses {
+			bestGuesses = best[k][n-1] + factorialLog10
+			bestK = k
+		}
+	}
+
+	sequence := make([]*strengthMatch, bestK+1)
+	j := n - 1
+	for k := bestK; k >= 0; k-- {
+		sequence[k] = last[k][j]
+		j = sequence[k].i - 1
+	}
+	return sequence, bestGuesses
+}
+
+// strengthFeedback explains the longest pattern in a weak password
+func strengthFeedback(sequence []*strengthMatch, length int) (string, []string) {
+	extra := "Add another word or two. Uncommon words are better."
+
+	var longest *strengthMatch
+	for _, match := range sequence {
+		if match.pattern == "bruteforce" {
+			continue
+		}
+		if longest == nil || len([]rune(match.token)) > len([]rune(longest.token)) {
+			longest = match
+		}
+	}
+
+	if longest == nil {
+		return "", []string{extra}
+	}
+
+	warning, suggestions := matchFeedback(longest, len(sequence) == 1 && len([]rune(longest.token)) == length)
+	return warning, append([]string{extra}, suggestions...)
+}
+
+func matchFeedback(match *strengthMatch, isSoleMatch bool) (string, []string) {
+	switch match.pattern {
+	case "dictionary":
+		return dictionaryFeedback(match, isSoleMatch)
+	case "spatial":
+		warning := "Short keyboard patterns are easy to guess"
+		if match.turns == 1 {
+			warning = "Straight rows of keys are easy to guess"
+		}
+		return warning, []string{"Use a longer keyboard pattern with more turns"}
+	case "repeat":
+		warning := `Repeats like "abcabcabc" are only slightly harder to guess than "abc"`
+		if match.baseLength == 1 {
+			warning = `Repeats like "aaa" are easy to guess`
+		}
+		return warning, []string{"Avoid repeated words and characters"}
+	case "sequence":
+		return "Sequences like abc or 6543 are easy to guess", []string{"Avoid sequences"}
+	case "year":
+		return "Recent years are easy to guess", []string{
+			"Avoid recent years",
+			"Avoid years that are associated with you",
+		}
+	}
+	return "", nil
+}
+
+func dictionaryFeedback(match *strengthMatch, isSoleMatch bool) (string, []string) {
+	var warning string
+	switch match.dictionary {
+	case "passwords":
+		switch {
+		case !isSoleMatch || match.l33t || match.reversed:
+			warning = "This is similar to a commonly used password"
+		case match.rank <= 10:
+			warning = "This is a top-10 common password"
+		case match.rank <= 100:
+			warning = "This is a top-100 common password"
+		default:
+			warning = "This is a very common password"
+		}
+	case "user_inputs":
+		warning = "Passwords containing your username or email are easy to guess"
+	case "site":
+		warning = "Passwords containing the name of the site are easy to guess"
+	default:
+		if isSoleMatch {
+			warning = "A word by itself is easy to guess"
+		}
+	}
+
+	var suggestions []string
+	runes := []rune(match.token)
+	if unicode.IsUpper(runes[0]) {
+		suggestions = append(suggestions, "Capitalization doesn't help very much")
+	} else if strings.ToUpper(match.token) == match.token && strings.ToLower(match.token) != match.token {
+		suggestions = append(suggestions, "All-uppercase is almost as easy to guess as all-lowercase")
+	}
+	if match.reversed {
+		suggestions = append(suggestions, "Reversed words aren't much harder to guess")
+	}
+	if match.l33t {
+		suggestions = append(suggestions, "Predictable substitutions like '@' instead of 'a' don't help very much")
+	}
+	return warning, suggestions
+}
+
+func binomial(n int, k int) float64 {
+	if k > n {
+		return 0
+	}
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result *= float64(n-k+i) / float64(i)
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+package internal
+
+// Word lists used by the password strength estimator, most common first. The
+// position in the list is used as the number of guesses needed to find a
+// word. Kept short on purpose, breached passwords are caught by the breached
+// password corpus.
+
+var /* const */ commonPasswords = []string{
+	"123456", "password", "12345678", "qwerty", "123456789", "12345", "1234",
+	"111111", "1234567", "dragon", "123123", "baseball", "abc123", "football",
+	"monkey", "letmein", "696969", "shadow", "master", "666666", "qwertyuiop",
+	"123321", "mustang", "1234567890", "michael", "654321", "superman",
+	"1qaz2wsx", "7777777", "121212", "000000", "qazwsx", "123qwe", "killer",
+	"trustno1", "jordan", "jennifer", "zxcvbnm", "asdfgh", "hunter", "buster",
+	"soccer", "harley", "batman", "andrew", "tigger", "sunshine", "iloveyou",
+	"2000", "charlie", "robert", "thomas", "hockey", "ranger", "daniel",
+	"starwars", "klaster", "112233", "george", "computer", "michelle",
+	"jessica", "pepper", "1111", "zxcvbn", "555555", "11111111", "131313",
+	"freedom", "777777", "pass", "maggie", "159753", "aaaaaa", "ginger",
+	"princess", "joshua", "cheese", "amanda", "summer", "love", "ashley",
+	"nicole", "chelsea", "biteme", "matthew", "access", "yankees", "987654321",
+	"dallas", "austin", "thunder", "taylor", "matrix", "mobilemail", "mom",
+	"monitor", "monitoring", "montana", "moon", "moscow", "welcome",
+	"passw0rd", "admin", "login", "solo", "qwerty123", "password1", "secret",
+	"whatever", "gamer", "letmein1", "hello", "flower", "lovely", "samsung",
+	"starcraft", "warcraft", "minecraft", "pokemon", "naruto", "azerty",
+}
+
+var /* const */ commonWords = []string{
+	"the", "you", "and", "that", "this", "for", "have", "love", "what", "your",
+	"not", "all", "know", "like", "just", "get", "was", "with", "can", "are",
+	"good", "one", "time", "out", "there", "now", "well", "right", "how",
+	"here", "want", "come", "think", "back", "yes", "see", "man", "really",
+	"look", "only", "tell", "need", "make", "let", "take", "about", "from",
+	"god", "little", "sure", "over", "more", "life", "down", "they", "please",
+	"very", "strong", "secure", "money", "happy", "baby", "girl", "boy",
+	"friend", "house", "home", "world", "night", "day", "morning", "family",
+	"heart", "blue", "red", "green", "black", "white", "yellow", "orange",
+	"purple", "star", "sun", "moon", "fire", "water", "earth", "wind", "ice",
+	"dog", "cat", "horse", "tiger", "lion", "bear", "wolf", "eagle", "dragon",
+	"king", "queen", "prince", "angel", "devil", "magic", "power", "hero",
+	"super", "best", "cool", "crazy", "sweet", "pretty", "party", "music",
+	"game", "games", "gamer", "player", "play", "winner", "champion", "team",
+	"league", "legend", "legends", "counter", "strike", "dota", "esports",
+	"sport", "sports", "fantasy", "draft", "drafts", "pick", "ban", "rank",
+	"ranked", "pro", "noob", "clutch", "victory", "battle", "war", "shot",
+	"head", "sniper", "gold", "silver", "diamond", "master", "summer",
+	"winter", "spring", "autumn", "january", "february", "march", "april",
+	"june", "july", "august", "september", "october", "november", "december",
+	"monday", "friday", "sunday", "correct", "battery", "staple",
+}
+
+// Always a bad idea to include in a password for this site
+var /* const */ siteWords = []string{
+	"esportsdrafts", "esportsdraft", "esports", "drafts",
+}
+
+// Unshifted rows of a QWERTY keyboard. Each row is offset about half a key
+// to the right of the row above.
+var /* const */ qwertyRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+}
+
+// Shifted characters mapped to the key they are typed with
+var /* const */ qwertyShifted = map[rune]rune{
+	'~': '`', '!': '1', '@': '2', '#': '3', '$': '4', '%': '5', '^': '6',
+	'&': '7', '*': '8', '(': '9', ')': '0', '_': '-', '+': '=', '{': '[',
+	'}': ']', '|': '\\', ':': ';', '"': '\'', '<': ',', '>': '.', '?': '/',
+}
+
+// Common character substitutions, some characters have several readings
+var /* const */ l33tTable = map[rune][]rune{
+	'4': {'a'},
+	'@': {'a'},
+	'8': {'b'},
+	'(': {'c'},
+	'3': {'e'},
+	'6': {'g'},
+	'1': {'i', 'l'},
+	'!': {'i'},
+	'|': {'i', 'l'},
+	'0': {'o'},
+	'$': {'s'},
+	'5': {'s'},
+	'7': {'t'},
+	'+': {'t'},
+	'2': {'z'},
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordStrengthScores(t *testing.T) {
+	tables := []struct {
+		password string
+		accepted bool
+	}{
+		{"aaaaaaaaaaaa", false},
+		{"password", false},
+		{"P4ssw0rd", false},
+		{"qwertyuiop", false},
+		{"abcdefghijkl", false},
+		{"zaq12wsxcde3", false},
+		{"pellepelle12", false},
+		{"esportsdrafts2019", false},
+		{"correcthorsebatterystaple", true},
+		{"jfk3Ld9!xq2Pzv", true},
+		{"xk-_qpzmrtvbnwe", true},
+	}
+	for _, table := range tables {
+		strength := EstimatePasswordStrength(table.password, []string{"pelle", "pelle@test.nu"})
+		accepted := strength.Score >= minPasswordScore
+		if accepted != table.accepted {
+			t.Errorf("Password '%s' got score %d, wanted accepted %t", table.password, strength.Score, table.accepted)
+		}
+		if !accepted && len(strength.Suggestions) == 0 {
+			t.Errorf("Rejected password '%s' has no suggestions", table.password)
+		}
+	}
+}
+
+func TestPasswordStrengthFeedback(t *testing.T) {
+	tables := []struct {
+		password string
+		warning  string
+	}{
+		{"aaaaaaaaaaaa", "Repeats"},
+		{"password", "top-10 common password"},
+		{"asdfghjkl", "Straight rows of keys"},
+		{"abcdefghijkl", "Sequences"},
+		{"pelle1990", "username or email"},
+		{"testnu", "username or email"},
+	}
+	for _, table := range tables {
+		strength := EstimatePasswordStrength(table.password, []string{"pelle", "pelle@test.nu"})
+		if !strings.Contains(strength.Warning, table.warning) {
+			t.Errorf("Password '%s' got warning '%s', wanted '%s'", table.password, strength.Warning, table.warning)
+		}
+	}
+}
+
+func TestPasswordStrengthUserInputs(t *testing.T) {
+	password := "gibberishname77"
+	without := EstimatePasswordStrength(password, nil)
+	with := EstimatePasswordStrength(password, []string{"gibberishname"})
+	if with.GuessesLog10 >= without.GuessesLog10 {
+		t.Errorf("Password containing the username should be weaker")
+	}
+}
+
+func TestPasswordStrengthEmpty(t *testing.T) {
+	strength := EstimatePasswordStrength("", nil)
+	if strength.Score != 0 || len(strength.Suggestions) == 0 {
+		t.Errorf("Empty password should score 0 with suggestions")
+	}
+}
+
+func TestPasswordStrengthLongPassword(t *testing.T) {
+	// Longest allowed password must be fast to estimate
+	strength := EstimatePasswordStrength(strings.Repeat("ab1", maxPasswordLength/3), nil)
+	if strength.Score >= minPasswordScore {
+		t.Errorf("Long repeated password should be weak, got score %d", strength.Score)
+	}
+}
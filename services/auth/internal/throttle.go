@@ -0,0 +1,377 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// Failures older than this are forgotten
+	throttleWindow = 15 * time.Minute
+	// Failures allowed before delays kick in
+	throttleFreeUserAttempts = 3
+	// Many users can share an IP so allow a lot more before slowing it down
+	throttleFreeIPAttempts = 30
+	// Delay after the first throttled failure, doubled for every failure after
+	throttleBaseDelay = time.Second
+	throttleMaxDelay  = time.Minute
+	// Failures within the window before the username is locked
+	lockoutThreshold = 10
+	lockoutDuration  = 30 * time.Minute
+)
+
+// AttemptStore keeps track of failed login attempts and lockouts. Keys are
+// opaque strings, e.g. 'user:pelle' or 'ip:127.0.0.1'.
+type AttemptStore interface {
+	// AddFailure records a failed attempt for key
+	AddFailure(key string, at time.Time) error
+	// Failures returns the time of every failure for key since a point in
+	// time, oldest first
+	Failures(key string, since time.Time) ([]time.Time, error)
+	// Reset forgets all failures for key
+	Reset(key string) error
+	// Lock locks key until a point in time
+	Lock(key string, until time.Time) error
+	// LockedUntil returns when the lock on key expires. Zero if not locked.
+	LockedUntil(key string) (time.Time, error)
+	// Unlock removes the lock on key
+	Unlock(key string) error
+}
+
+// Throttler slows down and eventually locks out repeated failed logins using
+// sliding window counters per username and per IP
+type Throttler struct {
+	store AttemptStore
+	now   func() time.Time
+}
+
+// NewThrottler creates a throttler backed by store
+func NewThrottler(store AttemptStore) *Throttler {
+	return &Throttler{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+func userThrottleKey(username string) string {
+	return "user:" + username
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check returns how long the caller has to wait before the next attempt for
+// username from ip is allowed. Locked is true if the username is locked out,
+// in which case retryAfter is the time left on the lock.
+func (t *Throttler) Check(username string, ip string) (retryAfter time.Duration, locked bool, err error) {
+	now := t.now()
+
+	lockedUntil, err := t.store.LockedUntil(userThrottleKey(username))
+	if err != nil {
+		return 0, false, err
+	}
+	if now.Before(lockedUntil) {
+		return lockedUntil.Sub(now), true, nil
+	}
+
+	userDelay, err := t.delay(userThrottleKey(username), throttleFreeUserAttempts, now)
+	if err != nil {
+		return 0, false, err
+	}
+
+	ipDelay, err := t.delay(ipThrottleKey(ip), throttleFreeIPAttempts, now)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if ipDelay > userDelay {
+		return ipDelay, false, nil
+	}
+	return userDelay, false, nil
+}
+
+// delay computes the progressive delay for key, doubling for every failure
+// past the free attempts
+func (t *Throttler) delay(key string, freeAttempts int, now time.Time) (time.Duration, error) {
+	failures, err := t.store.Failures(key, now.Add(-throttleWindow))
+	if err != nil {
+		return 0, err
+	}
+
+	excess := len(failures) - freeAttempts
+	if excess < 0 {
+		return 0, nil
+	}
+
+	delay := throttleMaxDelay
+	if excess < 16 {
+		delay = throttleBaseDelay << uint(excess)
+		if delay > throttleMaxDelay {
+			delay = throttleMaxDelay
+		}
+	}
+
+	wait := failures[len(failures)-1].Add(delay).Sub(now)
+	if wait < 0 {
+		return 0, nil
+	}
+	return wait, nil
+}
+
+// RecordFailure records a failed attempt for username from ip. Returns true
+// if this failure locked the username.
+func (t *Throttler) RecordFailure(username string, ip string) (bool, error) {
+	now := t.now()
+	userKey := userThrottleKey(username)
+
+	err := t.store.AddFailure(userKey, now)
+	if err != nil {
+		return false, err
+	}
+
+	err = t.store.AddFailure(ipThrottleKey(ip), now)
+	if err != nil {
+		return false, err
+	}
+
+	failures, err := t.store.Failures(userKey, now.Add(-throttleWindow))
+	if err != nil {
+		return false, err
+	}
+
+	if len(failures) < lockoutThreshold {
+		return false, nil
+	}
+
+	err = t.store.Lock(userKey, now.Add(lockoutDuration))
+	if err != nil {
+		return false, err
+	}
+	// Start over once the lock expires
+	return true, t.store.Reset(userKey)
+}
+
+// RecordSuccess forgets earlier failures for username. Failures per IP are
+// kept so one valid account can not be used to reset the IP counter.
+func (t *Throttler) RecordSuccess(username string) error {
+	return t.store.Reset(userThrottleKey(username))
+}
+
+// Lock locks username until the given time regardless of failures
+func (t *Throttler) Lock(username string, until time.Time) error {
+	return t.store.Lock(userThrottleKey(username), until)
+}
+
+// Unlock lifts a lockout on username and forgets its failures
+func (t *Throttler) Unlock(username string) error {
+	err := t.store.Unlock(userThrottleKey(username))
+	if err != nil {
+		return err
+	}
+	return t.store.Reset(userThrottleKey(username))
+}
+
+// MemoryAttemptStore keeps attempts in memory. Only suitable for tests and
+// single instance deployments.
+type MemoryAttemptStore struct {
+	mu       sync.Mutex
+	failures map[string][]time.Time
+	locks    map[string]time.Time
+}
+
+// NewMemoryAttemptStore creates an empty in-memory store
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{
+		failures: map[string][]time.Time{},
+		locks:    map[string]time.Time{},
+	}
+}
+
+// AddFailure records a failed attempt for key
+func (s *MemoryAttemptStore) AddFailure(key string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[key] = append(s.failures[key], at)
+	return nil
+}
+
+// Failures returns failures for key since a point in time, dropping older
+// ones
+func (s *MemoryAttemptStore) Failures(key string, since time.Time) ([]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var recent []time.Time
+	for _, at := range s.failures[key] {
+		if at.After(since) {
+			recent = append(recent, at)
+		}
+	}
+	s.failures[key] = recent
+
+	sort.Slice(recent, func(i, j int) bool { return recent[i].Before(recent[j]) })
+	return append([]time.Time{}, recent...), nil
+}
+
+// Reset forgets all failures for key
+func (s *MemoryAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, key)
+	return nil
+}
+
+// Lock locks key until a point in time
+func (s *MemoryAttemptStore) Lock(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locks[key] = until
+	return nil
+}
+
+// LockedUntil returns when the lock on key expires
+func (s *MemoryAttemptStore) LockedUntil(key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.locks[key], nil
+}
+
+// Unlock removes the lock on key
+func (s *MemoryAttemptStore) Unlock(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.locks, key)
+	return nil
+}
+
+// GormAttemptStore keeps attempts in the database so all instances of the
+// service share them
+type GormAttemptStore struct {
+	dbHandler *gorm.DB
+}
+
+// NewGormAttemptStore creates a store using the login attempt tables
+func NewGormAttemptStore(dbHandler *gorm.DB) *GormAttemptStore {
+	return &GormAttemptStore{dbHandler: dbHandler}
+}
+
+// AddFailure records a failed attempt for key
+func (s *GormAttemptStore) AddFailure(key string, at time.Time) error {
+	return s.dbHandler.Create(&db.LoginAttempt{Key: key, AttemptedAt: at}).Error
+}
+
+// Failures returns failures for key since a point in time. Older attempts
+// are never read again so they are deleted.
+func (s *GormAttemptStore) Failures(key string, since time.Time) ([]time.Time, error) {
+	err := s.dbHandler.Unscoped().Where("`key` = ? AND attempted_at <= ?", key, since).Delete(&db.LoginAttempt{}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var attempts []db.LoginAttempt
+	err = s.dbHandler.Where("`key` = ? AND attempted_at > ?", key, since).Order("attempted_at").Find(&attempts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	failures := make([]time.Time, len(attempts))
+	for i, attempt := range attempts {
+		failures[i] = attempt.AttemptedAt
+	}
+	return failures, nil
+}
+
+// Reset forgets all failures for key
+func (s *GormAttemptStore) Reset(key string) error {
+	return s.dbHandler.Unscoped().Where("`key` = ?", key).Delete(&db.LoginAttempt{}).Error
+}
+
+// Lock locks key until a point in time
+func (s *GormAttemptStore) Lock(key string, until time.Time) error {
+	return s.dbHandler.Save(&db.LoginLockout{Key: key, LockedUntil: until}).Error
+}
+
+// LockedUntil returns when the lock on key expires
+func (s *GormAttemptStore) LockedUntil(key string) (time.Time, error) {
+	var lockout db.LoginLockout
+	err := s.dbHandler.Where("`key` = ?", key).First(&lockout).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return lockout.LockedUntil, nil
+}
+
+// Unlock removes the lock on key
+func (s *GormAttemptStore) Unlock(key string) error {
+	return s.dbHandler.Where("`key` = ?", key).Delete(&db.LoginLockout{}).Error
+}
+
+// rejectThrottled writes a 429 response if username or the client IP has to
+// wait before trying again. Returns true if a response was written.
+func (a *AuthAPI) rejectThrottled(ctx echo.Context, username string) (bool, error) {
+	retryAfter, locked, err := a.throttler.Check(strings.ToLower(username), ctx.RealIP())
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return true, sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	if retryAfter <= 0 {
+		return false, nil
+	}
+
+	seconds := setRetryAfter(ctx, retryAfter)
+	if locked {
+		return true, sendAuthAPIError(ctx, http.StatusTooManyRequests,
+			"Account temporarily locked due to too many failed login attempts")
+	}
+	return true, sendAuthAPIError(ctx, http.StatusTooManyRequests,
+		fmt.Sprintf("Too many failed login attempts, try again in %d seconds", seconds))
+}
+
+// setRetryAfter sets the Retry-After header, rounded up to whole seconds.
+// Returns the number of seconds.
+func setRetryAfter(ctx echo.Context, retryAfter time.Duration) int {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	ctx.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return seconds
+}
+
+// recordAuthFailure counts a failed login for username. The owner of account
+// is notified if this failure locked it, account is nil for unknown users.
+func (a *AuthAPI) recordAuthFailure(ctx echo.Context, username string, account *db.Account, method string) {
+	logger := efanlog.GetLogger()
+
+	// Unknown usernames are not stored, they are often mistyped passwords
+	userID := uuid.Nil
+	if account != nil {
+		userID = account.ID
+	}
+	a.recordSecurityEvent(ctx, userID, db.LoginFailed, method)
+
+	locked, err := a.throttler.RecordFailure(strings.ToLower(username), ctx.RealIP())
+	if err != nil {
+		logger.Errorf("Failed to record failed login for '%s': %s", username, err)
+		return
+	}
+
+	if locked && account != nil {
+		logger.Infof("Account '%s' locked after too many failed login attempts", username)
+		go ScheduleAccountLockedEmail(a.beanstalkHandler, account.Username, account.Email, time.Now().Add(lockoutDuration))
+	}
+}
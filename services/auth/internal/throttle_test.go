@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newTestThrottler() (*Throttler, *time.Time) {
+	now := time.Now()
+	throttler := NewThrottler(NewMemoryAttemptStore())
+	throttler.now = func() time.Time { return now }
+	return throttler, &now
+}
+
+func TestThrottleProgressiveDelay(t *testing.T) {
+	throttler, now := newTestThrottler()
+
+	for i := 0; i < throttleFreeUserAttempts; i++ {
+		throttler.RecordFailure("pelle", "127.0.0.1")
+	}
+
+	retryAfter, locked, err := throttler.Check("pelle", "127.0.0.1")
+	if err != nil || locked {
+		t.Fatalf("Unexpected lock or error: %+v", err)
+	}
+	if retryAfter != throttleBaseDelay {
+		t.Errorf("Wrong delay after free attempts, got %s, wanted %s", retryAfter, throttleBaseDelay)
+	}
+
+	throttler.RecordFailure("pelle", "127.0.0.1")
+	retryAfter, _, _ = throttler.Check("pelle", "127.0.0.1")
+	if retryAfter != 2*throttleBaseDelay {
+		t.Errorf("Delay should double, got %s, wanted %s", retryAfter, 2*throttleBaseDelay)
+	}
+
+	// Other users from the same IP are not affected yet
+	retryAfter, _, _ = throttler.Check("kalle", "127.0.0.1")
+	if retryAfter != 0 {
+		t.Errorf("Other username should not be delayed, got %s", retryAfter)
+	}
+
+	// Failures slide out of the window
+	*now = now.Add(throttleWindow + time.Second)
+	retryAfter, _, _ = throttler.Check("pelle", "127.0.0.1")
+	if retryAfter != 0 {
+		t.Errorf("Old failures should be forgotten, got delay %s", retryAfter)
+	}
+}
+
+func TestThrottleLockout(t *testing.T) {
+	throttler, now := newTestThrottler()
+
+	for i := 0; i < lockoutThreshold-1; i++ {
+		locked, err := throttler.RecordFailure("pelle", "127.0.0.1")
+		if err != nil || locked {
+			t.Fatalf("Locked too early after %d failures", i+1)
+		}
+	}
+
+	locked, _ := throttler.RecordFailure("pelle", "127.0.0.1")
+	if !locked {
+		t.Fatalf("Account should be locked after %d failures", lockoutThreshold)
+	}
+
+	retryAfter, locked, _ := throttler.Check("pelle", "10.0.0.1")
+	if !locked || retryAfter != lockoutDuration {
+		t.Errorf("Lock should apply from any IP, got locked %t for %s", locked, retryAfter)
+	}
+
+	*now = now.Add(lockoutDuration)
+	_, locked, _ = throttler.Check("pelle", "10.0.0.1")
+	if locked {
+		t.Errorf("Lock should expire")
+	}
+}
+
+func TestThrottleUnlockAndSuccess(t *testing.T) {
+	throttler, _ := newTestThrottler()
+
+	for i := 0; i < lockoutThreshold; i++ {
+		throttler.RecordFailure("pelle", "127.0.0.1")
+	}
+
+	err := throttler.Unlock("pelle")
+	if err != nil {
+		t.Fatalf("Failed to unlock: %+v", err)
+	}
+
+	retryAfter, locked, _ := throttler.Check("pelle", "10.0.0.1")
+	if locked || retryAfter != 0 {
+		t.Errorf("Unlocked account should not be throttled")
+	}
+
+	for i := 0; i < throttleFreeUserAttempts+1; i++ {
+		throttler.RecordFailure("pelle", "10.0.0.1")
+	}
+	throttler.RecordSuccess("pelle")
+
+	retryAfter, _, _ = throttler.Check("pelle", "10.0.0.2")
+	if retryAfter != 0 {
+		t.Errorf("Successful login should reset username failures, got delay %s", retryAfter)
+	}
+}
+
+func TestThrottleIP(t *testing.T) {
+	throttler, _ := newTestThrottler()
+
+	// Spraying one guess each over many usernames
+	for i := 0; i < throttleFreeIPAttempts; i++ {
+		throttler.RecordFailure(fmt.Sprintf("user%d", i), "127.0.0.1")
+	}
+
+	retryAfter, _, _ := throttler.Check("fresh_user", "127.0.0.1")
+	if retryAfter == 0 {
+		t.Errorf("IP should be throttled after %d failures", throttleFreeIPAttempts)
+	}
+
+	retryAfter, _, _ = throttler.Check("fresh_user", "10.0.0.1")
+	if retryAfter != 0 {
+		t.Errorf("Other IPs should not be throttled")
+	}
+}
+
+func TestThrottleManualLock(t *testing.T) {
+	throttler, now := newTestThrottler()
+
+	err := throttler.Lock("pelle", now.Add(reportedLoginLockout))
+	if err != nil {
+		t.Fatalf("Failed to lock: %+v", err)
+	}
+
+	retryAfter, locked, _ := throttler.Check("pelle", "127.0.0.1")
+	if !locked || retryAfter != reportedLoginLockout {
+		t.Errorf("Expected lock for %s, got %s (locked: %t)", reportedLoginLockout, retryAfter, locked)
+	}
+
+	throttler.Unlock("pelle")
+	if _, locked, _ = throttler.Check("pelle", "127.0.0.1"); locked {
+		t.Errorf("Unlock should lift a manual lock")
+	}
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPRFCVectors(t *testing.T) {
+	// Test vectors from RFC 6238 Appendix B (SHA1)
+	key := []byte("12345678901234567890")
+	tables := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+	}
+	for _, table := range tables {
+		res := totpCode(key, totpStep(time.Unix(table.unix, 0)), 8)
+		if res != table.expected {
+			t.Errorf("TOTP code at %d was incorrect, got %s, wanted %s", table.unix, res, table.expected)
+		}
+	}
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("Failed to generate secret: %+v", err)
+	}
+
+	now := time.Now()
+	code, err := GenerateTOTPCode(secret, now)
+	if err != nil {
+		t.Fatalf("Failed to generate code: %+v", err)
+	}
+
+	step, ok, err := ValidateTOTPCode(secret, code, now, 0)
+	if err != nil || !ok {
+		t.Errorf("Valid code was rejected")
+	}
+
+	// Allow one step of clock skew
+	_, ok, _ = ValidateTOTPCode(secret, code, now.Add(totpPeriod*time.Second), 0)
+	if !ok {
+		t.Errorf("Code from previous step should be accepted")
+	}
+
+	_, ok, _ = ValidateTOTPCode(secret, code, now.Add(5*totpPeriod*time.Second), 0)
+	if ok {
+		t.Errorf("Code from old step should be rejected")
+	}
+
+	// Same code can not be used twice
+	_, ok, _ = ValidateTOTPCode(secret, code, now, step)
+	if ok {
+		t.Errorf("Replayed code should be rejected")
+	}
+
+	_, ok, _ = ValidateTOTPCode(secret, "12345", now, 0)
+	if ok {
+		t.Errorf("Code with wrong length should be rejected")
+	}
+}
+
+func TestTOTPKeyURI(t *testing.T) {
+	uri := TOTPKeyURI("JBSWY3DPEHPK3PXP", "pelle")
+	if !strings.HasPrefix(uri, "otpauth://totp/esportsdrafts:pelle?") {
+		t.Errorf("Unexpected URI prefix: %s", uri)
+	}
+	if !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") {
+		t.Errorf("URI missing secret: %s", uri)
+	}
+}
@@ -3,6 +3,8 @@ package internal
 import (
 	"regexp"
 	"unicode/utf8"
+
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
 )
 
 // InputValidator has functions to check email, username, and passwords for
@@ -11,6 +13,8 @@ type InputValidator interface {
 	ValidateUsername(name string) bool
 	ValidateEmail(email string) bool
 	ValidatePassword(password string) bool
+	IsBreachedPassword(password string) bool
+	PasswordStrength(password string, userInputs ...string) PasswordStrength
 }
 
 // BasicValidator holds a baseline implementation for an Account input
@@ -18,6 +22,8 @@ type InputValidator interface {
 type BasicValidator struct {
 	maxUsernameLength, minUsernameLength int
 	maxPasswordLength, minPasswordLength int
+	// Optional, breached password checks are skipped if nil
+	breachedPasswords *BreachedPasswordCorpus
 }
 
 var /* const */ emailRegex = regexp.MustCompile(`^(([^<>()[\]\\.,;:\s@"]+(\.[^<>()[\]\\.,;:\s@"]+)*)|(".+"))@((\[[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}])|(([a-zA-Z\-0-9]+\.)+[a-zA-Z]{2,}))$`)
@@ -51,6 +57,28 @@ func (d *BasicValidator) ValidatePassword(password string) bool {
 	return validPasswordString(password, d.minPasswordLength, d.maxPasswordLength)
 }
 
+// PasswordStrength estimates how hard password is to guess. UserInputs are
+// other fields the user entered, e.g. username and email.
+func (d *BasicValidator) PasswordStrength(password string, userInputs ...string) PasswordStrength {
+	return EstimatePasswordStrength(password, userInputs)
+}
+
+// IsBreachedPassword returns true if password is found in the breached
+// password corpus. Lookup errors are logged and treated as not breached, an
+// unreadable corpus should not stop people from signing up.
+func (d *BasicValidator) IsBreachedPassword(password string) bool {
+	if d.breachedPasswords == nil {
+		return false
+	}
+
+	breached, err := d.breachedPasswords.Contains(password)
+	if err != nil {
+		efanlog.GetLogger().Errorf("Failed to check breached passwords: %s", err)
+		return false
+	}
+	return breached
+}
+
 // validUsernameString validates a username entry according to two rules:
 //   * Can only contain [a-z][0-9] and - or _
 // 	 * min <= Length <= max
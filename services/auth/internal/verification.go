@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	efanlog "github.com/esportsdrafts/esportsdrafts/libs/log"
+	"github.com/esportsdrafts/esportsdrafts/services/auth/db"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// How long an email verification code is valid
+	emailVerificationTimeout = 48 * time.Hour
+)
+
+// Verification codes an account can be sent, including the one in the
+// welcome email
+var verificationResendLimit = emailRateLimit{
+	cooldown: 2 * time.Minute,
+	limit:    5,
+	window:   24 * time.Hour,
+}
+
+// emailRateLimit limits how often an email with a new code is sent to the
+// same account
+type emailRateLimit struct {
+	// Minimum time between two emails
+	cooldown time.Duration
+	// Emails allowed within window
+	limit  int
+	window time.Duration
+}
+
+// errResendLimited is returned when an email can not be sent yet
+type errResendLimited struct {
+	retryAfter time.Duration
+}
+
+func (e errResendLimited) Error() string {
+	return fmt.Sprintf("email rate limited for %s", e.retryAfter)
+}
+
+// wait returns how long an account has to wait before it can be sent another
+// email. Sent holds the time of every email sent within the window, oldest
+// first. Zero if an email can be sent right away.
+func (l emailRateLimit) wait(sent []time.Time, now time.Time) time.Duration {
+	if len(sent) == 0 {
+		return 0
+	}
+
+	wait := sent[len(sent)-1].Add(l.cooldown).Sub(now)
+
+	// The limit is lifted once the oldest email counting towards it falls out
+	// of the window
+	if len(sent) >= l.limit {
+		limitWait := sent[len(sent)-l.limit].Add(l.window).Sub(now)
+		if limitWait > wait {
+			wait = limitWait
+		}
+	}
+
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// Resendverification replaces all email verification codes of the logged in
+// account with a new one and sends it in a new welcome email. Used when the
+// first email was lost or its code expired.
+func (a *AuthAPI) Resendverification(ctx echo.Context) error {
+	account, err := a.getAccountFromContext(ctx)
+	if err != nil {
+		return sendAuthAPIError(ctx, http.StatusUnauthorized, "User not found")
+	}
+
+	if account.IsEmailVerified() {
+		return ctx.JSON(http.StatusOK, map[string]int{})
+	}
+
+	var verifyCode *db.EmailVerificationCode
+	err = db.DoInTransaction(func(tx *gorm.DB) error {
+		// Lock the account row so concurrent requests can't both pass the
+		// limits below
+		var locked db.Account
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Where("id = ?", account.ID).First(&locked).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		// Used codes are soft deleted, they still count towards the limits
+		var codes []db.EmailVerificationCode
+		err = tx.Unscoped().Where("user_id = ? AND created_at > ?", account.ID, now.Add(-verificationResendLimit.window)).
+			Order("created_at").Find(&codes).Error
+		if err != nil {
+			return err
+		}
+
+		sent := make([]time.Time, len(codes))
+		for i, code := range codes {
+			sent[i] = code.CreatedAt
+		}
+
+		wait := verificationResendLimit.wait(sent, now)
+		if wait > 0 {
+			return errResendLimited{retryAfter: wait}
+		}
+
+		err = tx.Where("user_id = ?", account.ID).Delete(db.EmailVerificationCode{}).Error
+		if err != nil {
+			return err
+		}
+
+		verifyCode = &db.EmailVerificationCode{
+			UserID:    account.ID,
+			ExpiresAt: now.Add(emailVerificationTimeout),
+		}
+		return tx.Save(verifyCode).Error
+	}, a.dbHandler)
+
+	if limited, ok := err.(errResendLimited); ok {
+		seconds := setRetryAfter(ctx, limited.retryAfter)
+		return sendAuthAPIError(ctx, http.StatusTooManyRequests,
+			fmt.Sprintf("Too many verification emails requested, try again in %d seconds", seconds))
+	}
+	if err != nil {
+		efanlog.GetLogger().Info(err)
+		return sendAuthAPIError(ctx, http.StatusInternalServerError, defaultErrorMessage)
+	}
+
+	go ScheduleNewUserEmail(a.beanstalkHandler, account.Username, account.Email, verifyCode.ID.String())
+
+	return ctx.JSON(http.StatusOK, map[string]int{})
+}
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEmailRateLimitWait(t *testing.T) {
+	now := time.Now()
+	rateLimit := emailRateLimit{
+		cooldown: 2 * time.Minute,
+		limit:    5,
+		window:   24 * time.Hour,
+	}
+
+	if wait := rateLimit.wait(nil, now); wait != 0 {
+		t.Errorf("First code should be sent right away, got wait %s", wait)
+	}
+
+	sent := []time.Time{now.Add(-30 * time.Second)}
+	if wait := rateLimit.wait(sent, now); wait != rateLimit.cooldown-30*time.Second {
+		t.Errorf("Wrong cooldown, got %s", wait)
+	}
+
+	sent = []time.Time{now.Add(-rateLimit.cooldown)}
+	if wait := rateLimit.wait(sent, now); wait != 0 {
+		t.Errorf("Cooldown should have passed, got wait %s", wait)
+	}
+
+	// Limit reached long after the cooldown, has to wait for the oldest
+	// code to fall out of the window
+	sent = nil
+	for i := rateLimit.limit; i > 0; i-- {
+		sent = append(sent, now.Add(-time.Duration(i)*time.Hour))
+	}
+	wantWait := rateLimit.window - time.Duration(rateLimit.limit)*time.Hour
+	if wait := rateLimit.wait(sent, now); wait != wantWait {
+		t.Errorf("Wrong wait with limit reached, got %s, wanted %s", wait, wantWait)
+	}
+
+	if wait := rateLimit.wait(sent[1:], now); wait != 0 {
+		t.Errorf("Below limit should not wait, got %s", wait)
+	}
+}
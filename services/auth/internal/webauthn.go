@@ -0,0 +1,391 @@
+package internal
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Verification of WebAuthn (https://www.w3.org/TR/webauthn-2/) registration
+// and authentication ceremonies. Only what passkeys need is supported: 'none'
+// attestation and ES256 or RS256 credential keys.
+
+const (
+	webAuthnChallengeLength = 32
+
+	// Authenticator data flags
+	authDataUserPresent  = 0x01
+	authDataUserVerified = 0x04
+	authDataAttested     = 0x40
+	authDataExtensions   = 0x80
+
+	// COSE key parameters and algorithms, RFC 8152
+	coseKeyType      = 1
+	coseKeyAlgorithm = 3
+	coseEC2Curve     = -1
+	coseEC2X         = -2
+	coseEC2Y         = -3
+	coseRSAModulus   = -1
+	coseRSAExponent  = -2
+	coseKeyTypeEC2   = 2
+	coseKeyTypeRSA   = 3
+	coseCurveP256    = 1
+	coseAlgES256     = -7
+	coseAlgRS256     = -257
+
+	minRSAKeyBits = 2048
+)
+
+var (
+	// ErrWebAuthnVerification is returned when a ceremony response does not
+	// verify. Details are wrapped in the error message for logging only.
+	ErrWebAuthnVerification = errors.New("webauthn verification failed")
+
+	// ErrWebAuthnSignCount is returned when the signature counter of a
+	// credential did not increase, a sign the authenticator was cloned
+	ErrWebAuthnSignCount = errors.New("webauthn signature counter did not increase")
+)
+
+// RelyingParty identifies this service to WebAuthn authenticators
+type RelyingParty struct {
+	// Domain credentials are scoped to, e.g. 'esportsdrafts.com'
+	ID string
+	// Human readable name shown by authenticators
+	Name string
+	// Origin of the web client, e.g. 'https://esportsdrafts.com'
+	Origin string
+}
+
+// webAuthnClientData is the part of the client data JSON that is verified
+type webAuthnClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// authenticatorData is parsed authenticator data. Credential fields are only
+// set in registrations.
+type authenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	CredentialID []byte
+	// COSE encoded public key
+	PublicKey []byte
+}
+
+// webAuthnCredential is a verified new credential
+type webAuthnCredential struct {
+	ID           []byte
+	PublicKey    []byte
+	SignCount    uint32
+	UserVerified bool
+}
+
+func webAuthnError(format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s", ErrWebAuthnVerification, fmt.Sprintf(format, args...))
+}
+
+// newWebAuthnChallenge returns a random challenge, base64url encoded the same
+// way browsers encode it in the client data
+func newWebAuthnChallenge() (string, error) {
+	random, err := generateRandomBytes(webAuthnChallengeLength)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// decodeWebAuthnBase64 decodes base64url with or without padding, clients
+// differ in what they send
+func decodeWebAuthnBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// verifyClientData checks the type, challenge and origin of the client data
+func (rp *RelyingParty) verifyClientData(clientDataJSON []byte, ceremony string, challenge string) error {
+	var clientData webAuthnClientData
+	err := json.Unmarshal(clientDataJSON, &clientData)
+	if err != nil {
+		return webAuthnError("invalid client data: %s", err)
+	}
+
+	if clientData.Type != ceremony {
+		return webAuthnError("wrong ceremony type '%s'", clientData.Type)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(clientData.Challenge), []byte(challenge)) != 1 {
+		return webAuthnError("challenge mismatch")
+	}
+
+	if clientData.Origin != rp.Origin {
+		return webAuthnError("wrong origin '%s'", clientData.Origin)
+	}
+	return nil
+}
+
+// verifyAuthenticatorData checks the RP ID hash and that the user was both
+// present and verified, passkeys replace the password and second factor
+func (rp *RelyingParty) verifyAuthenticatorData(authData *authenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData.RPIDHash, rpIDHash[:]) {
+		return webAuthnError("RP ID hash mismatch")
+	}
+
+	if authData.Flags&authDataUserPresent == 0 {
+		return webAuthnError("user not present")
+	}
+
+	if authData.Flags&authDataUserVerified == 0 {
+		return webAuthnError("user not verified")
+	}
+	return nil
+}
+
+// verifyRegistration verifies the response to a credential creation and
+// returns the new credential
+func (rp *RelyingParty) verifyRegistration(challenge string, clientDataJSON []byte, attestationObject []byte) (*webAuthnCredential, error) {
+	err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, n, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return nil, webAuthnError("invalid attestation object: %s", err)
+	}
+	if n != len(attestationObject) {
+		return nil, webAuthnError("trailing data after attestation object")
+	}
+
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, webAuthnError("attestation object is not a map")
+	}
+
+	// Attestation is not used to decide which authenticators are allowed,
+	// clients are asked for 'none'
+	if format, _ := attestation["fmt"].(string); format != "none" {
+		return nil, webAuthnError("unsupported attestation format '%s'", format)
+	}
+	if statement, _ := attestation["attStmt"].(map[interface{}]interface{}); len(statement) != 0 {
+		return nil, webAuthnError("unexpected attestation statement")
+	}
+
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, webAuthnError("missing authenticator data")
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rp.verifyAuthenticatorData(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	if authData.CredentialID == nil {
+		return nil, webAuthnError("missing attested credential data")
+	}
+
+	// Make sure the key is usable before storing it
+	_, err = parseCOSEKey(authData.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webAuthnCredential{
+		ID:           authData.CredentialID,
+		PublicKey:    authData.PublicKey,
+		SignCount:    authData.SignCount,
+		UserVerified: true,
+	}, nil
+}
+
+// verifyAssertion verifies the response to a credential request against the
+// stored public key and signature counter. Returns the new counter.
+func (rp *RelyingParty) verifyAssertion(challenge string, publicKey []byte, signCount uint32, clientDataJSON []byte, rawAuthData []byte, signature []byte) (uint32, error) {
+	err := rp.verifyClientData(clientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return 0, err
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+
+	err = rp.verifyAuthenticatorData(authData)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, rawAuthData...), clientDataHash[:]...)
+	err = verifyCOSESignature(key, signed, signature)
+	if err != nil {
+		return 0, err
+	}
+
+	// Authenticators without a counter always send zero
+	if (authData.SignCount != 0 || signCount != 0) && authData.SignCount <= signCount {
+		return 0, ErrWebAuthnSignCount
+	}
+	return authData.SignCount, nil
+}
+
+// parseAuthenticatorData parses the binary authenticator data structure
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, webAuthnError("authenticator data too short")
+	}
+
+	authData := &authenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if authData.Flags&authDataAttested != 0 {
+		// AAGUID followed by the length of the credential ID
+		if len(rest) < 18 {
+			return nil, webAuthnError("attested credential data too short")
+		}
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLength {
+			return nil, webAuthnError("credential ID too short")
+		}
+		authData.CredentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, webAuthnError("invalid credential public key: %s", err)
+		}
+		authData.PublicKey = rest[:n]
+		rest = rest[n:]
+	}
+
+	if authData.Flags&authDataExtensions != 0 {
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, webAuthnError("invalid extensions: %s", err)
+		}
+		rest = rest[n:]
+	}
+
+	if len(rest) != 0 {
+		return nil, webAuthnError("trailing data after authenticator data")
+	}
+	return authData, nil
+}
+
+// parseCOSEKey parses a COSE encoded ES256 or RS256 public key
+func parseCOSEKey(data []byte) (crypto.PublicKey, error) {
+	decoded, n, err := decodeCBOR(data)
+	if err != nil {
+		return nil, webAuthnError("invalid COSE key: %s", err)
+	}
+	if n != len(data) {
+		return nil, webAuthnError("trailing data after COSE key")
+	}
+
+	params, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, webAuthnError("COSE key is not a map")
+	}
+
+	keyType, _ := params[int64(coseKeyType)].(int64)
+	algorithm, _ := params[int64(coseKeyAlgorithm)].(int64)
+
+	switch {
+	case keyType == coseKeyTypeEC2 && algorithm == coseAlgES256:
+		curve, _ := params[int64(coseEC2Curve)].(int64)
+		x, _ := params[int64(coseEC2X)].([]byte)
+		y, _ := params[int64(coseEC2Y)].([]byte)
+		if curve != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, webAuthnError("invalid EC2 key parameters")
+		}
+
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, webAuthnError("EC2 point not on curve")
+		}
+		return key, nil
+
+	case keyType == coseKeyTypeRSA && algorithm == coseAlgRS256:
+		modulus, _ := params[int64(coseRSAModulus)].([]byte)
+		exponent, _ := params[int64(coseRSAExponent)].([]byte)
+		if len(exponent) == 0 || len(exponent) > 4 {
+			return nil, webAuthnError("invalid RSA exponent")
+		}
+
+		key := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(modulus),
+			E: int(new(big.Int).SetBytes(exponent).Int64()),
+		}
+		if key.N.BitLen() < minRSAKeyBits {
+			return nil, webAuthnError("RSA key too short")
+		}
+		return key, nil
+	}
+
+	return nil, webAuthnError("unsupported COSE key type %d with algorithm %d", keyType, algorithm)
+}
+
+// verifyCOSESignature verifies a signature over data made with the private
+// part of key
+func verifyCOSESignature(key crypto.PublicKey, data []byte, signature []byte) error {
+	digest := sha256.Sum256(data)
+
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		// ASN.1 DER encoded, unlike JWT signatures
+		var sig struct {
+			R, S *big.Int
+		}
+		rest, err := asn1.Unmarshal(signature, &sig)
+		if err != nil || len(rest) != 0 {
+			return webAuthnError("invalid ECDSA signature encoding")
+		}
+		if !ecdsa.Verify(key, digest[:], sig.R, sig.S) {
+			return webAuthnError("invalid signature")
+		}
+		return nil
+
+	case *rsa.PublicKey:
+		err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+		if err != nil {
+			return webAuthnError("invalid signature")
+		}
+		return nil
+	}
+
+	return webAuthnError("unsupported key type %T", key)
+}
@@ -0,0 +1,389 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+)
+
+// Encoding side of the CBOR subset the tests need. Maps are given as key
+// value pairs to keep the encoding deterministic.
+type cborPairs []interface{}
+
+func encodeCBORHead(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= 0xffff:
+		head := []byte{major<<5 | 25, 0, 0}
+		binary.BigEndian.PutUint16(head[1:], uint16(arg))
+		return head
+	default:
+		head := []byte{major<<5 | 26, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(head[1:], uint32(arg))
+		return head
+	}
+}
+
+func encodeCBOR(item interface{}) []byte {
+	switch v := item.(type) {
+	case int:
+		if v < 0 {
+			return encodeCBORHead(cborNegative, uint64(-1-v))
+		}
+		return encodeCBORHead(cborUnsigned, uint64(v))
+	case []byte:
+		return append(encodeCBORHead(cborBytes, uint64(len(v))), v...)
+	case string:
+		return append(encodeCBORHead(cborText, uint64(len(v))), v...)
+	case cborPairs:
+		out := encodeCBORHead(cborMap, uint64(len(v)/2))
+		for _, element := range v {
+			out = append(out, encodeCBOR(element)...)
+		}
+		return out
+	}
+	panic("unsupported type")
+}
+
+// softAuthenticator is a passkey authenticator in software
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+	flags        byte
+	noCounter    bool
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 16)
+	_, err = rand.Read(credentialID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{
+		key:          key,
+		credentialID: credentialID,
+		flags:        authDataUserPresent | authDataUserVerified,
+	}
+}
+
+func (s *softAuthenticator) coseKey() []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	s.key.X.FillBytes(x)
+	s.key.Y.FillBytes(y)
+	return encodeCBOR(cborPairs{
+		coseKeyType, coseKeyTypeEC2,
+		coseKeyAlgorithm, coseAlgES256,
+		coseEC2Curve, coseCurveP256,
+		coseEC2X, x,
+		coseEC2Y, y,
+	})
+}
+
+func (s *softAuthenticator) authData(rpID string, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+
+	flags := s.flags
+	if attested {
+		flags |= authDataAttested
+	}
+	data = append(data, flags)
+
+	counter := make([]byte, 4)
+	binary.BigEndian.PutUint32(counter, s.signCount)
+	data = append(data, counter...)
+
+	if attested {
+		// Zero AAGUID, as sent with 'none' attestation
+		data = append(data, make([]byte, 16)...)
+		length := make([]byte, 2)
+		binary.BigEndian.PutUint16(length, uint16(len(s.credentialID)))
+		data = append(data, length...)
+		data = append(data, s.credentialID...)
+		data = append(data, s.coseKey()...)
+	}
+	return data
+}
+
+func clientDataJSON(t *testing.T, ceremony string, challenge string, origin string) []byte {
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    origin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// register returns client data and attestation object for a creation
+func (s *softAuthenticator) register(t *testing.T, rp *RelyingParty, challenge string) ([]byte, []byte) {
+	attestationObject := encodeCBOR(cborPairs{
+		"fmt", "none",
+		"attStmt", cborPairs{},
+		"authData", s.authData(rp.ID, true),
+	})
+	return clientDataJSON(t, "webauthn.create", challenge, rp.Origin), attestationObject
+}
+
+// assert returns client data, authenticator data and signature for a login
+func (s *softAuthenticator) assert(t *testing.T, rp *RelyingParty, challenge string) ([]byte, []byte, []byte) {
+	if !s.noCounter {
+		s.signCount++
+	}
+	clientData := clientDataJSON(t, "webauthn.get", challenge, rp.Origin)
+	authData := s.authData(rp.ID, false)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, s.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clientData, authData, signature
+}
+
+func testRelyingParty() *RelyingParty {
+	return &RelyingParty{
+		ID:     "esportsdrafts.localhost",
+		Name:   "esportsdrafts",
+		Origin: "https://esportsdrafts.localhost",
+	}
+}
+
+func TestWebAuthnRegistrationAndAssertion(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := newSoftAuthenticator(t)
+
+	challenge, err := newWebAuthnChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientData, attestationObject := authenticator.register(t, rp, challenge)
+	credential, err := rp.verifyRegistration(challenge, clientData, attestationObject)
+	if err != nil {
+		t.Fatalf("Registration failed: %s", err)
+	}
+	if !bytes.Equal(credential.ID, authenticator.credentialID) {
+		t.Errorf("Wrong credential ID")
+	}
+
+	signCount := credential.SignCount
+	for i := 0; i < 3; i++ {
+		challenge, err = newWebAuthnChallenge()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		clientData, authData, signature := authenticator.assert(t, rp, challenge)
+		signCount, err = rp.verifyAssertion(challenge, credential.PublicKey, signCount, clientData, authData, signature)
+		if err != nil {
+			t.Fatalf("Assertion %d failed: %s", i, err)
+		}
+		if signCount != authenticator.signCount {
+			t.Errorf("Wrong sign count %d, wanted %d", signCount, authenticator.signCount)
+		}
+	}
+}
+
+func TestWebAuthnRejectsBadRegistrations(t *testing.T) {
+	rp := testRelyingParty()
+	challenge, _ := newWebAuthnChallenge()
+
+	authenticator := newSoftAuthenticator(t)
+	clientData, attestationObject := authenticator.register(t, rp, challenge)
+	otherChallenge, _ := newWebAuthnChallenge()
+	if _, err := rp.verifyRegistration(otherChallenge, clientData, attestationObject); err == nil {
+		t.Errorf("Registration for another challenge should fail")
+	}
+
+	otherOrigin := *rp
+	otherOrigin.Origin = "https://evil.localhost"
+	clientData, attestationObject = authenticator.register(t, &otherOrigin, challenge)
+	if _, err := rp.verifyRegistration(challenge, clientData, attestationObject); err == nil {
+		t.Errorf("Registration from another origin should fail")
+	}
+
+	otherRP := *rp
+	otherRP.ID = "evil.localhost"
+	clientData, _ = authenticator.register(t, rp, challenge)
+	_, attestationObject = authenticator.register(t, &otherRP, challenge)
+	if _, err := rp.verifyRegistration(challenge, clientData, attestationObject); err == nil {
+		t.Errorf("Registration for another RP ID should fail")
+	}
+
+	authenticator.flags = authDataUserPresent
+	clientData, attestationObject = authenticator.register(t, rp, challenge)
+	if _, err := rp.verifyRegistration(challenge, clientData, attestationObject); err == nil {
+		t.Errorf("Registration without user verification should fail")
+	}
+
+	authenticator.flags = authDataUserPresent | authDataUserVerified
+	clientData, attestationObject = authenticator.register(t, rp, challenge)
+	if _, err := rp.verifyRegistration(challenge, clientData, attestationObject[:len(attestationObject)-1]); err == nil {
+		t.Errorf("Truncated attestation object should fail")
+	}
+
+	attestationObject = encodeCBOR(cborPairs{
+		"fmt", "packed",
+		"attStmt", cborPairs{},
+		"authData", authenticator.authData(rp.ID, true),
+	})
+	if _, err := rp.verifyRegistration(challenge, clientData, attestationObject); err == nil {
+		t.Errorf("Attestation formats other than 'none' should fail")
+	}
+}
+
+func TestWebAuthnRejectsBadAssertions(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := newSoftAuthenticator(t)
+	publicKey := authenticator.coseKey()
+	challenge, _ := newWebAuthnChallenge()
+
+	clientData, authData, signature := authenticator.assert(t, rp, challenge)
+	otherChallenge, _ := newWebAuthnChallenge()
+	if _, err := rp.verifyAssertion(otherChallenge, publicKey, 0, clientData, authData, signature); err == nil {
+		t.Errorf("Assertion for another challenge should fail")
+	}
+
+	otherKey := newSoftAuthenticator(t).coseKey()
+	if _, err := rp.verifyAssertion(challenge, otherKey, 0, clientData, authData, signature); err == nil {
+		t.Errorf("Assertion signed by another key should fail")
+	}
+
+	signature[len(signature)-1] ^= 0xff
+	if _, err := rp.verifyAssertion(challenge, publicKey, 0, clientData, authData, signature); err == nil {
+		t.Errorf("Assertion with a broken signature should fail")
+	}
+
+	// Replaying an old counter points to a cloned authenticator
+	clientData, authData, signature = authenticator.assert(t, rp, challenge)
+	_, err := rp.verifyAssertion(challenge, publicKey, authenticator.signCount, clientData, authData, signature)
+	if err != ErrWebAuthnSignCount {
+		t.Errorf("Expected sign count error, got %v", err)
+	}
+
+	clientData, authData, signature = authenticator.assert(t, rp, challenge)
+	authData[36]++
+	if _, err := rp.verifyAssertion(challenge, publicKey, 0, clientData, authData, signature); err == nil {
+		t.Errorf("Assertion with modified authenticator data should fail")
+	}
+
+	// Authenticators without a counter always send zero
+	counterless := newSoftAuthenticator(t)
+	counterless.noCounter = true
+	for i := 0; i < 2; i++ {
+		clientData, authData, signature = counterless.assert(t, rp, challenge)
+		if _, err := rp.verifyAssertion(challenge, counterless.coseKey(), 0, clientData, authData, signature); err != nil {
+			t.Errorf("Assertion without a counter failed: %s", err)
+		}
+	}
+
+	authenticator.flags = authDataUserPresent
+	clientData, authData, signature = authenticator.assert(t, rp, challenge)
+	if _, err := rp.verifyAssertion(challenge, publicKey, 0, clientData, authData, signature); err == nil {
+		t.Errorf("Assertion without user verification should fail")
+	}
+
+	authenticator.flags = authDataUserPresent | authDataUserVerified
+	clientData, authData, signature = authenticator.assert(t, rp, challenge)
+	var clientDataFields map[string]string
+	_ = json.Unmarshal(clientData, &clientDataFields)
+	clientDataFields["type"] = "webauthn.create"
+	clientData, _ = json.Marshal(clientDataFields)
+	if _, err := rp.verifyAssertion(challenge, publicKey, 0, clientData, authData, signature); err == nil {
+		t.Errorf("Assertion with the wrong ceremony type should fail")
+	}
+}
+
+func TestWebAuthnChallengeEncoding(t *testing.T) {
+	challenge, err := newWebAuthnChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := decodeWebAuthnBase64(challenge)
+	if err != nil || len(decoded) != webAuthnChallengeLength {
+		t.Errorf("Challenge '%s' should decode to %d bytes", challenge, webAuthnChallengeLength)
+	}
+
+	padded := base64.URLEncoding.EncodeToString(decoded)
+	if again, err := decodeWebAuthnBase64(padded); err != nil || !bytes.Equal(again, decoded) {
+		t.Errorf("Padded base64url should be accepted")
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	encoded := encodeCBOR(cborPairs{
+		1, 2,
+		-3, []byte{1, 2, 3},
+		"text", "value",
+		"nested", cborPairs{-1, -300},
+	})
+
+	decoded, n, err := decodeCBOR(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(encoded) {
+		t.Errorf("Used %d bytes of %d", n, len(encoded))
+	}
+
+	items := decoded.(map[interface{}]interface{})
+	if items[int64(1)] != int64(2) {
+		t.Errorf("Wrong integer value %v", items[int64(1)])
+	}
+	if !bytes.Equal(items[int64(-3)].([]byte), []byte{1, 2, 3}) {
+		t.Errorf("Wrong byte string value %v", items[int64(-3)])
+	}
+	if items["text"] != "value" {
+		t.Errorf("Wrong text value %v", items["text"])
+	}
+	if nested := items["nested"].(map[interface{}]interface{}); nested[int64(-1)] != int64(-300) {
+		t.Errorf("Wrong nested value %v", nested[int64(-1)])
+	}
+
+	invalid := [][]byte{
+		{},
+		// Truncated byte string
+		{0x43, 1, 2},
+		// Indefinite length array
+		{0x9f, 0x01, 0xff},
+		// Half precision float
+		{0xf9, 0x3c, 0x00},
+		// Map claiming more entries than there is data
+		{0xb8, 0xff, 0x01},
+		// Duplicate map keys
+		{0xa2, 0x01, 0x01, 0x01, 0x02},
+		// Array as map key
+		{0xa1, 0x80, 0x01},
+	}
+	for _, data := range invalid {
+		if _, _, err := decodeCBOR(data); err == nil {
+			t.Errorf("Decoding %x should fail", data)
+		}
+	}
+
+	deep := bytes.Repeat([]byte{0x81}, cborMaxDepth+2)
+	deep = append(deep, 0x01)
+	if _, _, err := decodeCBOR(deep); err == nil {
+		t.Errorf("Deeply nested data should fail")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/matcornic/hermes/v2"
@@ -131,3 +132,416 @@ func SendResetPasswordEmail(username string, userEmail string, code string) erro
 
 	return nil
 }
+
+// SendMFACodeEmail sends a one-time login code to the user
+func SendMFACodeEmail(username string, userEmail string, code string) error {
+	email := hermes.Email{
+		Body: hermes.Body{
+			Name: username,
+			Intros: []string{
+				"A sign in to your esportsdrafts account requires a one-time code.",
+			},
+			Dictionary: []hermes.Entry{
+				{Key: "Code", Value: code},
+			},
+			Outros: []string{
+				"The code expires in 10 minutes and can only be used once.",
+				"If you did not try to sign in, someone else knows your password. Please change it as soon as possible.",
+			},
+			Signature: "Thanks",
+		},
+	}
+
+	// Generate an HTML email with the provided contents (for modern clients)
+	emailBody, err := h.GenerateHTML(email)
+	if err != nil {
+		return err
+	}
+
+	// Local dev environment cannot send emails anywhere so dump to fake inbox
+	// aka a file in a folder
+	if env == "local" {
+		return writeLocalEmail("mfa_code", username, emailBody)
+	}
+
+	// TODO: Call email API to actually send out the email
+	emailText, err := h.GeneratePlainText(email)
+	if err != nil {
+		return err
+	}
+
+	print(emailText)
+
+	return nil
+}
+
+// SendAccountLockedEmail tells the user their account was temporarily locked
+// after too many failed login attempts
+func SendAccountLockedEmail(username string, userEmail string, lockedUntil time.Time) error {
+	email := hermes.Email{
+		Body: hermes.Body{
+			Name: username,
+			Intros: []string{
+				"Your esportsdrafts account has been temporarily locked after too many failed login attempts.",
+			},
+			Dictionary: []hermes.Entry{
+				{Key: "Locked until", Value: lockedUntil.UTC().Format("2006-01-02 15:04 MST")},
+			},
+			Actions: []hermes.Action{
+				{
+					Instructions: "If this was not you, someone may be trying to guess your password. We recommend resetting it:",
+					Button: hermes.Button{
+						Color: "#DC4D2F",
+						Text:  "Reset your password",
+						Link:  fmt.Sprintf("https://%s/reset_password", baseURL),
+					},
+				},
+			},
+			Outros: []string{
+				"The account unlocks automatically, no action is needed if it was you.",
+				"Need help, or have questions? Just reply to this email, we'd love to help.",
+			},
+			Signature: "Thanks",
+		},
+	}
+
+	// Generate an HTML email with the provided contents (for modern clients)
+	emailBody, err := h.GenerateHTML(email)
+	if err != nil {
+		return err
+	}
+
+	// Local dev environment cannot send emails anywhere so dump to fake inbox
+	// aka a file in a folder
+	if env == "local" {
+		return writeLocalEmail("account_locked", username, emailBody)
+	}
+
+	// TODO: Call email API to actually send out the email
+	emailText, err := h.GeneratePlainText(email)
+	if err != nil {
+		return err
+	}
+
+	print(emailText)
+
+	return nil
+}
+
+// SendPasswordChangedEmail tells the user their password was changed so they
+// can react if it was not them
+func SendPasswordChangedEmail(username string, userEmail string, changedAt time.Time) error {
+	email := hermes.Email{
+		Body: hermes.Body{
+			Name: username,
+			Intros: []string{
+				"The password of your esportsdrafts account was just changed and all other sessions were logged out.",
+			},
+			Dictionary: []hermes.Entry{
+				{Key: "Changed at", Value: changedAt.UTC().Format("2006-01-02 15:04 MST")},
+			},
+			Actions: []hermes.Action{
+				{
+					Instructions: "If this was not you, reset your password right away:",
+					Button: hermes.Button{
+						Color: "#DC4D2F",
+						Text:  "Reset your password",
+						Link:  fmt.Sprintf("https://%s/reset_password", baseURL),
+					},
+				},
+			},
+			Outros: []string{
+				"No action is needed if it was you.",
+				"Need help, or have questions? Just reply to this email, we'd love to help.",
+			},
+			Signature: "Thanks",
+		},
+	}
+
+	// Generate an HTML email with the provided contents (for modern clients)
+	emailBody, err := h.GenerateHTML(email)
+	if err != nil {
+		return err
+	}
+
+	// Local dev environment cannot send emails anywhere so dump to fake inbox
+	// aka a file in a folder
+	if env == "local" {
+		return writeLocalEmail("password_changed", username, emailBody)
+	}
+
+	// TODO: Call email API to actually send out the email
+	emailText, err := h.GeneratePlainText(email)
+	if err != nil {
+		return err
+	}
+
+	print(emailText)
+
+	return nil
+}
+
+// SendEmailChangeConfirmEmail sends the code confirming an email change to
+// the new address
+func SendEmailChangeConfirmEmail(username string, userEmail string, code string) error {
+	email := hermes.Email{
+		Body: hermes.Body{
+			Name: username,
+			Intros: []string{
+				"You asked to use this address for your esportsdrafts account.",
+			},
+			Actions: []hermes.Action{
+				{
+					Instructions: "To start using this address, please click here:",
+					Button: hermes.Button{
+						Color: "#22BC66",
+						Text:  "Confirm your new email",
+						Link:  fmt.Sprintf("https://%s/confirm_email?user=%s&token=%s", baseURL, username, code),
+					},
+				},
+			},
+			Outros: []string{
+				"If you did not ask for this you can ignore this email.",
+				"Need help, or have questions? Just reply to this email, we'd love to help.",
+			},
+			Signature: "Thanks",
+		},
+	}
+
+	// Generate an HTML email with the provided contents (for modern clients)
+	emailBody, err := h.GenerateHTML(email)
+	if err != nil {
+		return err
+	}
+
+	// Local dev environment cannot send emails anywhere so dump to fake inbox
+	// aka a file in a folder
+	if env == "local" {
+		return writeLocalEmail("email_change_confirm", username, emailBody)
+	}
+
+	// TODO: Call email API to actually send out the email
+	emailText, err := h.GeneratePlainText(email)
+	if err != nil {
+		return err
+	}
+
+	print(emailText)
+
+	return nil
+}
+
+// SendEmailChangeNoticeEmail tells the user at their old address that the
+// email of their account is being changed, with a link to cancel
+func SendEmailChangeNoticeEmail(username string, userEmail string, newEmail string, cancelCode string) error {
+	email := hermes.Email{
+		Body: hermes.Body{
+			Name: username,
+			Intros: []string{
+				"Someone asked to change the email of your esportsdrafts account.",
+			},
+			Dictionary: []hermes.Entry{
+				{Key: "New email", Value: newEmail},
+			},
+			Actions: []hermes.Action{
+				{
+					Instructions: "If this was not you, cancel the change. The link also restores this address for a week after the change:",
+					Button: hermes.Button{
+						Color: "#DC4D2F",
+						Text:  "Cancel email change",
+						Link:  fmt.Sprintf("https://%s/cancel_email_change?user=%s&token=%s", baseURL, username, cancelCode),
+					},
+				},
+			},
+			Outros: []string{
+				"No action is needed if it was you.",
+				"Need help, or have questions? Just reply to this email, we'd love to help.",
+			},
+			Signature: "Thanks",
+		},
+	}
+
+	// Generate an HTML email with the provided contents (for modern clients)
+	emailBody, err := h.GenerateHTML(email)
+	if err != nil {
+		return err
+	}
+
+	// Local dev environment cannot send emails anywhere so dump to fake inbox
+	// aka a file in a folder
+	if env == "local" {
+		return writeLocalEmail("email_change_notice", username, emailBody)
+	}
+
+	// TODO: Call email API to actually send out the email
+	emailText, err := h.GeneratePlainText(email)
+	if err != nil {
+		return err
+	}
+
+	print(emailText)
+
+	return nil
+}
+
+// SendLoginLinkEmail sends a single-use sign-in link
+func SendLoginLinkEmail(username string, userEmail string, code string) error {
+	email := hermes.Email{
+		Body: hermes.Body{
+			Name: username,
+			Intros: []string{
+				"You asked for a link to sign in to esportsdrafts.",
+			},
+			Actions: []hermes.Action{
+				{
+					Instructions: "Click the button to sign in, the link works once and expires in 15 minutes:",
+					Button: hermes.Button{
+						Color: "#22BC66",
+						Text:  "Sign in",
+						Link:  fmt.Sprintf("https://%s/login_link?user=%s&token=%s", baseURL, username, code),
+					},
+				},
+			},
+			Outros: []string{
+				"If you did not ask for this you can ignore this email, nobody can sign in without the link.",
+				"Need help, or have questions? Just reply to this email, we'd love to help.",
+			},
+			Signature: "Thanks",
+		},
+	}
+
+	// Generate an HTML email with the provided contents (for modern clients)
+	emailBody, err := h.GenerateHTML(email)
+	if err != nil {
+		return err
+	}
+
+	// Local dev environment cannot send emails anywhere so dump to fake inbox
+	// aka a file in a folder
+	if env == "local" {
+		return writeLocalEmail("login_link", username, emailBody)
+	}
+
+	// TODO: Call email API to actually send out the email
+	emailText, err := h.GeneratePlainText(email)
+	if err != nil {
+		return err
+	}
+
+	print(emailText)
+
+	return nil
+}
+
+// SendRecoveryCodeUsedEmail tells the user a MFA recovery code was used to log
+// in so they can react if it was not them
+func SendRecoveryCodeUsedEmail(username string, userEmail string, usedAt time.Time, remaining int) error {
+	email := hermes.Email{
+		Body: hermes.Body{
+			Name: username,
+			Intros: []string{
+				"A recovery code was just used instead of your second factor to log in to your esportsdrafts account.",
+			},
+			Dictionary: []hermes.Entry{
+				{Key: "Used at", Value: usedAt.UTC().Format("2006-01-02 15:04 MST")},
+				{Key: "Recovery codes left", Value: strconv.Itoa(remaining)},
+			},
+			Actions: []hermes.Action{
+				{
+					Instructions: "If this was not you, reset your password right away:",
+					Button: hermes.Button{
+						Color: "#DC4D2F",
+						Text:  "Reset your password",
+						Link:  fmt.Sprintf("https://%s/reset_password", baseURL),
+					},
+				},
+			},
+			Outros: []string{
+				"If it was you, consider generating a new set of recovery codes from your account settings.",
+				"Need help, or have questions? Just reply to this email, we'd love to help.",
+			},
+			Signature: "Thanks",
+		},
+	}
+
+	// Generate an HTML email with the provided contents (for modern clients)
+	emailBody, err := h.GenerateHTML(email)
+	if err != nil {
+		return err
+	}
+
+	// Local dev environment cannot send emails anywhere so dump to fake inbox
+	// aka a file in a folder
+	if env == "local" {
+		return writeLocalEmail("mfa_recovery_used", username, emailBody)
+	}
+
+	// TODO: Call email API to actually send out the email
+	emailText, err := h.GeneratePlainText(email)
+	if err != nil {
+		return err
+	}
+
+	print(emailText)
+
+	return nil
+}
+
+// SendNewLoginEmail tells the user about a login from a device not seen
+// before, with a link to lock the account if it was not them
+func SendNewLoginEmail(username string, userEmail string, loginAt time.Time, userAgent string, ip string, reportCode string) error {
+	if userAgent == "" {
+		userAgent = "Unknown"
+	}
+
+	email := hermes.Email{
+		Body: hermes.Body{
+			Name: username,
+			Intros: []string{
+				"Your esportsdrafts account was just logged in to from a device we have not seen before.",
+			},
+			Dictionary: []hermes.Entry{
+				{Key: "Time", Value: "Around " + loginAt.UTC().Format("2006-01-02 15:04 MST")},
+				{Key: "Browser or app", Value: userAgent},
+				{Key: "IP address", Value: ip},
+			},
+			Actions: []hermes.Action{
+				{
+					Instructions: "If this was not you, lock your account. All sessions are logged out and you get an email to set a new password:",
+					Button: hermes.Button{
+						Color: "#DC4D2F",
+						Text:  "This wasn't me",
+						Link:  fmt.Sprintf("https://%s/report_login?user=%s&token=%s", baseURL, username, reportCode),
+					},
+				},
+			},
+			Outros: []string{
+				"No action is needed if it was you.",
+				"Need help, or have questions? Just reply to this email, we'd love to help.",
+			},
+			Signature: "Thanks",
+		},
+	}
+
+	// Generate an HTML email with the provided contents (for modern clients)
+	emailBody, err := h.GenerateHTML(email)
+	if err != nil {
+		return err
+	}
+
+	// Local dev environment cannot send emails anywhere so dump to fake inbox
+	// aka a file in a folder
+	if env == "local" {
+		return writeLocalEmail("new_login", username, emailBody)
+	}
+
+	// TODO: Call email API to actually send out the email
+	emailText, err := h.GeneratePlainText(email)
+	if err != nil {
+		return err
+	}
+
+	print(emailText)
+
+	return nil
+}
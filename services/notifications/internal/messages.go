@@ -102,6 +102,174 @@ func RunReceiveLoop() {
 				}
 				continue
 			}
+		case "mfa_code_email":
+			var msg models.MFACodeEmail
+			err = json.Unmarshal(body, &msg)
+			if err != nil {
+				logger.Warnf("Failed to parse MFA code message %d", id)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
+			logger.Infof("Sending MFA code email to user '%s'", msg.Username)
+			err = SendMFACodeEmail(msg.Username, msg.Email, msg.Code)
+			if err != nil {
+				logger.Warnf("Failed to send MFA code email. Error: %s", err)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
+		case "account_locked_email":
+			var msg models.AccountLockedEmail
+			err = json.Unmarshal(body, &msg)
+			if err != nil {
+				logger.Warnf("Failed to parse account locked message %d, with body: %s", id, body)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
+			logger.Infof("Sending account locked email to user '%s'", msg.Username)
+			err = SendAccountLockedEmail(msg.Username, msg.Email, msg.LockedUntil)
+			if err != nil {
+				logger.Warnf("Failed to send account locked email. Error: %s", err)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
+		case "password_changed_email":
+			var msg models.PasswordChangedEmail
+			err = json.Unmarshal(body, &msg)
+			if err != nil {
+				logger.Warnf("Failed to parse password changed message %d, with body: %s", id, body)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
+			logger.Infof("Sending password changed email to user '%s'", msg.Username)
+			err = SendPasswordChangedEmail(msg.Username, msg.Email, msg.ChangedAt)
+			if err != nil {
+				logger.Warnf("Failed to send password changed email. Error: %s", err)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
+		case "email_change_confirm_email":
+			var msg models.EmailChangeConfirmEmail
+			err = json.Unmarshal(body, &msg)
+			if err != nil {
+				logger.Warnf("Failed to parse email change confirmation message %d, with body: %s", id, body)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
+			logger.Infof("Sending email change confirmation email to user '%s'", msg.Username)
+			err = SendEmailChangeConfirmEmail(msg.Username, msg.Email, msg.ConfirmCode)
+			if err != nil {
+				logger.Warnf("Failed to send email change confirmation email. Error: %s", err)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
+		case "email_change_notice_email":
+			var msg models.EmailChangeNoticeEmail
+			err = json.Unmarshal(body, &msg)
+			if err != nil {
+				logger.Warnf("Failed to parse email change notice message %d, with body: %s", id, body)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
+			logger.Infof("Sending email change notice email to user '%s'", msg.Username)
+			err = SendEmailChangeNoticeEmail(msg.Username, msg.Email, msg.NewEmail, msg.CancelCode)
+			if err != nil {
+				logger.Warnf("Failed to send email change notice email. Error: %s", err)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
+		case "login_link_email":
+			var msg models.LoginLinkEmail
+			err = json.Unmarshal(body, &msg)
+			if err != nil {
+				logger.Warnf("Failed to parse sign-in link message %d, with body: %s", id, body)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
+			logger.Infof("Sending sign-in link email to user '%s'", msg.Username)
+			err = SendLoginLinkEmail(msg.Username, msg.Email, msg.LoginCode)
+			if err != nil {
+				logger.Warnf("Failed to send sign-in link email. Error: %s", err)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
+		case "recovery_code_used_email":
+			var msg models.RecoveryCodeUsedEmail
+			err = json.Unmarshal(body, &msg)
+			if err != nil {
+				logger.Warnf("Failed to parse recovery code used message %d, with body: %s", id, body)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
+			logger.Infof("Sending recovery code used email to user '%s'", msg.Username)
+			err = SendRecoveryCodeUsedEmail(msg.Username, msg.Email, msg.UsedAt, msg.Remaining)
+			if err != nil {
+				logger.Warnf("Failed to send recovery code used email. Error: %s", err)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
+		case "new_login_email":
+			var msg models.NewLoginEmail
+			err = json.Unmarshal(body, &msg)
+			if err != nil {
+				logger.Warnf("Failed to parse new login message %d, with body: %s", id, body)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
+			logger.Infof("Sending new login email to user '%s'", msg.Username)
+			err = SendNewLoginEmail(msg.Username, msg.Email, msg.LoginAt, msg.UserAgent, msg.IP, msg.ReportCode)
+			if err != nil {
+				logger.Warnf("Failed to send new login email. Error: %s", err)
+				err = c.Release(id, ReleasePriority, ReleaseDelay)
+				if err != nil {
+					logger.Errorf("Failed to release message %d. Error: \n%s", id, err)
+				}
+				continue
+			}
 		default:
 			logger.Infof("Burying job with id %d", id)
 			err = c.Bury(id, BuryPriority)
@@ -124,3 +124,20 @@ def get_verification_token(welcome_email: Text) -> Tuple[Optional[Text],
             break
 
     return user_id, token
+
+
+def get_mfa_code(mfa_email: Text) -> Optional[Text]:
+    """
+    Extract the one-time login code from a MFA code email.
+
+    Arguments:
+    mfa_email -- the email body as string
+
+    Returns:
+    The code as string or None if not found
+
+    """
+    match = re.search(r'Code:\s*</dt>\s*<dd[^>]*>\s*([^<\s]+)', mfa_email)
+    if match is None:
+        return None
+    return match.group(1)
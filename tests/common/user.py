@@ -1,7 +1,7 @@
 """User class and functions to CRUD users."""
 
 import time
-from typing import List, Optional, Text  # noqa
+from typing import Dict, List, Optional, Text  # noqa
 
 import jwt
 import requests
@@ -63,13 +63,24 @@ class User():
         self.user_id = claims.get('user_id')
         self.roles = claims.get('roles', [])
 
+    @property
+    def auth_headers(self) -> Dict[Text, Text]:
+        """Headers authenticating a request as the user, if logged in."""
+        headers = {}
+        if self.__auth_token is not None:
+            headers['Authorization'] = f'Bearer: {self.__auth_token}'
+        return headers
+
     def logout(self):
-        """Clear authentication for the user."""
-        payload = {}
-        res = requests.post(self.url + '/v1/auth/auth',
-                            json=payload,
+        """Revoke and clear authentication for the user."""
+        res = requests.post(self.url + '/v1/auth/logout',
+                            json={},
+                            headers=self.auth_headers,
                             verify=not self.url.endswith('.localhost'))
         raise_on_error(res)
+        self.__auth_token = None
+        self.__auth_expires_in = None
+        self.roles = []
 
     @property
     def is_authenticated(self) -> bool:
@@ -143,6 +154,13 @@ def verify_email(user: User, token: Text) -> None:
     raise_on_error(res)
 
 
+def resend_verification_email(user: User) -> None:
+    res = requests.post(user.url + '/v1/auth/verifyemail/resend', json={},
+                        headers=user.auth_headers,
+                        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+
+
 def check_username_available(username: Text, env: Text) -> bool:
     if username is None:
         return False
@@ -151,6 +169,20 @@ def check_username_available(username: Text, env: Text) -> bool:
     return res.status_code == 200
 
 
+def check_password_strength(password: Text, env: Text,
+                            username: Text = None,
+                            email: Text = None) -> Dict:
+    payload = {'password': password}
+    if username is not None:
+        payload['username'] = username
+    if email is not None:
+        payload['email'] = email
+    res = requests.post(f'{env}/v1/auth/check', json=payload,
+                        verify=not env.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
 def reset_password_request(user: User):
     payload = {
         'username': user.username,
@@ -172,3 +204,296 @@ def verify_password_reset(user: User, token: Text, new_password: Text):
         user.url + '/v1/auth/passwordreset/verify', json=payload,
         verify=not user.url.endswith('.localhost'))
     raise_on_error(res)
+
+
+def change_password(user: User, current_password: Text,
+                    new_password: Text) -> Dict:
+    payload = {
+        'current_password': current_password,
+        'new_password': new_password,
+    }
+    res = requests.post(
+        user.url + '/v1/auth/password', json=payload,
+        headers=user.auth_headers,
+        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def request_email_change(user: User, new_email: Text) -> None:
+    payload = {
+        'email': new_email,
+        'password': user.password,
+    }
+    res = requests.post(
+        user.url + '/v1/auth/email', json=payload,
+        headers=user.auth_headers,
+        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+
+
+def confirm_email_change(user: User, token: Text) -> None:
+    payload = {
+        'username': user.username,
+        'token': token,
+    }
+    res = requests.post(
+        user.url + '/v1/auth/email/confirm', json=payload,
+        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+
+
+def cancel_email_change(user: User, token: Text) -> None:
+    payload = {
+        'username': user.username,
+        'token': token,
+    }
+    res = requests.post(
+        user.url + '/v1/auth/email/cancel', json=payload,
+        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+
+
+def export_account(user: User) -> Dict:
+    res = requests.get(
+        user.url + '/v1/auth/account/export',
+        headers=user.auth_headers,
+        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def request_account_deletion(user: User) -> Dict:
+    res = requests.post(
+        user.url + '/v1/auth/account/deletion',
+        json={'password': user.password},
+        headers=user.auth_headers,
+        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def cancel_account_deletion(user: User) -> None:
+    res = requests.delete(
+        user.url + '/v1/auth/account/deletion',
+        headers=user.auth_headers,
+        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+
+
+def request_login_link(url: Text, username: Text) -> int:
+    payload = {
+        'claim': 'email_link',
+        'username': username,
+    }
+    res = requests.post(url + '/v1/auth/auth', json=payload,
+                        verify=not url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.status_code
+
+
+def consume_login_link(user: User, token: Text) -> Dict:
+    payload = {
+        'username': user.username,
+        'token': token,
+    }
+    res = requests.post(user.url + '/v1/auth/link', json=payload,
+                        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def begin_passkey_registration(user: User) -> Dict:
+    res = requests.post(
+        user.url + '/v1/auth/webauthn/register',
+        headers=user.auth_headers,
+        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def list_passkeys(user: User) -> List[Dict]:
+    res = requests.get(
+        user.url + '/v1/auth/webauthn/credentials',
+        headers=user.auth_headers,
+        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def request_passkey_challenge(url: Text,
+                              username: Optional[Text] = None) -> Dict:
+    payload = {'claim': 'webauthn'}
+    if username:
+        payload['username'] = username
+    res = requests.post(url + '/v1/auth/auth', json=payload,
+                        verify=not url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def enable_email_mfa(user: User) -> Dict:
+    res = requests.post(
+        user.url + '/v1/auth/mfa/email',
+        headers=user.auth_headers,
+        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def request_mfa_challenge(user: User) -> Dict:
+    payload = {
+        'username': user.username,
+        'password': user.password,
+        'claim': 'username+password',
+    }
+    res = requests.post(user.url + '/v1/auth/auth', json=payload,
+                        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def login_with_mfa_code(user: User, challenge: Text, code: Text) -> Dict:
+    payload = {
+        'claim': 'mfa_code',
+        'token': challenge,
+        'mfa_code': code,
+    }
+    res = requests.post(user.url + '/v1/auth/auth', json=payload,
+                        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def login_with_recovery_code(user: User, challenge: Text,
+                             code: Text) -> Dict:
+    payload = {
+        'claim': 'mfa_recovery',
+        'token': challenge,
+        'mfa_code': code,
+    }
+    res = requests.post(user.url + '/v1/auth/auth', json=payload,
+                        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def get_recovery_code_status(user: User) -> Dict:
+    res = requests.get(
+        user.url + '/v1/auth/mfa/recovery',
+        headers=user.auth_headers,
+        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def regenerate_recovery_codes(user: User, password: Text) -> Dict:
+    res = requests.post(
+        user.url + '/v1/auth/mfa/recovery',
+        json={'password': password},
+        headers=user.auth_headers,
+        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def create_api_key(user: User, name: Text, scopes: List[Text],
+                   expires_at: Optional[Text] = None) -> Dict:
+    body = {'name': name, 'scopes': scopes}
+    if expires_at is not None:
+        body['expires_at'] = expires_at
+    res = requests.post(
+        user.url + '/v1/auth/apikeys',
+        json=body,
+        headers=user.auth_headers,
+        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def list_api_keys(user: User) -> List[Dict]:
+    res = requests.get(
+        user.url + '/v1/auth/apikeys',
+        headers=user.auth_headers,
+        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def delete_api_key(user: User, key_id: Text) -> None:
+    res = requests.delete(
+        user.url + '/v1/auth/apikeys/' + key_id,
+        headers=user.auth_headers,
+        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+
+
+def get_identity(url: Text, headers: Dict[Text, Text]) -> Dict:
+    res = requests.get(url + '/v1/auth/me', headers=headers,
+                       verify=not url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def request_service_token(url: Text, client_id: Text,
+                          client_secret: Text) -> Dict:
+    payload = {
+        'claim': 'client_credentials',
+        'client_id': client_id,
+        'client_secret': client_secret,
+    }
+    res = requests.post(url + '/v1/auth/auth', json=payload,
+                        verify=not url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def list_activity(user: User) -> List[Dict]:
+    res = requests.get(user.url + '/v1/auth/activity',
+                       headers=user.auth_headers,
+                       verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def report_login(url: Text, username: Text, token: Text) -> None:
+    payload = {
+        'username': username,
+        'token': token,
+    }
+    res = requests.post(url + '/v1/auth/devices/report', json=payload,
+                        verify=not url.endswith('.localhost'))
+    raise_on_error(res)
+
+
+def list_sessions(user: User) -> List[Dict]:
+    res = requests.get(user.url + '/v1/auth/sessions',
+                       headers=user.auth_headers,
+                       verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
+
+
+def revoke_session(user: User, session_id: Text) -> None:
+    res = requests.delete(user.url + '/v1/auth/sessions/' + session_id,
+                          headers=user.auth_headers,
+                          verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+
+
+def revoke_other_sessions(user: User) -> None:
+    res = requests.post(user.url + '/v1/auth/sessions/revoke_others',
+                        headers=user.auth_headers,
+                        verify=not user.url.endswith('.localhost'))
+    raise_on_error(res)
+
+
+def refresh_auth_token(url: Text, refresh_token: Text) -> Dict:
+    payload = {
+        'claim': 'refresh_token',
+        'token': refresh_token,
+    }
+    res = requests.post(url + '/v1/auth/auth', json=payload,
+                        verify=not url.endswith('.localhost'))
+    raise_on_error(res)
+    return res.json()
@@ -0,0 +1,36 @@
+from tests.common.user import (cancel_account_deletion, create_new_account,
+                               export_account, request_account_deletion)
+from tests.common.utils import gen_random_chars
+
+
+def test_export_account(user):
+    user.login()
+    export = export_account(user)
+
+    assert export['account']['username'] == user.username
+    assert export['account']['email'] == user.email
+    assert export['refresh_tokens']
+    assert export['sessions']
+    for key in ['known_devices', 'login_link_tokens', 'role_assignments',
+                'role_changes']:
+        assert key in export
+    assert 'password_hash' not in export['account']
+
+
+def test_account_deletion(api_env_url):
+    user = create_new_account(
+        'test_user_' + gen_random_chars(14),
+        'test_user_' + gen_random_chars(14) + '@test.nu',
+        gen_random_chars(30),
+        api_env_url)
+    user.login()
+
+    status = request_account_deletion(user)
+    assert status['delete_after']
+
+    # Can still log in during the cooling-off period
+    user.login()
+    assert export_account(user)['account']['delete_after']
+
+    cancel_account_deletion(user)
+    assert 'delete_after' not in export_account(user)['account']
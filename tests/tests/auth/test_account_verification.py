@@ -1,7 +1,12 @@
+import time
+
+import requests
 
 from tests.common.email import (get_emails_from_local_inbox,
                                 get_verification_token, read_local_email)
-from tests.common.user import verify_email
+from tests.common.user import (create_new_account, resend_verification_email,
+                               verify_email)
+from tests.common.utils import gen_random_chars
 
 
 def test_verify_invalid_token(user):
@@ -35,3 +40,41 @@ def test_verify_verified(user):
 
     verify_email(user, token)
     verify_email(user, token)
+
+
+def test_resend_verification(api_env_url):
+    user = create_new_account(
+        'test_user_' + gen_random_chars(14),
+        'test_user_' + gen_random_chars(14) + '@test.nu',
+        gen_random_chars(30),
+        api_env_url)
+    user.login()
+
+    # Emails are named by the second they were sent
+    time.sleep(2)
+    resend_verification_email(user)
+
+    # Still in cooldown
+    try:
+        resend_verification_email(user)
+        assert False
+    except requests.HTTPError:
+        pass
+
+    time.sleep(2)
+    emails = get_emails_from_local_inbox(user.username, 'welcome')
+    assert len(emails) == 2
+
+    _, old_token = get_verification_token(read_local_email(emails[0]))
+    _, new_token = get_verification_token(read_local_email(emails[1]))
+
+    try:
+        verify_email(user, old_token)
+        assert False
+    except requests.HTTPError:
+        pass
+
+    verify_email(user, new_token)
+
+    user.login()
+    assert 'email_verify' not in user.roles
@@ -0,0 +1,30 @@
+import requests
+
+from tests.common.user import list_activity
+
+
+def test_login_activity(user):
+    original_password = user.password
+    user.password = original_password + 'wrong'
+    try:
+        user.login()
+        assert False
+    except requests.HTTPError:
+        pass
+    user.password = original_password
+
+    user.login()
+    events = list_activity(user)
+    assert events[0]['type'] == 'login_success'
+    assert events[0]['detail'] == 'username+password'
+    assert events[0]['request_id']
+    assert any(e['type'] == 'login_failure' for e in events)
+
+
+def test_security_events_admin_only(user):
+    user.login()
+    res = requests.get(user.url + '/v1/auth/admin/events',
+                       params={'username': user.username},
+                       headers=user.auth_headers,
+                       verify=not user.url.endswith('.localhost'))
+    assert res.status_code == 401
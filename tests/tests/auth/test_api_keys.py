@@ -0,0 +1,87 @@
+import datetime
+import time
+
+import requests
+
+from tests.common.user import (change_password, create_api_key,
+                               create_new_account, delete_api_key,
+                               get_identity, list_api_keys)
+from tests.common.utils import gen_random_chars, raise_on_error
+
+
+def __check_fails(fn):
+    try:
+        fn()
+        assert False
+    except requests.HTTPError:
+        pass
+
+
+def __list_api_keys_with(url, headers):
+    res = requests.get(url + '/v1/auth/apikeys', headers=headers,
+                       verify=not url.endswith('.localhost'))
+    raise_on_error(res)
+
+
+def test_api_key(user):
+    user.login()
+    created = create_api_key(user, 'lineup bot', ['lineups:read'])
+    key = created['key']
+    assert key.startswith(created['api_key']['prefix'])
+
+    listed = [k for k in list_api_keys(user)
+              if k['id'] == created['api_key']['id']]
+    assert listed
+    assert 'key' not in listed[0]
+
+    headers = {'Authorization': 'Bearer: ' + key}
+    identity = get_identity(user.url, headers)
+    assert identity['username'] == user.username
+    assert identity['scopes'] == ['lineups:read']
+
+    # Keys can not manage the account
+    __check_fails(lambda: __list_api_keys_with(user.url, headers))
+
+    delete_api_key(user, created['api_key']['id'])
+    __check_fails(lambda: get_identity(user.url, headers))
+
+
+def test_expired_api_key_not_listed(user):
+    user.login()
+    expires_at = datetime.datetime.utcnow() + datetime.timedelta(seconds=5)
+    created = create_api_key(user, 'short lived', ['lineups:read'],
+                             expires_at.strftime('%Y-%m-%dT%H:%M:%SZ'))
+    time.sleep(6)
+
+    assert all(k['id'] != created['api_key']['id']
+               for k in list_api_keys(user))
+
+
+def test_api_key_unknown_scope(user):
+    user.login()
+    __check_fails(lambda: create_api_key(user, 'bot', ['admin']))
+
+
+def test_identity_with_token(user):
+    user.login()
+    identity = get_identity(user.url, user.auth_headers)
+    assert identity['username'] == user.username
+    assert identity['scopes'] == []
+
+
+def test_api_keys_revoked_on_password_change(api_env_url):
+    password = gen_random_chars(30)
+    user = create_new_account(
+        'test_user_' + gen_random_chars(14),
+        'test_user_' + gen_random_chars(14) + '@test.nu',
+        password,
+        api_env_url)
+    user.login()
+    created = create_api_key(user, 'lineup bot', ['lineups:read'])
+    headers = {'Authorization': 'Bearer: ' + created['key']}
+    assert get_identity(user.url, headers)['username'] == user.username
+
+    # Keys created by someone who took over the account must not outlive
+    # the password change
+    change_password(user, password, gen_random_chars(30))
+    __check_fails(lambda: get_identity(user.url, headers))
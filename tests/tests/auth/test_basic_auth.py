@@ -4,7 +4,8 @@ import time
 import requests
 
 from tests.common.email import get_emails_from_local_inbox, read_local_email
-from tests.common.user import check_username_available, create_new_account
+from tests.common.user import (check_password_strength,
+                               check_username_available, create_new_account)
 from tests.common.utils import gen_random_chars
 
 
@@ -77,6 +78,28 @@ def test_check_username(user):
     assert check_username_available(gen_random_chars(20), user.url)
 
 
+def test_check_password_strength(user):
+    weak = check_password_strength('aaaaaaaaaaaa', user.url)
+    assert weak['score'] == 0
+    assert not weak['accepted']
+    assert weak['warning']
+
+    # Passwords containing the username are weaker
+    with_username = check_password_strength(
+        user.username + '1', user.url, username=user.username)
+    assert not with_username['accepted']
+
+    strong = check_password_strength(gen_random_chars(30), user.url)
+    assert strong['accepted']
+
+
+def test_weak_password_rejected():
+    __check_fails(lambda: create_new_account(
+        gen_random_chars(10),
+        gen_random_chars(10) + '@test.nu',
+        'qwertyuiop1234'))
+
+
 def test_verification_email_sent(user, env):
     # When doing local testing sent emails are stored on disk in /tmp/inbox\
     sent_time = int(time.time())
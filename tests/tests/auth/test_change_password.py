@@ -0,0 +1,67 @@
+import copy
+import time
+
+import requests
+
+from tests.common.email import get_emails_from_local_inbox
+from tests.common.user import change_password, create_new_account
+from tests.common.utils import gen_random_chars
+
+
+def __check_fails(fn):
+    try:
+        fn()
+        assert False
+    except requests.HTTPError:
+        pass
+
+
+def test_change_password(api_env_url, env):
+    old_password = gen_random_chars(30)
+    new_password = gen_random_chars(30)
+    user = create_new_account(
+        'test_user_' + gen_random_chars(14),
+        'test_user_' + gen_random_chars(14) + '@test.nu',
+        old_password,
+        api_env_url)
+    user.login()
+
+    # Wrong current password
+    __check_fails(lambda: change_password(
+        user, gen_random_chars(30), new_password))
+
+    # Weak new password
+    __check_fails(lambda: change_password(
+        user, old_password, 'qwertyuiop1234'))
+
+    tokens = change_password(user, old_password, new_password)
+    assert tokens['access_token']
+    assert tokens['refresh_token']
+
+    __check_fails(user.login)
+
+    user.password = new_password
+    user.login()
+
+    if env == 'local':
+        time.sleep(2)
+        assert get_emails_from_local_inbox(user.username, 'password_changed')
+
+
+def test_change_password_signs_out_other_sessions(api_env_url):
+    old_password = gen_random_chars(30)
+    user = create_new_account(
+        'test_user_' + gen_random_chars(14),
+        'test_user_' + gen_random_chars(14) + '@test.nu',
+        old_password,
+        api_env_url)
+    user.login()
+    other = copy.copy(user)
+    other.login()
+
+    change_password(user, old_password, gen_random_chars(30))
+
+    res = requests.get(user.url + '/v1/auth/activity',
+                       headers=other.auth_headers,
+                       verify=not user.url.endswith('.localhost'))
+    assert res.status_code == 401
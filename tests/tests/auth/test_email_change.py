@@ -0,0 +1,88 @@
+import time
+
+import requests
+
+from tests.common.email import (get_emails_from_local_inbox,
+                                get_verification_token, read_local_email)
+from tests.common.user import (cancel_email_change, confirm_email_change,
+                               create_new_account, request_email_change,
+                               verify_email)
+from tests.common.utils import gen_random_chars
+
+
+def __check_fails(fn):
+    try:
+        fn()
+        assert False
+    except requests.HTTPError:
+        pass
+
+
+def __latest_token(username, email_type):
+    emails = get_emails_from_local_inbox(username, email_type)
+    assert emails, f'No {email_type} email found'
+    _, token = get_verification_token(read_local_email(emails[-1]))
+    return token
+
+
+def __new_verified_user(api_env_url):
+    user = create_new_account(
+        'test_user_' + gen_random_chars(14),
+        'test_user_' + gen_random_chars(14) + '@test.nu',
+        gen_random_chars(30),
+        api_env_url)
+    time.sleep(2)
+    verify_email(user, __latest_token(user.username, 'welcome'))
+    user.login()
+    return user
+
+
+def test_email_change(api_env_url, env, user):
+    if env != 'local':
+        return
+
+    changing = __new_verified_user(api_env_url)
+
+    # Already registered by another account
+    __check_fails(lambda: request_email_change(changing, user.email))
+
+    new_email = 'test_user_' + gen_random_chars(14) + '@test.nu'
+    request_email_change(changing, new_email)
+    time.sleep(2)
+
+    assert get_emails_from_local_inbox(changing.username,
+                                       'email_change_notice')
+    confirm_email_change(
+        changing, __latest_token(changing.username, 'email_change_confirm'))
+
+    # The new address counts as verified
+    changing.login()
+    assert 'email_verify' not in changing.roles
+
+    # Old address is free again
+    create_new_account(gen_random_chars(20), changing.email,
+                       gen_random_chars(30), api_env_url)
+
+
+def test_email_change_revert(api_env_url, env):
+    if env != 'local':
+        return
+
+    changing = __new_verified_user(api_env_url)
+    request_email_change(changing,
+                         'test_user_' + gen_random_chars(14) + '@test.nu')
+    time.sleep(2)
+
+    confirm_token = __latest_token(changing.username, 'email_change_confirm')
+    cancel_token = __latest_token(changing.username, 'email_change_notice')
+
+    confirm_email_change(changing, confirm_token)
+    cancel_email_change(changing, cancel_token)
+
+    # Restored to the old address, still verified
+    changing.login()
+    assert 'email_verify' not in changing.roles
+
+    # Codes can only be used once
+    __check_fails(lambda: confirm_email_change(changing, confirm_token))
+    __check_fails(lambda: cancel_email_change(changing, cancel_token))
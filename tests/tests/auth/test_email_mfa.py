@@ -0,0 +1,66 @@
+import time
+
+import requests
+
+from tests.common.email import (get_emails_from_local_inbox, get_mfa_code,
+                                get_verification_token, read_local_email)
+from tests.common.user import (create_new_account, enable_email_mfa,
+                               login_with_mfa_code, request_mfa_challenge,
+                               verify_email)
+from tests.common.utils import gen_random_chars
+
+
+def __new_verified_user(api_env_url):
+    user = create_new_account(
+        'test_user_' + gen_random_chars(14),
+        'test_user_' + gen_random_chars(14) + '@test.nu',
+        gen_random_chars(30),
+        api_env_url)
+    time.sleep(2)
+    emails = get_emails_from_local_inbox(user.username, 'welcome')
+    assert emails
+    _, token = get_verification_token(read_local_email(emails[-1]))
+    verify_email(user, token)
+    user.login()
+    return user
+
+
+def __latest_mfa_code(user):
+    time.sleep(2)
+    emails = get_emails_from_local_inbox(user.username, 'mfa_code')
+    assert emails
+    code = get_mfa_code(read_local_email(emails[-1]))
+    assert code
+    return code
+
+
+def test_email_mfa_login(api_env_url, env):
+    if env != 'local':
+        return
+
+    user = __new_verified_user(api_env_url)
+    enable_email_mfa(user)
+
+    challenge = request_mfa_challenge(user)
+    assert challenge['mfa_required']
+    code = __latest_mfa_code(user)
+
+    try:
+        login_with_mfa_code(user, challenge['access_token'],
+                            gen_random_chars(8))
+        assert False
+    except requests.HTTPError:
+        pass
+
+    # Codes are typed by hand, case does not matter
+    tokens = login_with_mfa_code(user, challenge['access_token'],
+                                 code.lower())
+    assert tokens['access_token']
+    assert not tokens.get('mfa_required')
+
+    # Single use
+    try:
+        login_with_mfa_code(user, challenge['access_token'], code)
+        assert False
+    except requests.HTTPError:
+        pass
@@ -0,0 +1,35 @@
+import time
+
+import requests
+
+from tests.common.email import (get_emails_from_local_inbox,
+                                get_verification_token, read_local_email)
+from tests.common.user import consume_login_link, request_login_link
+from tests.common.utils import gen_random_chars
+
+
+def test_login_link_unknown_user(user):
+    # Same response whether the account exists or not
+    assert request_login_link(user.url, gen_random_chars(20)) == 202
+
+
+def test_login_link(user, env):
+    if env != 'local':
+        return
+
+    assert request_login_link(user.url, user.username) == 202
+    time.sleep(2)
+
+    emails = get_emails_from_local_inbox(user.username, 'login_link')
+    assert emails
+    _, token = get_verification_token(read_local_email(emails[-1]))
+
+    tokens = consume_login_link(user, token)
+    assert tokens['access_token']
+
+    # Single use
+    try:
+        consume_login_link(user, token)
+        assert False
+    except requests.HTTPError:
+        pass
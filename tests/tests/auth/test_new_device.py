@@ -0,0 +1,82 @@
+import time
+
+import requests
+
+from tests.common.email import (get_emails_from_local_inbox,
+                                get_verification_token, read_local_email)
+from tests.common.user import (consume_login_link, create_new_account,
+                               report_login, request_login_link)
+from tests.common.utils import gen_random_chars
+
+
+def __login_with_user_agent(user, user_agent):
+    payload = {
+        'username': user.username,
+        'password': user.password,
+        'claim': 'username+password',
+    }
+    return requests.post(user.url + '/v1/auth/auth', json=payload,
+                         headers={'User-Agent': user_agent},
+                         verify=not user.url.endswith('.localhost'))
+
+
+def test_report_unknown_code(user):
+    try:
+        report_login(user.url, user.username, gen_random_chars(36))
+        assert False
+    except requests.HTTPError:
+        pass
+
+
+def __check_fails(fn):
+    try:
+        fn()
+        assert False
+    except requests.HTTPError:
+        pass
+
+
+def test_new_device_login(api_env_url, env):
+    if env != 'local':
+        return
+
+    # Reporting locks the account, so not the shared test user
+    user = create_new_account(
+        'test_user_' + gen_random_chars(14),
+        'test_user_' + gen_random_chars(14) + '@test.nu',
+        gen_random_chars(30),
+        api_env_url)
+
+    # The first device of an account is not reported
+    user.login()
+    user.login()
+    time.sleep(2)
+    assert not get_emails_from_local_inbox(user.username, 'new_login')
+
+    res = __login_with_user_agent(user, 'unknown-device/1.0')
+    assert res.status_code == 200
+    time.sleep(2)
+
+    emails = get_emails_from_local_inbox(user.username, 'new_login')
+    assert len(emails) == 1
+    _, token = get_verification_token(read_local_email(emails[-1]))
+
+    assert request_login_link(user.url, user.username) == 202
+    time.sleep(2)
+    links = get_emails_from_local_inbox(user.username, 'login_link')
+    assert links
+    _, link_token = get_verification_token(read_local_email(links[-1]))
+
+    report_login(user.url, user.username, token)
+
+    # Outstanding sign-in links do not get around the lock
+    __check_fails(lambda: consume_login_link(user, link_token))
+
+    # Locked until the password is reset
+    res = __login_with_user_agent(user, 'unknown-device/1.0')
+    assert res.status_code == 429
+    time.sleep(2)
+    assert get_emails_from_local_inbox(user.username, 'reset_password')
+
+    # Single use
+    __check_fails(lambda: report_login(user.url, user.username, token))
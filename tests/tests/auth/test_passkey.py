@@ -0,0 +1,42 @@
+import requests
+
+from tests.common.user import (begin_passkey_registration, list_passkeys,
+                               request_passkey_challenge)
+from tests.common.utils import gen_random_chars
+
+
+def test_passkey_registration_options(user):
+    user.login()
+    options = begin_passkey_registration(user)
+
+    assert options['challenge']
+    assert options['rp_id']
+    assert options['user_name'] == user.username
+    assert options['credentials'] == []
+    assert list_passkeys(user) == []
+
+
+def test_passkey_challenge(user):
+    options = request_passkey_challenge(user.url, user.username)
+    assert options['challenge']
+    assert options['credentials'] == []
+
+    # Unknown users get the same response
+    options = request_passkey_challenge(user.url, gen_random_chars(20))
+    assert options['challenge']
+    assert options['credentials'] == []
+
+
+def test_passkey_invalid_assertion(user):
+    payload = {
+        'claim': 'webauthn',
+        'assertion': {
+            'credential_id': 'AAAA',
+            'client_data_json': 'AAAA',
+            'authenticator_data': 'AAAA',
+            'signature': 'AAAA',
+        },
+    }
+    res = requests.post(user.url + '/v1/auth/auth', json=payload,
+                        verify=not user.url.endswith('.localhost'))
+    assert res.status_code in (400, 401)
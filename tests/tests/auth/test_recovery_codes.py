@@ -0,0 +1,91 @@
+import time
+
+import requests
+
+from tests.common.email import (get_emails_from_local_inbox,
+                                get_verification_token, read_local_email)
+from tests.common.user import (create_new_account, enable_email_mfa,
+                               get_recovery_code_status,
+                               login_with_recovery_code,
+                               regenerate_recovery_codes,
+                               request_mfa_challenge, verify_email)
+from tests.common.utils import gen_random_chars
+
+
+def __check_fails(fn):
+    try:
+        fn()
+        assert False
+    except requests.HTTPError:
+        pass
+
+
+def __new_verified_user(api_env_url):
+    user = create_new_account(
+        'test_user_' + gen_random_chars(14),
+        'test_user_' + gen_random_chars(14) + '@test.nu',
+        gen_random_chars(30),
+        api_env_url)
+    time.sleep(2)
+    emails = get_emails_from_local_inbox(user.username, 'welcome')
+    assert emails
+    _, token = get_verification_token(read_local_email(emails[-1]))
+    verify_email(user, token)
+    user.login()
+    return user
+
+
+def test_recovery_codes(api_env_url, env):
+    if env != 'local':
+        return
+
+    user = __new_verified_user(api_env_url)
+    codes = enable_email_mfa(user)['codes']
+    assert len(codes) == 10
+
+    challenge = request_mfa_challenge(user)
+    assert challenge['mfa_required']
+
+    tokens = login_with_recovery_code(
+        user, challenge['access_token'], codes[0].lower())
+    assert tokens['access_token']
+    assert not tokens.get('mfa_required')
+
+    # Single use
+    challenge = request_mfa_challenge(user)
+    __check_fails(lambda: login_with_recovery_code(
+        user, challenge['access_token'], codes[0]))
+
+    assert get_recovery_code_status(user)['remaining'] == 9
+
+    time.sleep(2)
+    assert get_emails_from_local_inbox(user.username, 'mfa_recovery_used')
+
+
+def test_regenerate_recovery_codes(api_env_url, env):
+    if env != 'local':
+        return
+
+    user = __new_verified_user(api_env_url)
+    old_codes = enable_email_mfa(user)['codes']
+
+    __check_fails(lambda: regenerate_recovery_codes(
+        user, gen_random_chars(30)))
+
+    new_codes = regenerate_recovery_codes(user, user.password)['codes']
+    assert len(new_codes) == 10
+    assert get_recovery_code_status(user)['remaining'] == 10
+
+    challenge = request_mfa_challenge(user)
+    __check_fails(lambda: login_with_recovery_code(
+        user, challenge['access_token'], old_codes[1]))
+
+    challenge = request_mfa_challenge(user)
+    tokens = login_with_recovery_code(
+        user, challenge['access_token'], new_codes[1])
+    assert tokens['access_token']
+
+
+def test_regenerate_recovery_codes_without_mfa(user):
+    user.login()
+    __check_fails(lambda: regenerate_recovery_codes(user, user.password))
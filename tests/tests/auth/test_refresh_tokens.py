@@ -0,0 +1,42 @@
+import requests
+
+from tests.common.user import refresh_auth_token, request_mfa_challenge
+from tests.common.utils import gen_random_chars
+
+
+def __check_fails(fn):
+    try:
+        fn()
+        assert False
+    except requests.HTTPError:
+        pass
+
+
+def test_refresh_token_rotation(user):
+    tokens = request_mfa_challenge(user)
+    first = tokens['refresh_token']
+
+    rotated = refresh_auth_token(user.url, first)
+    assert rotated['access_token']
+    assert rotated['refresh_token'] != first
+
+    # A rotated token can not be used again
+    __check_fails(lambda: refresh_auth_token(user.url, first))
+
+
+def test_refresh_token_reuse_revokes_family(user):
+    first = request_mfa_challenge(user)['refresh_token']
+    second = refresh_auth_token(user.url, first)['refresh_token']
+
+    # Presenting the rotated token looks like theft, the newest token of the
+    # family stops working too
+    __check_fails(lambda: refresh_auth_token(user.url, first))
+    __check_fails(lambda: refresh_auth_token(user.url, second))
+
+    # Other logins are not affected
+    other = request_mfa_challenge(user)['refresh_token']
+    assert refresh_auth_token(user.url, other)['access_token']
+
+
+def test_unknown_refresh_token(user):
+    __check_fails(lambda: refresh_auth_token(user.url, gen_random_chars(43)))
@@ -0,0 +1,15 @@
+import requests
+
+
+def test_roles_admin_only(user):
+    user.login()
+    verify = not user.url.endswith('.localhost')
+    res = requests.get(user.url + '/v1/auth/admin/roles',
+                       headers=user.auth_headers, verify=verify)
+    assert res.status_code == 401
+
+    res = requests.post(user.url + '/v1/auth/admin/roles',
+                        json={'username': user.username, 'role': 'admin',
+                              'reason': 'Promoting myself'},
+                        headers=user.auth_headers, verify=verify)
+    assert res.status_code == 401
@@ -0,0 +1,21 @@
+import requests
+
+from tests.common.user import request_service_token
+from tests.common.utils import gen_random_chars
+
+
+def test_unknown_service_client(api_env_url):
+    try:
+        request_service_token(api_env_url, 'svc_' + gen_random_chars(16),
+                              gen_random_chars(43))
+        assert False
+    except requests.HTTPError:
+        pass
+
+
+def test_service_clients_admin_only(user):
+    user.login()
+    res = requests.get(user.url + '/v1/auth/admin/clients',
+                       headers=user.auth_headers,
+                       verify=not user.url.endswith('.localhost'))
+    assert res.status_code == 401
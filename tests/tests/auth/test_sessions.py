@@ -0,0 +1,71 @@
+import copy
+
+import requests
+
+from tests.common.user import (list_sessions, revoke_other_sessions,
+                               revoke_session)
+
+
+def __other_login(user):
+    other = copy.copy(user)
+    other.login()
+    return other
+
+
+def test_list_sessions(user):
+    user.login()
+    sessions = list_sessions(user)
+    current = [s for s in sessions if s['current']]
+    assert len(current) == 1
+    assert current[0]['ip']
+    assert current[0]['last_seen_at']
+
+
+def test_revoke_session(user):
+    user.login()
+    other = __other_login(user)
+
+    other_id = [s['id'] for s in list_sessions(other) if s['current']][0]
+    revoke_session(user, other_id)
+
+    # Rejected right away, not once the access token expires
+    res = requests.get(user.url + '/v1/auth/sessions',
+                       headers=other.auth_headers,
+                       verify=not user.url.endswith('.localhost'))
+    assert res.status_code == 401
+
+    assert other_id not in [s['id'] for s in list_sessions(user)]
+
+    try:
+        revoke_session(user, other_id)
+        assert False
+    except requests.HTTPError:
+        pass
+
+
+def test_revoke_other_sessions(user):
+    user.login()
+    others = [__other_login(user) for _ in range(2)]
+
+    revoke_other_sessions(user)
+
+    for other in others:
+        res = requests.get(user.url + '/v1/auth/sessions',
+                           headers=other.auth_headers,
+                           verify=not user.url.endswith('.localhost'))
+        assert res.status_code == 401
+
+    sessions = list_sessions(user)
+    assert len(sessions) == 1
+    assert sessions[0]['current']
+
+
+def test_logout_ends_session(user):
+    user.login()
+    other = __other_login(user)
+    other_id = [s['id'] for s in list_sessions(other) if s['current']][0]
+
+    # No refresh token sent, only the access token in the header
+    other.logout()
+
+    assert other_id not in [s['id'] for s in list_sessions(user)]